@@ -0,0 +1,30 @@
+package bch
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/core/swapper/immediate"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+	"github.com/sirupsen/logrus"
+)
+
+// BitcoinCash is the Bitcoin Cash blockchain. It uses the same scripts as
+// Bitcoin, with fork id signatures and cash addresses.
+var BitcoinCash = btc.Chain{
+	Name:          blockchain.BitcoinCash,
+	DecodeAddress: DecodeAddress,
+	Sign:          btc.ForkIDSignature,
+}
+
+// MainNetParams are the network parameters of the Bitcoin Cash mainnet. Its
+// legacy addresses are the same as the Bitcoin addresses.
+var MainNetParams = chaincfg.MainNetParams
+
+// TestNetParams are the network parameters of the Bitcoin Cash testnet.
+var TestNetParams = chaincfg.TestNet3Params
+
+// NewBCHSwapContractBinder returns a new Bitcoin Cash Atom instance
+func NewBCHSwapContractBinder(account btc.Account, swap swap.Swap, cost blockchain.Cost, logger logrus.FieldLogger) (immediate.Contract, error) {
+	return btc.NewSwapContractBinder(BitcoinCash, account, swap, cost, logger)
+}
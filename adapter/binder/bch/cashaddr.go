@@ -0,0 +1,151 @@
+package bch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// The types of cash addresses.
+const (
+	typeP2PKH = byte(0)
+	typeP2SH  = byte(1)
+)
+
+// ErrInvalidCashAddress is returned when an address is neither a valid cash
+// address nor a valid legacy address.
+type ErrInvalidCashAddress string
+
+// NewErrInvalidCashAddress returns a new ErrInvalidCashAddress.
+func NewErrInvalidCashAddress(address string, err error) error {
+	return ErrInvalidCashAddress(fmt.Sprintf("invalid bitcoin cash address %s: %v", address, err))
+}
+
+func (err ErrInvalidCashAddress) Error() string {
+	return string(err)
+}
+
+// Prefix returns the cash address prefix of the network.
+func Prefix(params *chaincfg.Params) string {
+	switch params.Net {
+	case chaincfg.MainNetParams.Net:
+		return "bitcoincash"
+	case chaincfg.RegressionNetParams.Net:
+		return "bchreg"
+	default:
+		return "bchtest"
+	}
+}
+
+// EncodeAddress encodes a P2PKH or P2SH address as a cash address.
+func EncodeAddress(address btcutil.Address, params *chaincfg.Params) (string, error) {
+	var addrType byte
+	switch address.(type) {
+	case *btcutil.AddressPubKeyHash:
+		addrType = typeP2PKH
+	case *btcutil.AddressScriptHash:
+		addrType = typeP2SH
+	default:
+		return "", NewErrInvalidCashAddress(address.String(), fmt.Errorf("unsupported address type"))
+	}
+
+	// The version byte sets the address type, and a hash size of 160 bits
+	payload, err := bech32.ConvertBits(append([]byte{addrType << 3}, address.ScriptAddress()...), 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := Prefix(params)
+	checksum := polymod(append(append(prefixData(prefix), payload...), 0, 0, 0, 0, 0, 0, 0, 0))
+
+	encoded := strings.Builder{}
+	encoded.WriteString(prefix)
+	encoded.WriteByte(':')
+	for _, b := range payload {
+		encoded.WriteByte(charset[b])
+	}
+	for i := 0; i < 8; i++ {
+		encoded.WriteByte(charset[(checksum>>uint(5*(7-i)))&0x1f])
+	}
+	return encoded.String(), nil
+}
+
+// DecodeAddress decodes a cash address, with or without its prefix, or a
+// legacy address.
+func DecodeAddress(address string, params *chaincfg.Params) (btcutil.Address, error) {
+	prefix := Prefix(params)
+	encoded := strings.ToLower(address)
+	if i := strings.IndexByte(encoded, ':'); i >= 0 {
+		if encoded[:i] != prefix {
+			return nil, NewErrInvalidCashAddress(address, fmt.Errorf("expected prefix %s", prefix))
+		}
+		encoded = encoded[i+1:]
+	}
+
+	data := make([]byte, len(encoded))
+	for i := range encoded {
+		b := strings.IndexByte(charset, encoded[i])
+		if b < 0 {
+			// Not a cash address, so it must be a legacy address
+			return btcutil.DecodeAddress(address, params)
+		}
+		data[i] = byte(b)
+	}
+	if len(data) < 8 || polymod(append(prefixData(prefix), data...)) != 0 {
+		return btcutil.DecodeAddress(address, params)
+	}
+
+	payload, err := bech32.ConvertBits(data[:len(data)-8], 5, 8, false)
+	if err != nil {
+		return nil, NewErrInvalidCashAddress(address, err)
+	}
+	if len(payload) != 21 {
+		return nil, NewErrInvalidCashAddress(address, fmt.Errorf("unsupported hash size"))
+	}
+
+	switch payload[0] >> 3 {
+	case typeP2PKH:
+		return btcutil.NewAddressPubKeyHash(payload[1:], params)
+	case typeP2SH:
+		return btcutil.NewAddressScriptHashFromHash(payload[1:], params)
+	default:
+		return nil, NewErrInvalidCashAddress(address, fmt.Errorf("unsupported address type"))
+	}
+}
+
+func prefixData(prefix string) []byte {
+	data := make([]byte, len(prefix)+1)
+	for i := range prefix {
+		data[i] = prefix[i] & 0x1f
+	}
+	return data
+}
+
+func polymod(data []byte) uint64 {
+	c := uint64(1)
+	for _, d := range data {
+		c0 := byte(c >> 35)
+		c = ((c & 0x07ffffffff) << 5) ^ uint64(d)
+		if c0&0x01 != 0 {
+			c ^= 0x98f2bc8e61
+		}
+		if c0&0x02 != 0 {
+			c ^= 0x79b76d99e2
+		}
+		if c0&0x04 != 0 {
+			c ^= 0xf33e5fb3c4
+		}
+		if c0&0x08 != 0 {
+			c ^= 0xae2eabe2a8
+		}
+		if c0&0x10 != 0 {
+			c ^= 0x1e4f43e470
+		}
+	}
+	return c ^ 1
+}
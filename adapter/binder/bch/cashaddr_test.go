@@ -0,0 +1,81 @@
+package bch_test
+
+import (
+	"encoding/hex"
+
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/adapter/binder/bch"
+)
+
+// The vectors are the examples of address translation in the cash address
+// specification, which all have the same hash.
+var _ = Describe("Cash addresses", func() {
+	hash, _ := hex.DecodeString("76a04053bda0a88bda5177b86a15c3b29f559873")
+
+	It("should encode and decode mainnet P2PKH addresses", func() {
+		address, err := btcutil.NewAddressPubKeyHash(hash, &MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(address.EncodeAddress()).Should(Equal("1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu"))
+
+		encoded, err := EncodeAddress(address, &MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(encoded).Should(Equal("bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"))
+
+		decoded, err := DecodeAddress(encoded, &MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(decoded.ScriptAddress()).Should(Equal(hash))
+		Expect(decoded).Should(BeAssignableToTypeOf(&btcutil.AddressPubKeyHash{}))
+	})
+
+	It("should encode and decode mainnet P2SH addresses", func() {
+		address, err := btcutil.NewAddressScriptHashFromHash(hash, &MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(address.EncodeAddress()).Should(Equal("3CWFddi6m4ndiGyKqzYvsFYagqDLPVMTzC"))
+
+		encoded, err := EncodeAddress(address, &MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(encoded).Should(Equal("bitcoincash:ppm2qsznhks23z7629mms6s4cwef74vcwvn0h829pq"))
+
+		decoded, err := DecodeAddress(encoded, &MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(decoded.ScriptAddress()).Should(Equal(hash))
+		Expect(decoded).Should(BeAssignableToTypeOf(&btcutil.AddressScriptHash{}))
+	})
+
+	It("should encode and decode testnet addresses", func() {
+		address, err := btcutil.NewAddressPubKeyHash(hash, &TestNetParams)
+		Expect(err).Should(BeNil())
+
+		encoded, err := EncodeAddress(address, &TestNetParams)
+		Expect(err).Should(BeNil())
+		Expect(encoded).Should(Equal("bchtest:qpm2qsznhks23z7629mms6s4cwef74vcwvqcw003ap"))
+
+		decoded, err := DecodeAddress(encoded, &TestNetParams)
+		Expect(err).Should(BeNil())
+		Expect(decoded.ScriptAddress()).Should(Equal(hash))
+	})
+
+	It("should decode cash addresses without their prefix and in upper case", func() {
+		decoded, err := DecodeAddress("QPM2QSZNHKS23Z7629MMS6S4CWEF74VCWVY22GDX6A", &MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(decoded.ScriptAddress()).Should(Equal(hash))
+	})
+
+	It("should decode legacy addresses", func() {
+		decoded, err := DecodeAddress("1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", &MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(decoded.ScriptAddress()).Should(Equal(hash))
+	})
+
+	It("should not decode addresses of another network", func() {
+		_, err := DecodeAddress("bchtest:qpm2qsznhks23z7629mms6s4cwef74vcwvqcw003ap", &MainNetParams)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should not decode addresses with an invalid checksum", func() {
+		_, err := DecodeAddress("bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6b", &MainNetParams)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
@@ -4,10 +4,14 @@ import (
 	"encoding/base64"
 	"fmt"
 	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/republicprotocol/swapperd/adapter/binder/bch"
 	"github.com/republicprotocol/swapperd/adapter/binder/btc"
 	"github.com/republicprotocol/swapperd/adapter/binder/erc20"
 	"github.com/republicprotocol/swapperd/adapter/binder/eth"
+	"github.com/republicprotocol/swapperd/adapter/binder/ltc"
 	"github.com/republicprotocol/swapperd/adapter/wallet"
 	"github.com/republicprotocol/swapperd/core/swapper/immediate"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
@@ -18,12 +22,16 @@ import (
 type builder struct {
 	wallet.Wallet
 	logrus.FieldLogger
+	watcher eth.Watcher
 }
 
-func NewBuilder(wallet wallet.Wallet, logger logrus.FieldLogger) immediate.ContractBuilder {
+// NewBuilder returns a new ContractBuilder. The Ethereum watcher is optional,
+// without it the Ethereum contracts are polled directly.
+func NewBuilder(wallet wallet.Wallet, watcher eth.Watcher, logger logrus.FieldLogger) immediate.ContractBuilder {
 	return &builder{
 		wallet,
 		logger,
+		watcher,
 	}
 }
 
@@ -32,50 +40,82 @@ func (builder *builder) BuildSwapContracts(req immediate.SwapRequest) (immediate
 	if err != nil {
 		return nil, nil, err
 	}
-	nativeBinder, err := builder.buildBinder(native, req.SendCost, req.Blob.Password)
+	nativeBinder, err := builder.buildBinder(native, req.SendCost, req.Blob.Password, req.Blob.AddressIndex)
 	if err != nil {
 		return nil, nil, err
 	}
-	foreignBinder, err := builder.buildBinder(foreign, req.ReceiveCost, req.Blob.Password)
+	foreignBinder, err := builder.buildBinder(foreign, req.ReceiveCost, req.Blob.Password, req.Blob.AddressIndex)
 	if err != nil {
 		return nil, nil, err
 	}
 	return nativeBinder, foreignBinder, nil
 }
 
-func (builder *builder) buildBinder(swap swap.Swap, cost blockchain.Cost, password string) (immediate.Contract, error) {
-	switch swap.Token {
-	case blockchain.TokenBTC:
-		btcAccount, err := builder.BitcoinAccount(password)
+func (builder *builder) buildBinder(swap swap.Swap, cost blockchain.Cost, password string, addressIndex uint32) (immediate.Contract, error) {
+	config, err := builder.TokenConfig(swap.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Blockchain {
+	case blockchain.Bitcoin:
+		btcAccount, err := builder.UTXOAccountAt(password, blockchain.Bitcoin, addressIndex)
 		if err != nil {
 			return nil, err
 		}
 		return btc.NewBTCSwapContractBinder(btcAccount, swap, cost, builder.FieldLogger)
-	case blockchain.TokenETH:
-		ethAccount, err := builder.EthereumAccount(password)
+	case blockchain.Litecoin:
+		ltcAccount, err := builder.UTXOAccountAt(password, blockchain.Litecoin, addressIndex)
+		if err != nil {
+			return nil, err
+		}
+		return ltc.NewLTCSwapContractBinder(ltcAccount, swap, cost, builder.FieldLogger)
+	case blockchain.BitcoinCash:
+		bchAccount, err := builder.UTXOAccountAt(password, blockchain.BitcoinCash, addressIndex)
 		if err != nil {
 			return nil, err
 		}
-		return eth.NewETHSwapContractBinder(ethAccount, swap, cost, builder.FieldLogger)
-	case blockchain.TokenWBTC, blockchain.TokenDGX, blockchain.TokenREN,
-		blockchain.TokenTUSD, blockchain.TokenOMG, blockchain.TokenZRX,
-		blockchain.TokenGUSD, blockchain.TokenDAI, blockchain.TokenUSDC:
+		return bch.NewBCHSwapContractBinder(bchAccount, swap, cost, builder.FieldLogger)
+	case blockchain.Ethereum:
 		ethAccount, err := builder.EthereumAccount(password)
 		if err != nil {
 			return nil, err
 		}
-		return erc20.NewERC20SwapContractBinder(ethAccount, swap, cost, builder.FieldLogger)
+		tokenAddress, swapperAddress, err := builder.ContractAddresses(swap.Token)
+		if err != nil {
+			return nil, err
+		}
+		oracle, err := builder.gasPriceOracle(swap)
+		if err != nil {
+			return nil, err
+		}
+		if config.IsERC20() {
+			return erc20.NewERC20SwapContractBinder(ethAccount, common.HexToAddress(tokenAddress), common.HexToAddress(swapperAddress), swap, cost, builder.StandingAllowance(swap.Token), oracle, builder.watcher, builder.FieldLogger)
+		}
+		return eth.NewETHSwapContractBinder(ethAccount, common.HexToAddress(swapperAddress), swap, cost, oracle, builder.watcher, builder.FieldLogger)
 	default:
 		return nil, blockchain.NewErrUnsupportedToken(swap.Token.Name)
 	}
 }
 
+// gasPriceOracle returns a static oracle when the fee of the swap was chosen by
+// the user, and the oracle of the wallet otherwise.
+func (builder *builder) gasPriceOracle(swap swap.Swap) (eth.GasPriceOracle, error) {
+	if swap.Fee != nil {
+		return eth.NewStaticGasPriceOracle(swap.Fee), nil
+	}
+	return builder.GasPriceOracle()
+}
+
 func (builder *builder) buildComplementarySwaps(blob swap.SwapBlob) (swap.Swap, swap.Swap, error) {
 	fundingAddr, spendingAddr, err := builder.calculateAddresses(blob)
 	if err != nil {
 		return swap.Swap{}, swap.Swap{}, err
 	}
-	nativeExpiry, foreignExpiry := builder.calculateTimeLocks(blob)
+	nativeExpiry, foreignExpiry, err := builder.calculateTimeLocks(blob)
+	if err != nil {
+		return swap.Swap{}, swap.Swap{}, err
+	}
 
 	nativeSwap, err := builder.buildNativeSwap(blob, nativeExpiry, fundingAddr)
 	if err != nil {
@@ -85,11 +125,36 @@ func (builder *builder) buildComplementarySwaps(blob swap.SwapBlob) (swap.Swap,
 	if err != nil {
 		return swap.Swap{}, swap.Swap{}, err
 	}
+	if !blob.ShouldInitiateFirst {
+		if err := builder.setResponderExpiry(&foreignSwap, nativeSwap); err != nil {
+			return swap.Swap{}, swap.Swap{}, err
+		}
+	}
 	return nativeSwap, foreignSwap, nil
 }
 
+// setResponderExpiry sets the expiry of the leg of the responder on the leg of
+// the initiator, so that the leg of the initiator can be audited against it
+// before the responder funds its leg. Only the relative timelocks of the
+// initiator need it, since they start when the initiator funds its leg.
+func (builder *builder) setResponderExpiry(initiatorSwap *swap.Swap, responderSwap swap.Swap) error {
+	if initiatorSwap.TimeLockMode != swap.TimeLockModeSequence || initiatorSwap.Token.Blockchain == blockchain.Ethereum {
+		return nil
+	}
+	expiry, err := builder.TimeLockExpiry(responderSwap.Token.Blockchain, responderSwap.TimeLockMode, responderSwap.TimeLock, responderSwap.TimeLockBlocks)
+	if err != nil {
+		return err
+	}
+	if responderSwap.Token.Blockchain == blockchain.Ethereum {
+		initiatorSwap.ResponderExpiry = expiry
+		return nil
+	}
+	initiatorSwap.ResponderLockDuration = expiry - time.Now().Unix()
+	return nil
+}
+
 func (builder *builder) buildNativeSwap(blob swap.SwapBlob, timelock int64, fundingAddress string) (swap.Swap, error) {
-	token, err := blockchain.PatchToken(blob.SendToken)
+	token, err := builder.PatchToken(blob.SendToken)
 	if err != nil {
 		return swap.Swap{}, err
 	}
@@ -98,8 +163,9 @@ func (builder *builder) buildNativeSwap(blob swap.SwapBlob, timelock int64, fund
 		return swap.Swap{}, fmt.Errorf("corrupted send value: %v", blob.SendAmount)
 	}
 
+	// Ethereum swaps without a fee use the gas price oracle when they transact
 	fee, ok := new(big.Int).SetString(blob.SendFee, 10)
-	if !ok {
+	if !ok && token.Blockchain != blockchain.Ethereum {
 		fee, err = builder.Wallet.DefaultFee(token.Blockchain)
 		if err != nil {
 			return swap.Swap{}, fmt.Errorf("failed to get default fee: %v", err)
@@ -125,16 +191,18 @@ func (builder *builder) buildNativeSwap(blob swap.SwapBlob, timelock int64, fund
 		Value:           value,
 		Fee:             fee,
 		SecretHash:      secretHash,
-		TimeLock:        blob.TimeLock,
+		TimeLock:        timelock,
 		SpendingAddress: blob.SendTo,
 		FundingAddress:  fundingAddress,
 		BrokerAddress:   blob.BrokerSendTokenAddr,
 		BrokerFee:       brokerFee,
+		TimeLockMode:    blob.TimeLockMode,
+		TimeLockBlocks:  blob.SendTimeLockBlocks,
 	}, nil
 }
 
 func (builder *builder) buildForeignSwap(blob swap.SwapBlob, timelock int64, spendingAddress string) (swap.Swap, error) {
-	token, err := blockchain.PatchToken(blob.ReceiveToken)
+	token, err := builder.PatchToken(blob.ReceiveToken)
 	if err != nil {
 		return swap.Swap{}, err
 	}
@@ -144,8 +212,9 @@ func (builder *builder) buildForeignSwap(blob swap.SwapBlob, timelock int64, spe
 		return swap.Swap{}, fmt.Errorf("corrupted receive value: %v", blob.ReceiveAmount)
 	}
 
+	// Ethereum swaps without a fee use the gas price oracle when they transact
 	fee, ok := new(big.Int).SetString(blob.ReceiveFee, 10)
-	if !ok {
+	if !ok && token.Blockchain != blockchain.Ethereum {
 		fee, err = builder.Wallet.DefaultFee(token.Blockchain)
 		if err != nil {
 			return swap.Swap{}, fmt.Errorf("failed to get default fee: %v", err)
@@ -171,65 +240,96 @@ func (builder *builder) buildForeignSwap(blob swap.SwapBlob, timelock int64, spe
 		Value:           value,
 		Fee:             fee,
 		SecretHash:      secretHash,
-		TimeLock:        blob.TimeLock,
+		TimeLock:        timelock,
 		SpendingAddress: spendingAddress,
 		FundingAddress:  blob.ReceiveFrom,
 		BrokerAddress:   blob.BrokerReceiveTokenAddr,
 		BrokerFee:       brokerFee,
+		TimeLockMode:    blob.TimeLockMode,
+		TimeLockBlocks:  blob.ReceiveTimeLockBlocks,
 	}, nil
 }
 
-func (builder *builder) calculateTimeLocks(swap swap.SwapBlob) (native, foreign int64) {
-	if swap.ShouldInitiateFirst {
-		native = swap.TimeLock
-		foreign = swap.TimeLock - 24*60*60
-		return
-	}
-	native = swap.TimeLock - 24*60*60
-	foreign = swap.TimeLock
-	return
+// A chainSpec describes how the legs of a swap are built on a blockchain.
+type chainSpec struct {
+	// expiryGap is the time, in seconds, between the expiry of the responder's
+	// leg and the expiry of the initiator's leg. It gives the responder time
+	// to redeem on this blockchain after the secret has been revealed, so it
+	// covers the confirmations needed on the blockchain. The responder only
+	// accepts swaps that expire at least two expiry units from now, so the gap
+	// must not be longer than an expiry unit.
+	expiryGap int64
 }
 
-func (builder *builder) calculateAddresses(swap swap.SwapBlob) (string, string, error) {
-	sendToken, err := blockchain.PatchToken(swap.SendToken)
-	if err != nil {
-		return "", "", err
-	}
+var chainSpecs = map[blockchain.BlockchainName]chainSpec{
+	blockchain.Ethereum:    {expiryGap: swap.ExpiryUnit / 2},
+	blockchain.Bitcoin:     {expiryGap: swap.ExpiryUnit},
+	blockchain.Litecoin:    {expiryGap: swap.ExpiryUnit / 2},
+	blockchain.BitcoinCash: {expiryGap: swap.ExpiryUnit},
+}
 
-	receiveToken, err := blockchain.PatchToken(swap.ReceiveToken)
+// legSpecs returns the specs of the blockchains of both legs of the swap.
+func (builder *builder) legSpecs(swap swap.SwapBlob) (blockchain.Token, blockchain.Token, chainSpec, chainSpec, error) {
+	sendToken, err := builder.PatchToken(swap.SendToken)
 	if err != nil {
-		return "", "", err
+		return blockchain.Token{}, blockchain.Token{}, chainSpec{}, chainSpec{}, err
 	}
 
-	ethAccount, err := builder.EthereumAccount(swap.Password)
+	receiveToken, err := builder.PatchToken(swap.ReceiveToken)
 	if err != nil {
-		return "", "", err
+		return blockchain.Token{}, blockchain.Token{}, chainSpec{}, chainSpec{}, err
 	}
 
-	btcAccount, err := builder.BitcoinAccount(swap.Password)
-	if err != nil {
-		return "", "", err
+	sendSpec, sendOk := chainSpecs[sendToken.Blockchain]
+	receiveSpec, receiveOk := chainSpecs[receiveToken.Blockchain]
+	if !sendOk || !receiveOk {
+		return blockchain.Token{}, blockchain.Token{}, chainSpec{}, chainSpec{}, fmt.Errorf("unsupported blockchain pairing: %s <=> %s", sendToken.Blockchain, receiveToken.Blockchain)
 	}
+	return sendToken, receiveToken, sendSpec, receiveSpec, nil
+}
 
-	ethAddress := ethAccount.Address()
-	btcAddress, err := btcAccount.Address()
+// calculateTimeLocks returns the timelocks of the native and the foreign legs
+// of the swap. The leg of the initiator expires at the timelock of the swap,
+// and the leg of the responder expires earlier by the longest expiry gap of
+// the blockchains of the legs.
+func (builder *builder) calculateTimeLocks(swap swap.SwapBlob) (native, foreign int64, err error) {
+	_, _, sendSpec, receiveSpec, err := builder.legSpecs(swap)
 	if err != nil {
-		return "", "", err
+		return 0, 0, err
 	}
+	native, foreign = timeLocks(swap.TimeLock, swap.ShouldInitiateFirst, sendSpec, receiveSpec)
+	return native, foreign, nil
+}
 
-	if sendToken.Blockchain == blockchain.Ethereum && receiveToken.Blockchain == blockchain.Bitcoin {
-		return ethAddress.String(), btcAddress.EncodeAddress(), nil
+func timeLocks(timelock int64, initiateFirst bool, sendSpec, receiveSpec chainSpec) (native, foreign int64) {
+	expiryGap := sendSpec.expiryGap
+	if receiveSpec.expiryGap > expiryGap {
+		expiryGap = receiveSpec.expiryGap
+	}
+	if initiateFirst {
+		return timelock, timelock - expiryGap
 	}
+	return timelock - expiryGap, timelock
+}
 
-	if sendToken.Blockchain == blockchain.Bitcoin && receiveToken.Blockchain == blockchain.Ethereum {
-		return btcAddress.EncodeAddress(), ethAddress.String(), nil
+// calculateAddresses returns the addresses of the wallet on the blockchains
+// of the send and receive tokens.
+func (builder *builder) calculateAddresses(swap swap.SwapBlob) (string, string, error) {
+	sendToken, receiveToken, _, _, err := builder.legSpecs(swap)
+	if err != nil {
+		return "", "", err
 	}
 
-	if sendToken.Blockchain == blockchain.Ethereum && receiveToken.Blockchain == blockchain.Ethereum {
-		return ethAddress.String(), ethAddress.String(), nil
+	sendAddress, err := builder.GetAddressAt(swap.Password, sendToken.Blockchain, swap.AddressIndex)
+	if err != nil {
+		return "", "", err
 	}
 
-	return "", "", fmt.Errorf("unsupported blockchain pairing: %s <=> %s", sendToken.Blockchain, receiveToken.Blockchain)
+	receiveAddress, err := builder.GetAddressAt(swap.Password, receiveToken.Blockchain, swap.AddressIndex)
+	if err != nil {
+		return "", "", err
+	}
+	return sendAddress, receiveAddress, nil
 }
 
 func unmarshalSecretHash(secretHash string) ([32]byte, error) {
@@ -0,0 +1,13 @@
+package binder_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBinder(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Binder Suite")
+}
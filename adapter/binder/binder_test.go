@@ -0,0 +1,40 @@
+package binder_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/adapter/binder"
+
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+)
+
+var _ = Describe("Binder", func() {
+	const timelock = int64(1600000000)
+
+	DescribeTable("timelocks of the legs of a swap",
+		func(send, receive blockchain.BlockchainName, gap int64) {
+			native, foreign := TimeLocks(timelock, true, send, receive)
+			Expect(native).Should(Equal(timelock))
+			Expect(foreign).Should(Equal(timelock - gap))
+
+			// The responder builds the same legs, with the roles swapped
+			native, foreign = TimeLocks(timelock, false, receive, send)
+			Expect(native).Should(Equal(timelock - gap))
+			Expect(foreign).Should(Equal(timelock))
+		},
+		Entry("ETH to ETH", blockchain.Ethereum, blockchain.Ethereum, swap.ExpiryUnit/2),
+		Entry("ETH to LTC", blockchain.Ethereum, blockchain.Litecoin, swap.ExpiryUnit/2),
+		Entry("ETH to BTC", blockchain.Ethereum, blockchain.Bitcoin, swap.ExpiryUnit),
+		Entry("BTC to ETH", blockchain.Bitcoin, blockchain.Ethereum, swap.ExpiryUnit),
+		Entry("BCH to LTC", blockchain.BitcoinCash, blockchain.Litecoin, swap.ExpiryUnit),
+	)
+
+	It("should leave the responder time to fund before its leg expires", func() {
+		// The responder accepts swaps that expire at least two expiry units
+		// from now
+		native, _ := TimeLocks(2*swap.ExpiryUnit, false, blockchain.Bitcoin, blockchain.BitcoinCash)
+		Expect(native).Should(BeNumerically(">=", swap.ExpiryUnit))
+	})
+})
@@ -0,0 +1,516 @@
+package btc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/republicprotocol/libbtc-go"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// DustAmount is the smallest output, in satoshis, relayed by the nodes. Change
+// below it is left to the miners.
+const DustAmount = 546
+
+// An Account is used by the binder to fund, redeem and refund atomic swaps. It
+// is implemented by libbtc for Bitcoin, and by NewAccount for the other UTXO
+// chains.
+type Account interface {
+	NetworkParams() *chaincfg.Params
+	FormatTransactionView(msg, txHash string) string
+	ScriptFunded(ctx context.Context, address string, value int64) (bool, int64, error)
+	ScriptSpent(ctx context.Context, address string) (bool, error)
+	GetScriptFromSpentP2SH(ctx context.Context, address string) ([]byte, error)
+	Balance(ctx context.Context, address string, confirmations int64) (int64, error)
+	SendTransaction(ctx context.Context, script []byte, fee int64, updateTxIn func(*wire.TxIn), preCond func(*wire.MsgTx) bool, f func(*txscript.ScriptBuilder), postCond func(*wire.MsgTx) bool) error
+	Address() (btcutil.Address, error)
+	Transfer(ctx context.Context, to string, value int64) (string, error)
+}
+
+// A TransactionSigner signs transactions without publishing them, so that they
+// can be published later by anyone. It is implemented by the accounts returned
+// by NewAccount, but not by libbtc.
+type TransactionSigner interface {
+	SignTransaction(ctx context.Context, script []byte, fee int64, updateTxIn func(*wire.TxIn), preCond func(*wire.MsgTx) bool, f func(*txscript.ScriptBuilder)) (*wire.MsgTx, error)
+}
+
+// A UTXO is an unspent transaction output.
+type UTXO struct {
+	TxHash        string `json:"txHash"`
+	Vout          uint32 `json:"vout"`
+	Amount        int64  `json:"amount"`
+	ScriptPubKey  string `json:"scriptPubKey"`
+	Confirmations int64  `json:"confirmations"`
+}
+
+// A Client reads the state of a UTXO chain, and publishes transactions to it.
+type Client interface {
+	NetworkParams() *chaincfg.Params
+	FormatTransactionView(msg, txHash string) string
+
+	OutputLister
+
+	// SpendingScripts returns the signature scripts of the inputs that spend
+	// the outputs of the address.
+	SpendingScripts(ctx context.Context, address string) ([][]byte, error)
+
+	PublishTransaction(ctx context.Context, tx *wire.MsgTx) error
+	Confirmations(ctx context.Context, txHash string) (int64, error)
+
+	ChainClock
+}
+
+// An OutputLister lists the unspent outputs of an address. It is implemented
+// by the accounts returned by NewAccount, but not by libbtc.
+type OutputLister interface {
+	// UnspentOutputs returns the unspent outputs of the address, including
+	// unconfirmed outputs.
+	UnspentOutputs(ctx context.Context, address string) ([]UTXO, error)
+}
+
+// A ChainClock reads the time of a UTXO chain, as seen by the timelocks of its
+// scripts. It is implemented by the accounts returned by NewAccount, but not
+// by libbtc.
+type ChainClock interface {
+	// BlockHeight returns the height of the best block.
+	BlockHeight(ctx context.Context) (int64, error)
+
+	// MedianTime returns the median time of the last 11 blocks, which is the
+	// time checked by OP_CHECKLOCKTIMEVERIFY.
+	MedianTime(ctx context.Context) (int64, error)
+}
+
+// A Chain describes a UTXO blockchain that supports the Bitcoin atomic swap
+// script.
+type Chain struct {
+	Name          blockchain.BlockchainName
+	DecodeAddress func(address string, params *chaincfg.Params) (btcutil.Address, error)
+	Sign          SignatureFunc
+}
+
+// Bitcoin is the Bitcoin blockchain.
+var Bitcoin = Chain{
+	Name:          blockchain.Bitcoin,
+	DecodeAddress: btcutil.DecodeAddress,
+	Sign:          LegacySignature,
+}
+
+// The types of the addresses of an account, following BIP44, BIP49 and BIP84.
+// The segregated witness types are not supported by Bitcoin Cash.
+const (
+	AddressTypeP2PKH      = "p2pkh"
+	AddressTypeP2SHP2WPKH = "p2sh-p2wpkh"
+	AddressTypeP2WPKH     = "p2wpkh"
+)
+
+type account struct {
+	Client
+	chain       Chain
+	addressType string
+	key         *btcec.PrivateKey
+
+	// fundingKeys are the keys whose outputs can fund transactions from the
+	// account, starting with its own key. Each transaction only spends the
+	// outputs of one of them.
+	fundingKeys []*btcec.PrivateKey
+}
+
+// NewAccount returns an Account on the chain, for the given private key.
+func NewAccount(chain Chain, client Client, privKey *ecdsa.PrivateKey) Account {
+	return NewHDAccount(chain, client, AddressTypeP2PKH, privKey, nil)
+}
+
+// NewHDAccount returns an Account on the chain, whose address is the address
+// of the private key. Transactions from the account are funded by the outputs
+// of its own key, or of the first of the other keys that covers them, so that
+// the balance of every address of an HD wallet can be spent from a fresh
+// address. The outputs of different keys are never spent together, which
+// would link their addresses. The change is sent to the address of the
+// account. The addresses of the keys are of the address type.
+func NewHDAccount(chain Chain, client Client, addressType string, privKey *ecdsa.PrivateKey, otherKeys []*ecdsa.PrivateKey) Account {
+	key := toBTCECKey(privKey)
+	fundingKeys := []*btcec.PrivateKey{key}
+	for _, otherKey := range otherKeys {
+		fundingKeys = append(fundingKeys, toBTCECKey(otherKey))
+	}
+	return &account{
+		Client:      client,
+		chain:       chain,
+		addressType: addressType,
+		key:         key,
+		fundingKeys: fundingKeys,
+	}
+}
+
+func toBTCECKey(privKey *ecdsa.PrivateKey) *btcec.PrivateKey {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), crypto.FromECDSA(privKey))
+	return key
+}
+
+func (account *account) Address() (btcutil.Address, error) {
+	return account.keyAddress(account.key)
+}
+
+func (account *account) keyAddress(key *btcec.PrivateKey) (btcutil.Address, error) {
+	return PubKeyAddress(account.addressType, key.PubKey(), account.NetworkParams())
+}
+
+// PubKeyAddress returns the address of the type for the public key.
+func PubKeyAddress(addressType string, pubKey *btcec.PublicKey, params *chaincfg.Params) (btcutil.Address, error) {
+	pubKeyHash := btcutil.Hash160(pubKey.SerializeCompressed())
+	switch addressType {
+	case AddressTypeP2PKH, "":
+		return btcutil.NewAddressPubKeyHash(pubKeyHash, params)
+	case AddressTypeP2WPKH:
+		return btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params)
+	case AddressTypeP2SHP2WPKH:
+		program, err := witnessProgram(pubKey, params)
+		if err != nil {
+			return nil, err
+		}
+		return btcutil.NewAddressScriptHash(program, params)
+	default:
+		return nil, NewErrUnsupportedAddressType(addressType)
+	}
+}
+
+// witnessProgram returns the P2WPKH script of the public key, which is the
+// redeem script of its P2SH-P2WPKH address.
+func witnessProgram(pubKey *btcec.PublicKey, params *chaincfg.Params) ([]byte, error) {
+	address, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(address)
+}
+
+// segWit returns true when the outputs of the keys of the account are
+// segregated witness outputs.
+func (account *account) segWit() bool {
+	return account.addressType == AddressTypeP2WPKH || account.addressType == AddressTypeP2SHP2WPKH
+}
+
+func (account *account) Balance(ctx context.Context, address string, confirmations int64) (int64, error) {
+	utxos, err := account.UnspentOutputs(ctx, address)
+	if err != nil {
+		return 0, err
+	}
+	balance := int64(0)
+	for _, utxo := range utxos {
+		if utxo.Confirmations >= confirmations {
+			balance += utxo.Amount
+		}
+	}
+	return balance, nil
+}
+
+func (account *account) ScriptFunded(ctx context.Context, address string, value int64) (bool, int64, error) {
+	balance, err := account.Balance(ctx, address, 0)
+	if err != nil {
+		return false, 0, err
+	}
+	return balance >= value && balance > 0, balance, nil
+}
+
+func (account *account) ScriptSpent(ctx context.Context, address string) (bool, error) {
+	scripts, err := account.SpendingScripts(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	return len(scripts) > 0, nil
+}
+
+func (account *account) GetScriptFromSpentP2SH(ctx context.Context, address string) ([]byte, error) {
+	scripts, err := account.SpendingScripts(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if len(scripts) == 0 {
+		return nil, ErrNotSpent
+	}
+	return scripts[0], nil
+}
+
+func (account *account) Transfer(ctx context.Context, to string, value int64) (string, error) {
+	tx, err := Transfer(ctx, account, account.chain, to, value, DefaultFee)
+	if err != nil {
+		return "", err
+	}
+	return tx.TxHash().String(), nil
+}
+
+// Transfer sends the value to the address from the account, paying the fee in
+// satoshis, and returns the signed transaction. It works with any Account on
+// the chain, including those of libbtc.
+func Transfer(ctx context.Context, account Account, chain Chain, to string, value, fee int64) (*wire.MsgTx, error) {
+	address, err := chain.DecodeAddress(to, account.NetworkParams())
+	if err != nil {
+		return nil, NewErrDecodeAddress(to, err)
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var signedTx *wire.MsgTx
+	if err := account.SendTransaction(
+		ctx,
+		nil,
+		fee,
+		nil,
+		func(tx *wire.MsgTx) bool {
+			tx.AddTxOut(wire.NewTxOut(value, pkScript))
+			return true
+		},
+		nil,
+		func(tx *wire.MsgTx) bool {
+			signedTx = tx
+			return true
+		},
+	); err != nil {
+		return nil, err
+	}
+	return signedTx, nil
+}
+
+// SendTransaction signs the transaction, publishes it, and returns once the
+// post-condition holds.
+func (account *account) SendTransaction(ctx context.Context, script []byte, fee int64, updateTxIn func(*wire.TxIn), preCond func(*wire.MsgTx) bool, f func(*txscript.ScriptBuilder), postCond func(*wire.MsgTx) bool) error {
+	tx, err := account.SignTransaction(ctx, script, fee, updateTxIn, preCond, f)
+	if err != nil {
+		return err
+	}
+
+	if err := account.PublishTransaction(ctx, tx); err != nil {
+		return NewErrPublishTransaction(err)
+	}
+
+	for {
+		if postCond == nil || postCond(tx) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ErrTimedOut
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// SignTransaction spends the outputs of the P2SH address of the script when it
+// is not nil, or the outputs of one of the funding keys otherwise. The
+// pre-condition adds the outputs of the transaction, and when spending from
+// the account the change is sent back to it. The signature script of each
+// input is the signature and the public key, followed by the data added by f
+// and the script itself. Segregated witness outputs of the account are signed
+// with a witness instead.
+func (account *account) SignTransaction(ctx context.Context, script []byte, fee int64, updateTxIn func(*wire.TxIn), preCond func(*wire.MsgTx) bool, f func(*txscript.ScriptBuilder)) (*wire.MsgTx, error) {
+	address, err := account.Address()
+	if err != nil {
+		return nil, err
+	}
+	payToAddrScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(2)
+	var inputs []input
+	if script != nil {
+		// The pre-condition of a spend from the script sizes its fee by the
+		// inputs, so they are added first
+		if inputs, err = account.scriptInputs(ctx, script); err != nil {
+			return nil, err
+		}
+		if err := addInputs(tx, inputs, updateTxIn); err != nil {
+			return nil, err
+		}
+		if preCond != nil && !preCond(tx) {
+			return nil, libbtc.ErrPreConditionCheckFailed
+		}
+	} else {
+		if preCond != nil && !preCond(tx) {
+			return nil, libbtc.ErrPreConditionCheckFailed
+		}
+		sent := int64(0)
+		for _, txOut := range tx.TxOut {
+			sent += txOut.Value
+		}
+		var total int64
+		if inputs, total, err = account.fundingInputs(ctx, sent+fee); err != nil {
+			return nil, err
+		}
+		if err := addInputs(tx, inputs, updateTxIn); err != nil {
+			return nil, err
+		}
+		if change := total - sent - fee; change >= DustAmount {
+			tx.AddTxOut(wire.NewTxOut(change, payToAddrScript))
+		}
+	}
+
+	var sigHashes *txscript.TxSigHashes
+	for i, input := range inputs {
+		if script == nil && account.segWit() {
+			if sigHashes == nil {
+				sigHashes = txscript.NewTxSigHashes(tx)
+			}
+			if err := account.signWitness(tx, sigHashes, i, input); err != nil {
+				return nil, NewErrSignTransaction(err)
+			}
+			continue
+		}
+
+		sig, err := account.chain.Sign(tx, i, input.subScript, input.utxo.Amount, input.key)
+		if err != nil {
+			return nil, NewErrSignTransaction(err)
+		}
+		builder := txscript.NewScriptBuilder()
+		builder.AddData(sig)
+		builder.AddData(input.key.PubKey().SerializeCompressed())
+		if f != nil {
+			f(builder)
+		}
+		if script != nil {
+			builder.AddData(script)
+		}
+		sigScript, err := builder.Script()
+		if err != nil {
+			return nil, NewErrSignTransaction(err)
+		}
+		tx.TxIn[i].SignatureScript = sigScript
+	}
+
+	return tx, nil
+}
+
+func addInputs(tx *wire.MsgTx, inputs []input, updateTxIn func(*wire.TxIn)) error {
+	for _, input := range inputs {
+		hash, err := chainhash.NewHashFromStr(input.utxo.TxHash)
+		if err != nil {
+			return err
+		}
+		txIn := wire.NewTxIn(wire.NewOutPoint(hash, input.utxo.Vout), nil, nil)
+		if updateTxIn != nil {
+			updateTxIn(txIn)
+		}
+		tx.AddTxIn(txIn)
+	}
+	return nil
+}
+
+// signWitness signs the input with a witness. The signature script of a
+// P2SH-P2WPKH input is its redeem script.
+func (account *account) signWitness(tx *wire.MsgTx, sigHashes *txscript.TxSigHashes, i int, input input) error {
+	program, err := witnessProgram(input.key.PubKey(), account.NetworkParams())
+	if err != nil {
+		return err
+	}
+	witness, err := txscript.WitnessSignature(tx, sigHashes, i, input.utxo.Amount, program, txscript.SigHashAll, input.key, true)
+	if err != nil {
+		return err
+	}
+	tx.TxIn[i].Witness = witness
+
+	if account.addressType == AddressTypeP2SHP2WPKH {
+		sigScript, err := txscript.NewScriptBuilder().AddData(program).Script()
+		if err != nil {
+			return err
+		}
+		tx.TxIn[i].SignatureScript = sigScript
+	}
+	return nil
+}
+
+// An input is an output spent by a transaction, with the key that signs it
+// and the script that it pays to.
+type input struct {
+	utxo      UTXO
+	key       *btcec.PrivateKey
+	subScript []byte
+}
+
+// scriptInputs returns the outputs of the P2SH address of the script, signed
+// by the key of the account.
+func (account *account) scriptInputs(ctx context.Context, script []byte) ([]input, error) {
+	scriptAddr, err := btcutil.NewAddressScriptHash(script, account.NetworkParams())
+	if err != nil {
+		return nil, NewErrBuildScript(err)
+	}
+	utxos, err := account.UnspentOutputs(ctx, scriptAddr.EncodeAddress())
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]input, len(utxos))
+	for i, utxo := range utxos {
+		inputs[i] = input{utxo, account.key, script}
+	}
+	return inputs, nil
+}
+
+// fundingInputs returns all the outputs of the first funding key whose outputs
+// cover the value, and their total.
+func (account *account) fundingInputs(ctx context.Context, value int64) ([]input, int64, error) {
+	largest := int64(0)
+	for _, key := range account.fundingKeys {
+		address, err := account.keyAddress(key)
+		if err != nil {
+			return nil, 0, err
+		}
+		payToAddrScript, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			return nil, 0, err
+		}
+		utxos, err := account.UnspentOutputs(ctx, address.EncodeAddress())
+		if err != nil {
+			return nil, 0, err
+		}
+
+		inputs := make([]input, len(utxos))
+		total := int64(0)
+		for i, utxo := range utxos {
+			inputs[i] = input{utxo, key, payToAddrScript}
+			total += utxo.Amount
+		}
+		if total >= value {
+			return inputs, total, nil
+		}
+		if total > largest {
+			largest = total
+		}
+	}
+
+	address, err := account.Address()
+	if err != nil {
+		return nil, 0, err
+	}
+	return nil, 0, NewErrInsufficientBalance(address.EncodeAddress(), value, largest)
+}
+
+type swapAccount struct {
+	Account
+	swap Account
+}
+
+// NewSwapAccount returns an Account that funds swaps from the funding account,
+// and pays their redeems and refunds back to it, but signs the spends of the
+// swap scripts with the key of the swap account, whose address is the one in
+// the scripts. It gives accounts that only have one address, such as those of
+// libbtc, an address per swap.
+func NewSwapAccount(funding, swap Account) Account {
+	return &swapAccount{funding, swap}
+}
+
+func (account *swapAccount) SendTransaction(ctx context.Context, script []byte, fee int64, updateTxIn func(*wire.TxIn), preCond func(*wire.MsgTx) bool, f func(*txscript.ScriptBuilder), postCond func(*wire.MsgTx) bool) error {
+	if script != nil {
+		return account.swap.SendTransaction(ctx, script, fee, updateTxIn, preCond, f, postCond)
+	}
+	return account.Account.SendTransaction(ctx, script, fee, updateTxIn, preCond, f, postCond)
+}
@@ -0,0 +1,155 @@
+package btc_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/adapter/binder/btc"
+)
+
+// mockClient holds the unspent outputs of each address in memory, and keeps
+// the transactions published to it.
+type mockClient struct {
+	utxos     map[string][]UTXO
+	listed    map[string]int
+	published []*wire.MsgTx
+}
+
+func newMockClient() *mockClient {
+	return &mockClient{utxos: map[string][]UTXO{}, listed: map[string]int{}}
+}
+
+func (client *mockClient) NetworkParams() *chaincfg.Params {
+	return &chaincfg.RegressionNetParams
+}
+
+func (client *mockClient) FormatTransactionView(msg, txHash string) string {
+	return fmt.Sprintf("%s, transaction hash = %s", msg, txHash)
+}
+
+func (client *mockClient) UnspentOutputs(ctx context.Context, address string) ([]UTXO, error) {
+	client.listed[address]++
+	return client.utxos[address], nil
+}
+
+func (client *mockClient) SpendingScripts(ctx context.Context, address string) ([][]byte, error) {
+	return nil, nil
+}
+
+func (client *mockClient) PublishTransaction(ctx context.Context, tx *wire.MsgTx) error {
+	client.published = append(client.published, tx)
+	return nil
+}
+
+func (client *mockClient) Confirmations(ctx context.Context, txHash string) (int64, error) {
+	return 0, nil
+}
+
+func (client *mockClient) BlockHeight(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (client *mockClient) MedianTime(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+var _ = Describe("HD accounts", func() {
+	params := &chaincfg.RegressionNetParams
+
+	newKey := func(seed byte) *btcec.PrivateKey {
+		key, _ := btcec.PrivKeyFromBytes(btcec.S256(), append(make([]byte, 31), seed))
+		return key
+	}
+	keyAddress := func(key *btcec.PrivateKey) string {
+		address, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160(key.PubKey().SerializeCompressed()), params)
+		return address.EncodeAddress()
+	}
+	fund := func(client *mockClient, key *btcec.PrivateKey, seed byte, amount int64) wire.OutPoint {
+		hash := chainhash.Hash{seed}
+		client.utxos[keyAddress(key)] = append(client.utxos[keyAddress(key)], UTXO{
+			TxHash: hash.String(),
+			Amount: amount,
+		})
+		return *wire.NewOutPoint(&hash, 0)
+	}
+	spent := func(tx *wire.MsgTx) []wire.OutPoint {
+		outPoints := []wire.OutPoint{}
+		for _, txIn := range tx.TxIn {
+			outPoints = append(outPoints, txIn.PreviousOutPoint)
+		}
+		return outPoints
+	}
+
+	swapKey, mainKey, otherKey := newKey(1), newKey(2), newKey(3)
+	to := keyAddress(newKey(4))
+
+	Context("when funding a transfer", func() {
+		It("should spend the outputs of its own key when they cover it", func() {
+			client := newMockClient()
+			own := fund(client, swapKey, 1, 60000)
+			fund(client, mainKey, 2, 100000)
+			account := NewHDAccount(Bitcoin, client, AddressTypeP2PKH, swapKey.ToECDSA(), []*ecdsa.PrivateKey{mainKey.ToECDSA()})
+
+			tx, err := Transfer(context.Background(), account, Bitcoin, to, 50000, 1000)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(spent(tx)).Should(ConsistOf(own))
+		})
+
+		It("should spend the outputs of only one of the other keys", func() {
+			client := newMockClient()
+			fund(client, swapKey, 1, 10000)
+			fund(client, mainKey, 2, 30000)
+			other := fund(client, otherKey, 3, 70000)
+			otherChange := fund(client, otherKey, 4, 5000)
+			account := NewHDAccount(Bitcoin, client, AddressTypeP2PKH, swapKey.ToECDSA(), []*ecdsa.PrivateKey{mainKey.ToECDSA(), otherKey.ToECDSA()})
+
+			tx, err := Transfer(context.Background(), account, Bitcoin, to, 50000, 1000)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(spent(tx)).Should(ConsistOf(other, otherChange))
+
+			// The change goes back to the address of the account
+			Expect(tx.TxOut).Should(HaveLen(2))
+			Expect(tx.TxOut[1].Value).Should(Equal(int64(70000 + 5000 - 50000 - 1000)))
+		})
+
+		It("should not combine the outputs of different keys", func() {
+			client := newMockClient()
+			fund(client, swapKey, 1, 30000)
+			fund(client, mainKey, 2, 30000)
+			account := NewHDAccount(Bitcoin, client, AddressTypeP2PKH, swapKey.ToECDSA(), []*ecdsa.PrivateKey{mainKey.ToECDSA()})
+
+			_, err := Transfer(context.Background(), account, Bitcoin, to, 50000, 1000)
+			Expect(err).Should(HaveOccurred())
+			Expect(client.published).Should(BeEmpty())
+		})
+	})
+
+	Context("when the unspent outputs are cached", func() {
+		It("should only list the outputs of an address again after a transaction is published", func() {
+			client := newMockClient()
+			fund(client, mainKey, 2, 100000)
+			account := NewHDAccount(Bitcoin, NewCachedClient(client), AddressTypeP2PKH, mainKey.ToECDSA(), nil)
+
+			for i := 0; i < 3; i++ {
+				balance, err := account.Balance(context.Background(), keyAddress(mainKey), 0)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(balance).Should(Equal(int64(100000)))
+			}
+			Expect(client.listed[keyAddress(mainKey)]).Should(Equal(1))
+
+			_, err := Transfer(context.Background(), account, Bitcoin, to, 50000, 1000)
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = account.Balance(context.Background(), keyAddress(mainKey), 0)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(client.listed[keyAddress(mainKey)]).Should(Equal(2))
+		})
+	})
+})
@@ -1,9 +1,11 @@
 package btc
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"time"
@@ -26,16 +28,31 @@ type btcSwapContractBinder struct {
 	fee        int64
 	verify     bool
 	cost       blockchain.Cost
+	chain      Chain
+	refundTx   string
+	refundErr  error
+	outputs    []swap.Output
+	funding    swap.FundingFlags
+	spentFee   int64
 	logrus.FieldLogger
-	libbtc.Account
+	Account
 }
 
 // NewBTCSwapContractBinder returns a new Bitcoin Atom instance
-func NewBTCSwapContractBinder(account libbtc.Account, swap swap.Swap, cost blockchain.Cost, logger logrus.FieldLogger) (immediate.Contract, error) {
-	script, scriptAddr, err := buildInitiateScript(swap, account.NetworkParams())
+func NewBTCSwapContractBinder(account Account, swap swap.Swap, cost blockchain.Cost, logger logrus.FieldLogger) (immediate.Contract, error) {
+	return NewSwapContractBinder(Bitcoin, account, swap, cost, logger)
+}
+
+// NewSwapContractBinder returns a new Atom instance for a UTXO chain that
+// supports the Bitcoin atomic swap script.
+func NewSwapContractBinder(chain Chain, account Account, swap swap.Swap, cost blockchain.Cost, logger logrus.FieldLogger) (immediate.Contract, error) {
+	script, scriptAddr, err := buildInitiateScript(swap, account.NetworkParams(), chain.DecodeAddress)
 	if err != nil {
 		return nil, err
 	}
+	if _, ok := account.(ChainClock); !ok && !timeMode(swap.TimeLockMode) {
+		return nil, ErrChainClockUnsupported
+	}
 
 	fields := logrus.Fields{}
 	fields["SwapID"] = swap.ID
@@ -43,18 +60,20 @@ func NewBTCSwapContractBinder(account libbtc.Account, swap swap.Swap, cost block
 	fields["Token"] = swap.Token.Name
 	logger = logger.WithFields(fields)
 
-	if _, ok := cost[blockchain.BTC]; !ok {
-		cost[blockchain.BTC] = big.NewInt(0)
+	if _, ok := cost[swap.Token.Name]; !ok {
+		cost[swap.Token.Name] = big.NewInt(0)
 	}
 
-	logger.Info(swap.ID, fmt.Sprintf("BTC atomic swap = %s", scriptAddr))
+	logger.Info(swap.ID, fmt.Sprintf("%s atomic swap = %s", swap.Token.Name, scriptAddr))
 	return &btcSwapContractBinder{
 		scriptAddr:  scriptAddr,
 		script:      script,
 		swap:        swap,
 		txVersion:   2,
 		fee:         swap.Fee.Int64(),
+		spentFee:    swap.Fee.Int64(),
 		verify:      true,
+		chain:       chain,
 		FieldLogger: logger,
 		Account:     account,
 		cost:        cost,
@@ -63,7 +82,7 @@ func NewBTCSwapContractBinder(account libbtc.Account, swap swap.Swap, cost block
 
 // Initiate the atomic swap by funding a HTLC on the Bitcoin blockchain.
 func (atom *btcSwapContractBinder) Initiate() error {
-	atom.Info("Initiating on Bitcoin blockchain for BTC")
+	atom.Info(fmt.Sprintf("Initiating on %s blockchain for %s", atom.chain.Name, atom.swap.Token.Name))
 	scriptAddr, err := btcutil.DecodeAddress(atom.scriptAddr, atom.NetworkParams())
 	if err != nil {
 		return NewErrInitiate(err)
@@ -89,9 +108,12 @@ func (atom *btcSwapContractBinder) Initiate() error {
 				return false
 			}
 			if funded {
-				atom.Info(fmt.Sprintf("Send value on Bitcoin blockchain = %d", atom.swap.Value.Int64()))
+				atom.Info(fmt.Sprintf("Send value on %s blockchain = %d", atom.chain.Name, atom.swap.Value.Int64()))
 				return false
 			}
+			if value > 0 {
+				atom.Warn(fmt.Sprintf("%s atomic swap is already funded with %d, topping up to %d", atom.chain.Name, value, atom.swap.Value.Int64()))
+			}
 			// creating unsigned transaction and adding transaction outputs
 			tx.AddTxOut(wire.NewTxOut(atom.swap.Value.Int64()-value, initiateScriptP2SHPKScript))
 			return !funded
@@ -103,32 +125,303 @@ func (atom *btcSwapContractBinder) Initiate() error {
 				return false
 			}
 			if funded {
-				atom.Info(atom.FormatTransactionView("Initiated on Bitcoin blockchain", tx.TxHash().String()))
+				atom.Info(atom.FormatTransactionView(fmt.Sprintf("Initiated on %s blockchain", atom.chain.Name), tx.TxHash().String()))
 			}
 			return funded
 		},
 	); err != nil && err != libbtc.ErrPreConditionCheckFailed {
 		return err
 	}
-	atom.cost[blockchain.BTC] = new(big.Int).Add(big.NewInt(atom.fee), atom.cost[blockchain.BTC])
-	atom.cost[blockchain.BTC] = new(big.Int).Add(atom.swap.BrokerFee, atom.cost[blockchain.BTC])
+	atom.cost[atom.swap.Token.Name] = new(big.Int).Add(big.NewInt(atom.fee), atom.cost[atom.swap.Token.Name])
+	atom.cost[atom.swap.Token.Name] = new(big.Int).Add(atom.swap.BrokerFee, atom.cost[atom.swap.Token.Name])
+	atom.auditOutputs(ctx)
+
+	// The refund is signed as soon as the contract is funded, so that it can
+	// be published without the daemon once the swap expires. Accounts that
+	// use blockchain.info cannot sign without publishing, which is reported
+	// with the swap.
+	if atom.refundErr = atom.presignRefund(ctx); atom.refundErr != nil && atom.refundErr != ErrSigningUnsupported {
+		atom.Warn(fmt.Sprintf("Cannot pre-sign the refund on %s blockchain: %v", atom.chain.Name, atom.refundErr))
+	}
+	return nil
+}
+
+// PresignedRefund returns the signed refund transaction, hex encoded, or the
+// error that stopped it from being signed. Both are empty if the contract has
+// not been funded yet.
+func (atom *btcSwapContractBinder) PresignedRefund() (string, error) {
+	return atom.refundTx, atom.refundErr
+}
+
+func (atom *btcSwapContractBinder) presignRefund(ctx context.Context) error {
+	signer, ok := atom.Account.(TransactionSigner)
+	if !ok {
+		return ErrSigningUnsupported
+	}
+	updateTxIn, preCond, f, err := atom.refundTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := signer.SignTransaction(ctx, atom.script, atom.fee, updateTxIn, preCond, f)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := tx.Serialize(buf); err != nil {
+		return err
+	}
+	atom.refundTx = hex.EncodeToString(buf.Bytes())
 	return nil
 }
 
+// refundTransaction returns the functions that build the refund transaction,
+// which spends all the outputs of the contract back to the account once the
+// timelock has passed.
+func (atom *btcSwapContractBinder) refundTransaction(ctx context.Context) (func(*wire.TxIn), func(*wire.MsgTx) bool, func(*txscript.ScriptBuilder), error) {
+	address, err := atom.Address()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	payToAddrScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Relative locks are checked against the sequence of the input, and
+	// absolute locks against the lock time of the transaction
+	sequence, lockTime := uint32(0), uint32(atom.swap.TimeLock)
+	switch atom.swap.TimeLockMode {
+	case swap.TimeLockModeHeight:
+		lockTime = uint32(atom.swap.TimeLockBlocks)
+	case swap.TimeLockModeSequence:
+		sequence, lockTime = uint32(atom.swap.TimeLockBlocks), 0
+	}
+
+	updateTxIn := func(txIn *wire.TxIn) {
+		txIn.Sequence = sequence
+	}
+	preCond := func(tx *wire.MsgTx) bool {
+		funded, val, err := atom.ScriptFunded(ctx, atom.scriptAddr, 0)
+		if err != nil {
+			return false
+		}
+		if funded {
+			atom.spentFee = atom.sweepFee(tx, AtomicSwapRefundScriptSize, 0)
+			if val-atom.spentFee < DustAmount {
+				atom.Error(fmt.Sprintf("Cannot refund %d on %s blockchain with a fee of %d", val, atom.chain.Name, atom.spentFee))
+				return false
+			}
+			tx.AddTxOut(wire.NewTxOut(val-atom.spentFee, payToAddrScript))
+		}
+		tx.LockTime = lockTime
+		return funded
+	}
+	f := func(builder *txscript.ScriptBuilder) {
+		builder.AddInt64(0)
+	}
+	return updateTxIn, preCond, f, nil
+}
+
 func (atom *btcSwapContractBinder) Audit() error {
 	if funded, _, err := atom.ScriptFunded(context.Background(), atom.scriptAddr, atom.swap.Value.Int64()); funded && err == nil {
-		return nil
+		atom.auditOutputs(context.Background())
+		return atom.auditTimeLock(context.Background())
 	}
-	if time.Now().Unix() > atom.swap.TimeLock {
+	if atom.expired(context.Background()) {
 		return immediate.ErrSwapExpired
 	}
 	return immediate.ErrAuditPending
 }
 
+// expired returns true once the contract can be refunded, as seen by the
+// blockchain. Time based timelocks are compared to the median time of the
+// last blocks, which lags behind the wall clock, and height based timelocks
+// to the best block. Relative timelocks expire once every output of the
+// contract is old enough, or at the timelock of the swap when the contract
+// has not been funded. Accounts that cannot read the blockchain use the wall
+// clock.
+func (atom *btcSwapContractBinder) expired(ctx context.Context) bool {
+	clock, ok := atom.Account.(ChainClock)
+	if !ok {
+		return time.Now().Unix() > atom.swap.TimeLock
+	}
+
+	switch atom.swap.TimeLockMode {
+	case swap.TimeLockModeHeight:
+		height, err := clock.BlockHeight(ctx)
+		if err != nil {
+			atom.Error(fmt.Sprintf("Cannot get the block height of %s blockchain: %v", atom.chain.Name, err))
+			return false
+		}
+		return height >= atom.swap.TimeLockBlocks
+	case swap.TimeLockModeSequence:
+		funded, err := atom.Balance(ctx, atom.scriptAddr, 0)
+		if err != nil {
+			atom.Error(fmt.Sprintf("Cannot get the balance of the contract on %s blockchain: %v", atom.chain.Name, err))
+			return false
+		}
+		if funded > 0 {
+			matured, err := atom.Balance(ctx, atom.scriptAddr, atom.swap.TimeLockBlocks)
+			if err != nil {
+				atom.Error(fmt.Sprintf("Cannot get the balance of the contract on %s blockchain: %v", atom.chain.Name, err))
+				return false
+			}
+			return matured == funded
+		}
+	}
+
+	medianTime, err := clock.MedianTime(ctx)
+	if err != nil {
+		atom.Error(fmt.Sprintf("Cannot get the median time of %s blockchain: %v", atom.chain.Name, err))
+		return false
+	}
+	return medianTime > atom.swap.TimeLock
+}
+
+// auditTimeLock checks that the relative timelock of the contract of the
+// initiator expires TimeLockMargin after the contract of the responder would,
+// if the responder funded it now. The relative timelock starts when each
+// output is funded, so the outputs that have aged since are checked every time
+// the contract is audited before the responder funds its contract.
+func (atom *btcSwapContractBinder) auditTimeLock(ctx context.Context) error {
+	if atom.swap.TimeLockMode != swap.TimeLockModeSequence || (atom.swap.ResponderExpiry == 0 && atom.swap.ResponderLockDuration == 0) {
+		return nil
+	}
+	spacing := int64(atom.NetworkParams().TargetTimePerBlock / time.Second)
+	if spacing <= 0 {
+		return fmt.Errorf("unknown block time of %s blockchain", atom.chain.Name)
+	}
+
+	now := time.Now().Unix()
+	responderExpiry := atom.swap.ResponderExpiry
+	if atom.swap.ResponderLockDuration > 0 {
+		responderExpiry = now + atom.swap.ResponderLockDuration
+	}
+	required := (responderExpiry + swap.TimeLockMargin - now + spacing - 1) / spacing
+	if required > atom.swap.TimeLockBlocks {
+		atom.Error(fmt.Sprintf("%s atomic swap expires after %d blocks, at least %d are required", atom.chain.Name, atom.swap.TimeLockBlocks, required))
+		return immediate.ErrUnsafeTimeLock
+	}
+
+	// Outputs with more confirmations than this expire in less than the
+	// required blocks
+	if required < 1 {
+		required = 1
+	}
+	aged, err := atom.Balance(ctx, atom.scriptAddr, atom.swap.TimeLockBlocks-required+1)
+	if err != nil {
+		return err
+	}
+	if aged > 0 {
+		atom.Error(fmt.Sprintf("%s atomic swap has %d funded too long ago, it expires in less than %d blocks", atom.chain.Name, aged, required))
+		return immediate.ErrUnsafeTimeLock
+	}
+	return nil
+}
+
+// FundingOutputs returns the outputs of the contract, as last seen by the
+// binder.
+func (atom *btcSwapContractBinder) FundingOutputs() []swap.Output {
+	return atom.outputs
+}
+
+// FundingFlags returns whether the contract was funded by more than one
+// output or with more than the agreed value, as last seen by the binder.
+func (atom *btcSwapContractBinder) FundingFlags() swap.FundingFlags {
+	return atom.funding
+}
+
+// auditOutputs records the outputs of the contract, and flags it when it has
+// been funded by more than one output or with more than the agreed value.
+// Accounts that cannot list the outputs of an address only check the balance.
+func (atom *btcSwapContractBinder) auditOutputs(ctx context.Context) {
+	lister, ok := atom.Account.(OutputLister)
+	if !ok {
+		total, err := atom.Balance(ctx, atom.scriptAddr, 0)
+		if err != nil {
+			atom.Error(fmt.Sprintf("Cannot get the balance of the contract on %s blockchain: %v", atom.chain.Name, err))
+			return
+		}
+		atom.flagFunding(1, total)
+		return
+	}
+	utxos, err := lister.UnspentOutputs(ctx, atom.scriptAddr)
+	if err != nil {
+		atom.Error(fmt.Sprintf("Cannot get the outputs of the contract on %s blockchain: %v", atom.chain.Name, err))
+		return
+	}
+	if len(utxos) == 0 {
+		return
+	}
+
+	outputs := make([]swap.Output, len(utxos))
+	total := int64(0)
+	for i, utxo := range utxos {
+		outputs[i] = swap.Output{
+			TxHash: utxo.TxHash,
+			Vout:   utxo.Vout,
+			Amount: big.NewInt(utxo.Amount).String(),
+		}
+		total += utxo.Amount
+	}
+	atom.outputs = outputs
+	atom.flagFunding(len(utxos), total)
+}
+
+func (atom *btcSwapContractBinder) flagFunding(outputs int, total int64) {
+	atom.funding = swap.FundingFlags{}
+	if outputs > 1 {
+		atom.Warn(fmt.Sprintf("%s atomic swap is funded by %d outputs", atom.chain.Name, outputs))
+		atom.funding.MultipleOutputs = true
+	}
+	if excess := total - atom.swap.Value.Int64(); excess > 0 {
+		atom.Warn(fmt.Sprintf("%s atomic swap is overfunded by %d", atom.chain.Name, excess))
+		atom.funding.Overfunded = big.NewInt(excess).String()
+	}
+}
+
+// p2pkhOutputSize is the size of a P2PKH output: its value, and its script
+// with its length.
+const p2pkhOutputSize = 8 + 1 + 25
+
+// sweepTxOverhead is the size of a transaction that spends the contract,
+// without its inputs: the version, the lock time, the counts of the inputs and
+// the outputs, and two P2PKH outputs.
+const sweepTxOverhead = 4 + 4 + 1 + 1 + 2*p2pkhOutputSize
+
+// sweepFee returns the fee of a transaction that spends the outputs of the
+// contract with signature scripts of the given size, without the contract
+// script, and that has extra outputs besides the two of the fee of the swap.
+// The fee of the swap is for a transaction that spends a single output, so it
+// is scaled by the size of the transaction.
+func (atom *btcSwapContractBinder) sweepFee(tx *wire.MsgTx, sigScriptSize, extraOutputs int) int64 {
+	// The contract script is pushed with OP_PUSHDATA1 or OP_PUSHDATA2
+	scriptSize := sigScriptSize + len(atom.script) + 3
+	return scaledFee(atom.fee, len(tx.TxIn), scriptSize, extraOutputs)
+}
+
+// scaledFee scales the fee of a transaction with a single input to a
+// transaction with the given inputs, whose signature scripts have the given
+// size, and with the given extra P2PKH outputs.
+func scaledFee(fee int64, inputs, sigScriptSize, extraOutputs int) int64 {
+	if inputs <= 1 && extraOutputs == 0 {
+		return fee
+	}
+	// The outpoint, the sequence, and the signature script with its length
+	inputSize := int64(32 + 4 + 4 + wire.VarIntSerializeSize(uint64(sigScriptSize)) + sigScriptSize)
+	single := sweepTxOverhead + inputSize
+	all := sweepTxOverhead + int64(inputs)*inputSize + int64(extraOutputs)*p2pkhOutputSize
+	return (fee*all + single - 1) / single
+}
+
+func timeMode(mode string) bool {
+	return mode == "" || mode == swap.TimeLockModeTime
+}
+
 // Redeem the Atomic Swap by revealing the secret and withdrawing funds from the
 // HTLC.
 func (atom *btcSwapContractBinder) Redeem(secret [32]byte) error {
-	atom.Info("Redeeming on Bitcoin blockchain")
+	atom.Info(fmt.Sprintf("Redeeming on %s blockchain", atom.chain.Name))
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 	address, err := atom.Address()
@@ -142,7 +435,7 @@ func (atom *btcSwapContractBinder) Redeem(secret [32]byte) error {
 
 	var feeAddrScript []byte
 	if atom.swap.BrokerFee.Int64() != 0 {
-		feeAddress, err := btcutil.DecodeAddress(atom.swap.BrokerAddress, atom.NetworkParams())
+		feeAddress, err := atom.chain.DecodeAddress(atom.swap.BrokerAddress, atom.NetworkParams())
 		if err != nil {
 			return NewErrRedeem(err)
 		}
@@ -153,6 +446,11 @@ func (atom *btcSwapContractBinder) Redeem(secret [32]byte) error {
 		}
 	}
 
+	var fundingAddrScript []byte
+	if fundingAddress, err := atom.chain.DecodeAddress(atom.swap.FundingAddress, atom.NetworkParams()); err == nil {
+		fundingAddrScript, _ = txscript.PayToAddrScript(fundingAddress)
+	}
+
 	if err := atom.SendTransaction(
 		ctx,
 		atom.script,
@@ -164,10 +462,28 @@ func (atom *btcSwapContractBinder) Redeem(secret [32]byte) error {
 				return false
 			}
 			if funded {
+				// Any value above the agreed value is returned to the
+				// funder, unless it is too small to be relayed, and the
+				// extra output is paid for by the fee
+				extraOutputs := 0
+				if excess := val - atom.swap.Value.Int64(); excess >= DustAmount && fundingAddrScript != nil {
+					atom.Warn(fmt.Sprintf("Returning %d overfunded on %s blockchain to %s", excess, atom.chain.Name, atom.swap.FundingAddress))
+					tx.AddTxOut(wire.NewTxOut(excess, fundingAddrScript))
+					val -= excess
+					extraOutputs = 1
+				} else if excess > 0 {
+					atom.Warn(fmt.Sprintf("Redeeming %d overfunded on %s blockchain", excess, atom.chain.Name))
+				}
+				atom.spentFee = atom.sweepFee(tx, AtomicSwapRedeemScriptSize, extraOutputs)
+
+				if val-atom.swap.BrokerFee.Int64()-atom.spentFee < DustAmount {
+					atom.Error(fmt.Sprintf("Cannot redeem %d on %s blockchain with a fee of %d", val, atom.chain.Name, atom.spentFee))
+					return false
+				}
 				if atom.swap.BrokerFee.Int64() != 0 {
 					tx.AddTxOut(wire.NewTxOut(atom.swap.BrokerFee.Int64(), feeAddrScript))
 				}
-				tx.AddTxOut(wire.NewTxOut(val-atom.swap.BrokerFee.Int64()-atom.fee, payToAddrScript))
+				tx.AddTxOut(wire.NewTxOut(val-atom.swap.BrokerFee.Int64()-atom.spentFee, payToAddrScript))
 			}
 			return funded
 		},
@@ -178,7 +494,7 @@ func (atom *btcSwapContractBinder) Redeem(secret [32]byte) error {
 		func(tx *wire.MsgTx) bool {
 			spent, err := atom.ScriptSpent(ctx, atom.scriptAddr)
 			if spent {
-				atom.Info(atom.FormatTransactionView("Redeemed on Bitcoin blockchain", tx.TxHash().String()))
+				atom.Info(atom.FormatTransactionView(fmt.Sprintf("Redeemed on %s blockchain", atom.chain.Name), tx.TxHash().String()))
 			}
 			if err != nil {
 				return false
@@ -188,14 +504,14 @@ func (atom *btcSwapContractBinder) Redeem(secret [32]byte) error {
 	); err != nil && err != libbtc.ErrPreConditionCheckFailed {
 		return err
 	}
-	atom.cost[blockchain.BTC] = new(big.Int).Add(big.NewInt(atom.fee), atom.cost[blockchain.BTC])
+	atom.cost[atom.swap.Token.Name] = new(big.Int).Add(big.NewInt(atom.spentFee), atom.cost[atom.swap.Token.Name])
 	return nil
 }
 
 func (atom *btcSwapContractBinder) AuditSecret() ([32]byte, error) {
-	atom.Info("Auditing secret on Bitcoin blockchain")
+	atom.Info(fmt.Sprintf("Auditing secret on %s blockchain", atom.chain.Name))
 	if spent, err := atom.ScriptSpent(context.Background(), atom.scriptAddr); !spent || err != nil {
-		if time.Now().Unix() > atom.swap.TimeLock {
+		if atom.expired(context.Background()) {
 			return [32]byte{}, immediate.ErrSwapExpired
 		}
 		return [32]byte{}, immediate.ErrAuditPending
@@ -214,7 +530,7 @@ func (atom *btcSwapContractBinder) AuditSecret() ([32]byte, error) {
 		if sha256.Sum256(push) == atom.swap.SecretHash {
 			var secret [32]byte
 			copy(secret[:], push)
-			atom.Info(fmt.Sprintf("Audit succeeded on Bitcoin blockchain secret = %s", base64.StdEncoding.EncodeToString(secret[:])))
+			atom.Info(fmt.Sprintf("Audit succeeded on %s blockchain secret = %s", atom.chain.Name, base64.StdEncoding.EncodeToString(secret[:])))
 			return secret, nil
 		}
 	}
@@ -223,54 +539,36 @@ func (atom *btcSwapContractBinder) AuditSecret() ([32]byte, error) {
 
 // Refund the Atomic Swap after expiry and withdraw funds from the HTLC.
 func (atom *btcSwapContractBinder) Refund() error {
-	atom.Info("Refunding on Bitcoin blockchain")
-	address, err := atom.Address()
+	atom.Info(fmt.Sprintf("Refunding on %s blockchain", atom.chain.Name))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	updateTxIn, preCond, f, err := atom.refundTransaction(ctx)
 	if err != nil {
-		return NewErrRedeem(err)
-	}
-	payToAddrScript, err := txscript.PayToAddrScript(address)
-	if err != nil {
-		return NewErrRedeem(err)
+		return NewErrRefund(err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
 	if err := atom.SendTransaction(
 		ctx,
 		atom.script,
 		atom.fee,
-		func(txIn *wire.TxIn) {
-			txIn.Sequence = 0
-		},
-		func(tx *wire.MsgTx) bool {
-			funded, val, err := atom.ScriptFunded(ctx, atom.scriptAddr, 0)
-			if err != nil {
-				return false
-			}
-			if funded {
-				tx.AddTxOut(wire.NewTxOut(val-atom.fee, payToAddrScript))
-			}
-			tx.LockTime = uint32(atom.swap.TimeLock)
-			return funded
-		},
-		func(builder *txscript.ScriptBuilder) {
-			builder.AddInt64(0)
-		},
+		updateTxIn,
+		preCond,
+		f,
 		func(tx *wire.MsgTx) bool {
 			spent, err := atom.ScriptSpent(ctx, atom.scriptAddr)
 			if err != nil {
 				return false
 			}
 			if spent {
-				atom.Info(atom.FormatTransactionView("Refunded on Bitcoin blockchain", tx.TxHash().String()))
+				atom.Info(atom.FormatTransactionView(fmt.Sprintf("Refunded on %s blockchain", atom.chain.Name), tx.TxHash().String()))
 			}
 			return spent
 		},
 	); err != nil && err != libbtc.ErrPreConditionCheckFailed {
 		return err
 	}
-	atom.cost[blockchain.BTC] = new(big.Int).Add(big.NewInt(atom.fee), atom.cost[blockchain.BTC])
-	atom.cost[blockchain.BTC] = new(big.Int).Sub(atom.cost[blockchain.BTC], atom.swap.BrokerFee)
+	atom.cost[atom.swap.Token.Name] = new(big.Int).Add(big.NewInt(atom.spentFee), atom.cost[atom.swap.Token.Name])
+	atom.cost[atom.swap.Token.Name] = new(big.Int).Sub(atom.cost[atom.swap.Token.Name], atom.swap.BrokerFee)
 	return nil
 }
 
@@ -0,0 +1,67 @@
+package btc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// UTXOCacheDuration is how long the unspent outputs of an address are cached
+// by the clients returned by NewCachedClient.
+const UTXOCacheDuration = 10 * time.Second
+
+type cachedUTXOs struct {
+	utxos   []UTXO
+	expires time.Time
+}
+
+type cachedClient struct {
+	Client
+
+	mu    *sync.Mutex
+	utxos map[string]cachedUTXOs
+}
+
+// NewCachedClient returns a Client that caches the unspent outputs of each
+// address for the UTXOCacheDuration, so that the many addresses of an HD
+// wallet are not listed again by every balance and transaction. The cache is
+// cleared whenever a transaction is published, since it spends and creates
+// outputs.
+func NewCachedClient(client Client) Client {
+	return &cachedClient{
+		Client: client,
+		mu:     new(sync.Mutex),
+		utxos:  map[string]cachedUTXOs{},
+	}
+}
+
+func (client *cachedClient) UnspentOutputs(ctx context.Context, address string) ([]UTXO, error) {
+	client.mu.Lock()
+	cached, ok := client.utxos[address]
+	client.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return append([]UTXO{}, cached.utxos...), nil
+	}
+
+	utxos, err := client.Client.UnspentOutputs(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	client.utxos[address] = cachedUTXOs{utxos, time.Now().Add(UTXOCacheDuration)}
+	client.mu.Unlock()
+	return append([]UTXO{}, utxos...), nil
+}
+
+func (client *cachedClient) PublishTransaction(ctx context.Context, tx *wire.MsgTx) error {
+	defer client.clear()
+	return client.Client.PublishTransaction(ctx, tx)
+}
+
+func (client *cachedClient) clear() {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.utxos = map[string]cachedUTXOs{}
+}
@@ -12,6 +12,10 @@ var ErrMalformedRedeemTx = fmt.Errorf("redeem transaction returned by the Bitcoi
 var ErrMalformedInitiateTx = fmt.Errorf("initiate transaction returned by the Bitcoin blockchain is malformed")
 var ErrUnknownMessageType = fmt.Errorf("unknown message type")
 var ErrTimedOut = fmt.Errorf("timed out")
+var ErrNotSpent = fmt.Errorf("script has not been spent")
+var ErrSigningUnsupported = fmt.Errorf("account cannot sign transactions without publishing them")
+var ErrTxNotFound = fmt.Errorf("transaction not found")
+var ErrChainClockUnsupported = fmt.Errorf("account cannot read the block height, so only time based timelocks are supported")
 
 func NewErrDecodeAddress(addr string, err error) error {
 	return fmt.Errorf("failed to decode address (%s): %v", addr, err)
@@ -41,6 +45,10 @@ func NewErrScriptExec(err error) error {
 	return fmt.Errorf("script execution error: %v", err)
 }
 
+func NewErrInvalidTimeLock(mode string, blocks int64) error {
+	return fmt.Errorf("invalid timelock of %d blocks in %s mode", blocks, mode)
+}
+
 func NewErrInitiate(err error) error {
 	return fmt.Errorf("failed to initiate: %v", err)
 }
@@ -60,3 +68,11 @@ func NewErrAudit(err error) error {
 func NewErrAuditSecret(err error) error {
 	return fmt.Errorf("failed to audit secret: %v", err)
 }
+
+func NewErrInsufficientBalance(address string, required, current int64) error {
+	return fmt.Errorf("insufficient balance in %s: required = %d, current = %d", address, required, current)
+}
+
+func NewErrUnsupportedAddressType(addressType string) error {
+	return fmt.Errorf("unsupported address type: %s", addressType)
+}
@@ -0,0 +1,36 @@
+package btc
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+)
+
+// BuildInitiateScript exports the initiate script of a swap, and its P2SH
+// address, to the tests.
+func BuildInitiateScript(swap swap.Swap, params *chaincfg.Params) ([]byte, string, error) {
+	return buildInitiateScript(swap, params, btcutil.DecodeAddress)
+}
+
+// NewRedeemScript exports the signature script that redeems a swap to the
+// tests.
+func NewRedeemScript(initiateScript, sig, pubkey []byte, secret [32]byte) ([]byte, error) {
+	return newRedeemScript(initiateScript, sig, pubkey, secret)
+}
+
+// NewRefundScript exports the signature script that refunds a swap to the
+// tests.
+func NewRefundScript(initiateScript, sig, pubkey []byte) ([]byte, error) {
+	return newRefundScript(initiateScript, sig, pubkey)
+}
+
+// ScriptLockTime exports the lock time of the initiate script to the tests.
+func ScriptLockTime(mode string, timelock, blocks int64) (int64, error) {
+	return scriptLockTime(mode, timelock, blocks)
+}
+
+// ScaledFee exports the fee of a transaction that spends many outputs, or has
+// extra outputs, to the tests.
+func ScaledFee(fee int64, inputs, sigScriptSize, extraOutputs int) int64 {
+	return scaledFee(fee, inputs, sigScriptSize, extraOutputs)
+}
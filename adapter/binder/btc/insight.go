@@ -0,0 +1,202 @@
+package btc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+type insightClient struct {
+	url    string
+	params *chaincfg.Params
+}
+
+// NewInsightClient returns a Client that uses the Insight API at the given
+// url, for example "https://insight.litecore.io/api".
+func NewInsightClient(url string, params *chaincfg.Params) Client {
+	return &insightClient{
+		url:    strings.TrimSuffix(url, "/"),
+		params: params,
+	}
+}
+
+func (client *insightClient) NetworkParams() *chaincfg.Params {
+	return client.params
+}
+
+func (client *insightClient) FormatTransactionView(msg, txHash string) string {
+	return fmt.Sprintf("%s, the transaction can be viewed at %s/tx/%s", msg, strings.TrimSuffix(client.url, "/api"), txHash)
+}
+
+func (client *insightClient) UnspentOutputs(ctx context.Context, address string) ([]UTXO, error) {
+	resp := []struct {
+		TxID          string `json:"txid"`
+		Vout          uint32 `json:"vout"`
+		Satoshis      int64  `json:"satoshis"`
+		ScriptPubKey  string `json:"scriptPubKey"`
+		Confirmations int64  `json:"confirmations"`
+	}{}
+	if err := client.get(ctx, fmt.Sprintf("/addr/%s/utxo", address), &resp); err != nil {
+		return nil, err
+	}
+
+	utxos := make([]UTXO, len(resp))
+	for i, utxo := range resp {
+		utxos[i] = UTXO{
+			TxHash:        utxo.TxID,
+			Vout:          utxo.Vout,
+			Amount:        utxo.Satoshis,
+			ScriptPubKey:  utxo.ScriptPubKey,
+			Confirmations: utxo.Confirmations,
+		}
+	}
+	return utxos, nil
+}
+
+func (client *insightClient) SpendingScripts(ctx context.Context, address string) ([][]byte, error) {
+	resp := struct {
+		Txs []struct {
+			Vin []struct {
+				Addr      string `json:"addr"`
+				ScriptSig struct {
+					Hex string `json:"hex"`
+				} `json:"scriptSig"`
+			} `json:"vin"`
+		} `json:"txs"`
+	}{}
+	if err := client.get(ctx, fmt.Sprintf("/txs?address=%s", url.QueryEscape(address)), &resp); err != nil {
+		return nil, err
+	}
+
+	scripts := [][]byte{}
+	for _, tx := range resp.Txs {
+		for _, vin := range tx.Vin {
+			if vin.Addr != address {
+				continue
+			}
+			script, err := hex.DecodeString(vin.ScriptSig.Hex)
+			if err != nil {
+				return nil, NewErrDecodeScript([]byte(vin.ScriptSig.Hex), err)
+			}
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts, nil
+}
+
+func (client *insightClient) PublishTransaction(ctx context.Context, tx *wire.MsgTx) error {
+	buf := new(bytes.Buffer)
+	if err := tx.Serialize(buf); err != nil {
+		return err
+	}
+	data, err := json.Marshal(struct {
+		RawTx string `json:"rawtx"`
+	}{hex.EncodeToString(buf.Bytes())})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", client.url+"/tx/send", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return client.do(ctx, req, nil)
+}
+
+func (client *insightClient) Confirmations(ctx context.Context, txHash string) (int64, error) {
+	resp := struct {
+		Confirmations int64 `json:"confirmations"`
+	}{}
+	if err := client.get(ctx, fmt.Sprintf("/tx/%s", txHash), &resp); err != nil {
+		if statusErr, ok := err.(insightStatusError); ok && statusErr.code == http.StatusNotFound {
+			return 0, ErrTxNotFound
+		}
+		return 0, err
+	}
+	return resp.Confirmations, nil
+}
+
+func (client *insightClient) BlockHeight(ctx context.Context) (int64, error) {
+	resp := struct {
+		Info struct {
+			Blocks int64 `json:"blocks"`
+		} `json:"info"`
+	}{}
+	if err := client.get(ctx, "/status?q=getInfo", &resp); err != nil {
+		return 0, err
+	}
+	return resp.Info.Blocks, nil
+}
+
+// MedianTime returns the median of the times of the last 11 blocks, since the
+// Insight API does not return the median time past.
+func (client *insightClient) MedianTime(ctx context.Context) (int64, error) {
+	resp := struct {
+		Blocks []struct {
+			Time int64 `json:"time"`
+		} `json:"blocks"`
+	}{}
+	if err := client.get(ctx, "/blocks?limit=11", &resp); err != nil {
+		return 0, err
+	}
+	if len(resp.Blocks) == 0 {
+		return 0, fmt.Errorf("no blocks returned by %s", client.url)
+	}
+
+	times := make([]int64, len(resp.Blocks))
+	for i, block := range resp.Blocks {
+		times[i] = block.Time
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[len(times)/2], nil
+}
+
+func (client *insightClient) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequest("GET", client.url+path, nil)
+	if err != nil {
+		return err
+	}
+	return client.do(ctx, req, v)
+}
+
+func (client *insightClient) do(ctx context.Context, req *http.Request, v interface{}) error {
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return insightStatusError{resp.StatusCode, fmt.Sprintf("unexpected status code %d from %s: %s", resp.StatusCode, req.URL.String(), string(data))}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// insightStatusError is returned for responses of the Insight API that are not
+// OK.
+type insightStatusError struct {
+	code int
+	msg  string
+}
+
+func (err insightStatusError) Error() string {
+	return err.msg
+}
@@ -1,11 +1,11 @@
 package btc
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/republicprotocol/swapperd/foundation/swap"
 	"golang.org/x/crypto/ripemd160"
@@ -33,7 +33,7 @@ const AtomicSwapRedeemScriptSize = 1 + 73 + 1 + 33 + 1 + 32 + 1
 //				<foreign_address>
 //			OP_ELSE
 //				<lock_time>
-//				OP_CHECKLOCKTIMEVERIFY
+//				OP_CHECKLOCKTIMEVERIFY | OP_CHECKSEQUENCEVERIFY
 //				OP_DROP
 //				OP_DUP
 //				OP_HASH160
@@ -42,7 +42,10 @@ const AtomicSwapRedeemScriptSize = 1 + 73 + 1 + 33 + 1 + 32 + 1
 //			OP_EQUALVERIFY
 //			OP_CHECKSIG
 //
-func newInitiateScript(pkhMe, pkhThem *[ripemd160.Size]byte, locktime int64, secretHash []byte) ([]byte, error) {
+// The lock time is a Unix time or a block height checked by
+// OP_CHECKLOCKTIMEVERIFY, or a number of blocks after funding checked by
+// OP_CHECKSEQUENCEVERIFY in the sequence mode.
+func newInitiateScript(pkhMe, pkhThem *[ripemd160.Size]byte, mode string, locktime int64, secretHash []byte) ([]byte, error) {
 	b := txscript.NewScriptBuilder()
 
 	b.AddOp(txscript.OP_IF)
@@ -60,7 +63,11 @@ func newInitiateScript(pkhMe, pkhThem *[ripemd160.Size]byte, locktime int64, sec
 	b.AddOp(txscript.OP_ELSE)
 	{
 		b.AddInt64(locktime)
-		b.AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+		if mode == swap.TimeLockModeSequence {
+			b.AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+		} else {
+			b.AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+		}
 		b.AddOp(txscript.OP_DROP)
 		b.AddOp(txscript.OP_DUP)
 		b.AddOp(txscript.OP_HASH160)
@@ -107,37 +114,48 @@ func newRefundScript(initiateScript, sig, pubkey []byte) ([]byte, error) {
 	return b.Script()
 }
 
-func addressToPubKeyHash(addrString string, chainParams *chaincfg.Params) (*btcutil.AddressPubKeyHash, error) {
-	btcAddr, err := btcutil.DecodeAddress(addrString, chainParams)
+func addressToPubKeyHash(addrString string, chainParams *chaincfg.Params, decodeAddress func(string, *chaincfg.Params) (btcutil.Address, error)) (*btcutil.AddressPubKeyHash, error) {
+	btcAddr, err := decodeAddress(addrString, chainParams)
 	if err != nil {
 		return nil, fmt.Errorf("address %s is not "+
 			"intended for use on %v", addrString, chainParams.Name)
 	}
-	addr, ok := btcAddr.(*btcutil.AddressPubKeyHash)
-	if !ok {
-		return nil, errors.New("%s is not p2pkh address")
+	switch addr := btcAddr.(type) {
+	case *btcutil.AddressPubKeyHash:
+		return addr, nil
+	case *btcutil.AddressWitnessPubKeyHash:
+		// The script checks the hash of the public key, which is the same
+		// for the P2WPKH address of a key
+		return btcutil.NewAddressPubKeyHash(addr.Hash160()[:], chainParams)
+	default:
+		return nil, fmt.Errorf("%s is not a p2pkh or p2wpkh address", addrString)
 	}
-	return addr, nil
 }
 
-func buildInitiateScript(swap swap.Swap, Net *chaincfg.Params) ([]byte, string, error) {
+func buildInitiateScript(swap swap.Swap, Net *chaincfg.Params, decodeAddress func(string, *chaincfg.Params) (btcutil.Address, error)) ([]byte, string, error) {
 	// decoding bitcoin addresses
-	FundingAddr, err := addressToPubKeyHash(swap.FundingAddress, Net)
+	FundingAddr, err := addressToPubKeyHash(swap.FundingAddress, Net, decodeAddress)
 	if err != nil {
 		return nil, "", NewErrDecodeAddress(swap.FundingAddress, err)
 	}
 
-	SpendingAddr, err := addressToPubKeyHash(swap.SpendingAddress, Net)
+	SpendingAddr, err := addressToPubKeyHash(swap.SpendingAddress, Net, decodeAddress)
 	if err != nil {
 		return nil, "", NewErrDecodeAddress(swap.SpendingAddress, err)
 	}
 
+	locktime, err := scriptLockTime(swap.TimeLockMode, swap.TimeLock, swap.TimeLockBlocks)
+	if err != nil {
+		return nil, "", NewErrBuildScript(err)
+	}
+
 	// creating atomic swap initiate script, addressScriptHash and script to
 	// deposit bitcoin tokens.
 	initiateScript, err := newInitiateScript(
 		FundingAddr.Hash160(),
 		SpendingAddr.Hash160(),
-		swap.TimeLock,
+		swap.TimeLockMode,
+		locktime,
 		swap.SecretHash[:],
 	)
 	if err != nil {
@@ -150,3 +168,25 @@ func buildInitiateScript(swap swap.Swap, Net *chaincfg.Params) ([]byte, string,
 
 	return initiateScript, initiateScriptP2SH.EncodeAddress(), nil
 }
+
+// scriptLockTime returns the lock time of the initiate script, which is the
+// timelock in the time mode, and the blocks otherwise. Block heights must be
+// below the Unix time threshold, and relative locks must fit in the 16 bits of
+// the sequence number.
+func scriptLockTime(mode string, timelock, blocks int64) (int64, error) {
+	switch mode {
+	case "", swap.TimeLockModeTime:
+		return timelock, nil
+	case swap.TimeLockModeHeight:
+		if blocks <= 0 || blocks >= txscript.LockTimeThreshold {
+			return 0, NewErrInvalidTimeLock(mode, blocks)
+		}
+	case swap.TimeLockModeSequence:
+		if blocks <= 0 || blocks > int64(wire.SequenceLockTimeMask) {
+			return 0, NewErrInvalidTimeLock(mode, blocks)
+		}
+	default:
+		return 0, fmt.Errorf("unknown timelock mode: %s", mode)
+	}
+	return blocks, nil
+}
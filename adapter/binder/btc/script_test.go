@@ -0,0 +1,211 @@
+package btc_test
+
+import (
+	"crypto/sha256"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+)
+
+// The scripts are run by the script engine of btcd, with the flags of the
+// standard transactions, so that they are checked the way nodes check them.
+var _ = Describe("Atomic swap scripts", func() {
+	const amount = 100000
+	params := &chaincfg.RegressionNetParams
+	secret := [32]byte{1, 2, 3}
+	secretHash := sha256.Sum256(secret[:])
+
+	// The keys are built when the specs are declared, a hash of 20 bytes is
+	// always a valid address
+	newKey := func(seed byte) (*btcec.PrivateKey, string) {
+		key, _ := btcec.PrivKeyFromBytes(btcec.S256(), append(make([]byte, 31), seed))
+		address, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160(key.PubKey().SerializeCompressed()), params)
+		return key, address.EncodeAddress()
+	}
+	funderKey, funderAddress := newKey(1)
+	spenderKey, spenderAddress := newKey(2)
+
+	newSwap := func(mode string, timelock, blocks int64) swap.Swap {
+		return swap.Swap{
+			FundingAddress:  funderAddress,
+			SpendingAddress: spenderAddress,
+			SecretHash:      secretHash,
+			TimeLock:        timelock,
+			TimeLockMode:    mode,
+			TimeLockBlocks:  blocks,
+		}
+	}
+
+	// spend returns a transaction that spends the contract, with the lock
+	// time and the sequence of its input set.
+	spend := func(lockTime, sequence uint32) *wire.MsgTx {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+		tx.TxIn[0].Sequence = sequence
+		tx.AddTxOut(wire.NewTxOut(amount-1000, []byte{txscript.OP_TRUE}))
+		tx.LockTime = lockTime
+		return tx
+	}
+
+	// execute runs the signature script of the transaction against the P2SH
+	// output of the contract.
+	execute := func(script []byte, p2sh string, tx *wire.MsgTx) error {
+		address, err := btcutil.DecodeAddress(p2sh, params)
+		Expect(err).Should(BeNil())
+		pkScript, err := txscript.PayToAddrScript(address)
+		Expect(err).Should(BeNil())
+		engine, err := txscript.NewEngine(pkScript, tx, 0, txscript.StandardVerifyFlags, nil, nil, amount)
+		Expect(err).Should(BeNil())
+		return engine.Execute()
+	}
+
+	refund := func(s swap.Swap, key *btcec.PrivateKey, lockTime, sequence uint32) error {
+		script, p2sh, err := BuildInitiateScript(s, params)
+		Expect(err).Should(BeNil())
+		tx := spend(lockTime, sequence)
+		sig, err := txscript.RawTxInSignature(tx, 0, script, txscript.SigHashAll, key)
+		Expect(err).Should(BeNil())
+		tx.TxIn[0].SignatureScript, err = NewRefundScript(script, sig, key.PubKey().SerializeCompressed())
+		Expect(err).Should(BeNil())
+		return execute(tx.TxIn[0].SignatureScript, p2sh, tx)
+	}
+
+	redeem := func(s swap.Swap, key *btcec.PrivateKey, secret [32]byte) error {
+		script, p2sh, err := BuildInitiateScript(s, params)
+		Expect(err).Should(BeNil())
+		tx := spend(0, wire.MaxTxInSequenceNum)
+		sig, err := txscript.RawTxInSignature(tx, 0, script, txscript.SigHashAll, key)
+		Expect(err).Should(BeNil())
+		tx.TxIn[0].SignatureScript, err = NewRedeemScript(script, sig, key.PubKey().SerializeCompressed(), secret)
+		Expect(err).Should(BeNil())
+		return execute(tx.TxIn[0].SignatureScript, p2sh, tx)
+	}
+
+	Context("when redeeming", func() {
+		It("should accept the secret and the key of the spender", func() {
+			Expect(redeem(newSwap(swap.TimeLockModeSequence, 0, 144), spenderKey, secret)).Should(BeNil())
+		})
+
+		It("should reject a wrong secret", func() {
+			Expect(redeem(newSwap(swap.TimeLockModeSequence, 0, 144), spenderKey, [32]byte{})).ShouldNot(BeNil())
+		})
+
+		It("should reject the key of the funder", func() {
+			Expect(redeem(newSwap(swap.TimeLockModeSequence, 0, 144), funderKey, secret)).ShouldNot(BeNil())
+		})
+	})
+
+	Context("when refunding with a relative timelock", func() {
+		s := newSwap(swap.TimeLockModeSequence, 0, 144)
+
+		It("should accept an input that is old enough", func() {
+			Expect(refund(s, funderKey, 0, 144)).Should(BeNil())
+		})
+
+		It("should reject an input that is too young", func() {
+			Expect(refund(s, funderKey, 0, 143)).ShouldNot(BeNil())
+		})
+
+		It("should reject the key of the spender", func() {
+			Expect(refund(s, spenderKey, 0, 144)).ShouldNot(BeNil())
+		})
+	})
+
+	Context("when refunding with a block height", func() {
+		s := newSwap(swap.TimeLockModeHeight, 0, 500)
+
+		It("should accept a transaction locked at the height", func() {
+			Expect(refund(s, funderKey, 500, 0)).Should(BeNil())
+		})
+
+		It("should reject a transaction locked before the height", func() {
+			Expect(refund(s, funderKey, 499, 0)).ShouldNot(BeNil())
+		})
+
+		It("should reject a transaction with a final input", func() {
+			Expect(refund(s, funderKey, 500, wire.MaxTxInSequenceNum)).ShouldNot(BeNil())
+		})
+	})
+
+	Context("when refunding with a unix time", func() {
+		s := newSwap(swap.TimeLockModeTime, 1600000000, 0)
+
+		It("should accept a transaction locked at the time", func() {
+			Expect(refund(s, funderKey, 1600000000, 0)).Should(BeNil())
+		})
+
+		It("should reject a transaction locked before the time", func() {
+			Expect(refund(s, funderKey, 1599999999, 0)).ShouldNot(BeNil())
+		})
+
+		It("should reject a transaction locked at a block height", func() {
+			Expect(refund(s, funderKey, 500, 0)).ShouldNot(BeNil())
+		})
+	})
+
+	Context("when building the initiate script", func() {
+		It("should use the same script for the P2WPKH address of a key", func() {
+			s := newSwap(swap.TimeLockModeSequence, 0, 144)
+			script, p2sh, err := BuildInitiateScript(s, params)
+			Expect(err).Should(BeNil())
+
+			witnessAddress, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(spenderKey.PubKey().SerializeCompressed()), params)
+			Expect(err).Should(BeNil())
+			s.SpendingAddress = witnessAddress.EncodeAddress()
+			witnessScript, witnessP2SH, err := BuildInitiateScript(s, params)
+			Expect(err).Should(BeNil())
+			Expect(witnessScript).Should(Equal(script))
+			Expect(witnessP2SH).Should(Equal(p2sh))
+		})
+
+		It("should reject invalid blocks", func() {
+			_, err := ScriptLockTime(swap.TimeLockModeSequence, 0, 0)
+			Expect(err).ShouldNot(BeNil())
+			_, err = ScriptLockTime(swap.TimeLockModeSequence, 0, int64(wire.SequenceLockTimeMask)+1)
+			Expect(err).ShouldNot(BeNil())
+			_, err = ScriptLockTime(swap.TimeLockModeHeight, 0, txscript.LockTimeThreshold)
+			Expect(err).ShouldNot(BeNil())
+			_, err = ScriptLockTime("blocks", 0, 144)
+			Expect(err).ShouldNot(BeNil())
+		})
+
+		It("should use the timelock in the time mode and the blocks otherwise", func() {
+			Expect(ScriptLockTime(swap.TimeLockModeTime, 1600000000, 144)).Should(Equal(int64(1600000000)))
+			Expect(ScriptLockTime("", 1600000000, 144)).Should(Equal(int64(1600000000)))
+			Expect(ScriptLockTime(swap.TimeLockModeHeight, 1600000000, 500)).Should(Equal(int64(500)))
+			Expect(ScriptLockTime(swap.TimeLockModeSequence, 1600000000, 144)).Should(Equal(int64(144)))
+		})
+	})
+})
+
+var _ = Describe("Sweep fees", func() {
+	// The signature script of a refund, with a contract script of 100 bytes
+	const sigScriptSize = AtomicSwapRefundScriptSize + 100 + 3
+
+	It("should keep the fee of a single input", func() {
+		Expect(ScaledFee(10000, 1, sigScriptSize, 0)).Should(Equal(int64(10000)))
+	})
+
+	It("should scale the fee with the size of the inputs", func() {
+		two := ScaledFee(10000, 2, sigScriptSize, 0)
+		three := ScaledFee(10000, 3, sigScriptSize, 0)
+		Expect(two).Should(BeNumerically(">", 10000))
+		Expect(two).Should(BeNumerically("<", 20000))
+		Expect(three - two).Should(BeNumerically("~", two-10000, 1))
+	})
+
+	It("should scale the fee with the size of the extra outputs", func() {
+		fee := ScaledFee(10000, 1, sigScriptSize, 1)
+		Expect(fee).Should(BeNumerically(">", 10000))
+		Expect(fee).Should(BeNumerically("<", ScaledFee(10000, 2, sigScriptSize, 0)))
+		Expect(ScaledFee(10000, 2, sigScriptSize, 1) - ScaledFee(10000, 2, sigScriptSize, 0)).Should(BeNumerically("~", fee-10000, 1))
+	})
+})
@@ -0,0 +1,40 @@
+package btc
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// DefaultFee is the fee, in satoshis, of transfers from an Account.
+const DefaultFee = 10000
+
+// SigHashForkID is the flag that Bitcoin Cash signatures must set, to
+// protect against replaying transactions on Bitcoin.
+const SigHashForkID = txscript.SigHashType(0x40)
+
+// A SignatureFunc signs the input of a transaction spending the given amount
+// from an output locked by the sub script. It returns the signature followed
+// by its hash type.
+type SignatureFunc func(tx *wire.MsgTx, idx int, subScript []byte, amount int64, key *btcec.PrivateKey) ([]byte, error)
+
+// LegacySignature signs the input using the original Bitcoin signature hash,
+// as used by Bitcoin and Litecoin.
+func LegacySignature(tx *wire.MsgTx, idx int, subScript []byte, amount int64, key *btcec.PrivateKey) ([]byte, error) {
+	return txscript.RawTxInSignature(tx, idx, subScript, txscript.SigHashAll, key)
+}
+
+// ForkIDSignature signs the input using the BIP143 signature hash with the
+// fork id flag, as used by Bitcoin Cash.
+func ForkIDSignature(tx *wire.MsgTx, idx int, subScript []byte, amount int64, key *btcec.PrivateKey) ([]byte, error) {
+	hashType := txscript.SigHashAll | SigHashForkID
+	hash, err := txscript.CalcWitnessSigHash(subScript, txscript.NewTxSigHashes(tx), hashType, tx, idx, amount)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := key.Sign(hash)
+	if err != nil {
+		return nil, err
+	}
+	return append(sig.Serialize(), byte(hashType)), nil
+}
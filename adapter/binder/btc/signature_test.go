@@ -0,0 +1,77 @@
+package btc_test
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/adapter/binder/btc"
+)
+
+// The vector is the native P2WPKH example of BIP143, whose second input is
+// signed with the BIP143 signature hash. Bitcoin Cash uses the same signature
+// hash, with the fork id flag in its hash type.
+var _ = Describe("Signatures", func() {
+	const (
+		unsignedTx = "0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f0000000000eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac11000000"
+		scriptCode = "76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac"
+		privKey    = "619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9"
+		sigHash    = "c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670"
+		amount     = 600000000
+	)
+
+	decode := func() (*wire.MsgTx, []byte, *btcec.PrivateKey) {
+		data, err := hex.DecodeString(unsignedTx)
+		Expect(err).Should(BeNil())
+		tx := wire.NewMsgTx(wire.TxVersion)
+		Expect(tx.Deserialize(bytes.NewReader(data))).Should(BeNil())
+		script, err := hex.DecodeString(scriptCode)
+		Expect(err).Should(BeNil())
+		keyData, err := hex.DecodeString(privKey)
+		Expect(err).Should(BeNil())
+		key, _ := btcec.PrivKeyFromBytes(btcec.S256(), keyData)
+		return tx, script, key
+	}
+
+	It("should compute the BIP143 signature hash of the reference vector", func() {
+		tx, script, _ := decode()
+		hash, err := txscript.CalcWitnessSigHash(script, txscript.NewTxSigHashes(tx), txscript.SigHashAll, tx, 1, amount)
+		Expect(err).Should(BeNil())
+		Expect(hex.EncodeToString(hash)).Should(Equal(sigHash))
+	})
+
+	It("should sign with the BIP143 signature hash and the fork id flag", func() {
+		tx, script, key := decode()
+		sig, err := ForkIDSignature(tx, 1, script, amount, key)
+		Expect(err).Should(BeNil())
+
+		hashType := txscript.SigHashAll | SigHashForkID
+		Expect(sig[len(sig)-1]).Should(Equal(byte(0x41)))
+		Expect(sig[len(sig)-1]).Should(Equal(byte(hashType)))
+
+		hash, err := txscript.CalcWitnessSigHash(script, txscript.NewTxSigHashes(tx), hashType, tx, 1, amount)
+		Expect(err).Should(BeNil())
+		Expect(hex.EncodeToString(hash)).ShouldNot(Equal(sigHash))
+
+		signature, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+		Expect(err).Should(BeNil())
+		Expect(signature.Verify(hash, key.PubKey())).Should(BeTrue())
+	})
+
+	It("should sign with the legacy signature hash", func() {
+		tx, script, key := decode()
+		sig, err := LegacySignature(tx, 1, script, amount, key)
+		Expect(err).Should(BeNil())
+		Expect(sig[len(sig)-1]).Should(Equal(byte(txscript.SigHashAll)))
+
+		hash, err := txscript.CalcSignatureHash(script, txscript.SigHashAll, tx, 1)
+		Expect(err).Should(BeNil())
+		signature, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+		Expect(err).Should(BeNil())
+		Expect(signature.Verify(hash, key.PubKey())).Should(BeTrue())
+	})
+})
@@ -11,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/republicprotocol/beth-go"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
 	"github.com/republicprotocol/swapperd/core/swapper/immediate"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
 	"github.com/republicprotocol/swapperd/foundation/swap"
@@ -26,21 +27,18 @@ type erc20SwapContractBinder struct {
 	tokenAddress   common.Address
 	swapperBinder  *SwapperdERC20
 	tokenBinder    *CompatibleERC20
+	watcher        eth.Watcher
+	allowance      *big.Int
+	oracle         eth.GasPriceOracle
 	cost           blockchain.Cost
 }
 
-// NewERC20SwapContractBinder returns a new ERC20 Atom instance
-func NewERC20SwapContractBinder(account beth.Account, swap swap.Swap, cost blockchain.Cost, logger logrus.FieldLogger) (immediate.Contract, error) {
-	tokenAddress, err := account.ReadAddress(fmt.Sprintf("%s", swap.Token.Name))
-	if err != nil {
-		return nil, err
-	}
-
-	swapperAddress, err := account.ReadAddress(fmt.Sprintf("Swapperd%s", swap.Token.Name))
-	if err != nil {
-		return nil, err
-	}
-
+// NewERC20SwapContractBinder returns a new ERC20 Atom instance. The gas price
+// of every transaction is chosen by the oracle. The watcher is optional, when
+// it is nil the contract is polled directly. When the standing allowance is
+// not nil, the swapper contract is approved for at least that amount so that
+// following swaps do not need to approve again.
+func NewERC20SwapContractBinder(account beth.Account, tokenAddress, swapperAddress common.Address, swap swap.Swap, cost blockchain.Cost, allowance *big.Int, oracle eth.GasPriceOracle, watcher eth.Watcher, logger logrus.FieldLogger) (immediate.Contract, error) {
 	tokenBinder, err := NewCompatibleERC20(tokenAddress, bind.ContractBackend(account.EthClient()))
 	if err != nil {
 		return nil, err
@@ -62,6 +60,10 @@ func NewERC20SwapContractBinder(account beth.Account, swap swap.Swap, cost block
 	fields["Token"] = swap.Token.Name
 	logger = logger.WithFields(fields)
 
+	if watcher != nil {
+		watcher.Watch(swapperAddress, id)
+	}
+
 	if _, ok := cost[blockchain.ETH]; !ok {
 		cost[blockchain.ETH] = big.NewInt(0)
 	}
@@ -76,6 +78,9 @@ func NewERC20SwapContractBinder(account beth.Account, swap swap.Swap, cost block
 		tokenAddress:   tokenAddress,
 		swapperBinder:  swapperBinder,
 		tokenBinder:    tokenBinder,
+		watcher:        watcher,
+		allowance:      allowance,
+		oracle:         oracle,
 		logger:         logger,
 		swap:           swap,
 		id:             id,
@@ -99,51 +104,39 @@ func (atom *erc20SwapContractBinder) Initiate() error {
 	}
 	atom.logger.Info(fmt.Sprintf("Initiating on Ethereum blockchain"))
 
-	// Approve the contract to transfer tokens
-	if err := atom.account.Transact(
-		ctx,
-		nil,
-		func(tops *bind.TransactOpts) (*types.Transaction, error) {
-			tops.GasPrice = atom.swap.Fee
-			tx, err := atom.tokenBinder.Approve(tops, atom.swapperAddress, atom.swap.Value)
-			if err != nil {
-				return tx, err
-			}
-			txFee := new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(tx.Gas())))
-			atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], txFee)
-			msg, _ := atom.account.FormatTransactionView("Approved on Ethereum blockchain", tx.Hash().String())
-			atom.logger.Info(msg)
-			return tx, nil
-		},
-		nil,
-		1,
-	); err != nil {
+	if err := atom.ensureAllowance(ctx); err != nil {
 		return err
 	}
 
 	// Initiate the Atomic Swap
+	sent := []*types.Transaction{}
+	defer func() { atom.recordFees(sent) }()
 	return atom.account.Transact(
 		ctx,
 		nil,
 		func(tops *bind.TransactOpts) (*types.Transaction, error) {
-			tops.GasPrice = atom.swap.Fee
 			var tx *types.Transaction
 			var err error
 			if atom.swap.BrokerFee.Cmp(big.NewInt(0)) > 0 {
+				if err := atom.setFees(ctx, tops, atom.swapperAddress, SwapperdERC20ABI, "initiateWithFees", atom.id, common.HexToAddress(atom.swap.SpendingAddress), common.HexToAddress(atom.swap.BrokerAddress), atom.swap.BrokerFee, atom.swap.SecretHash, big.NewInt(atom.swap.TimeLock), atom.swap.Value); err != nil {
+					return nil, err
+				}
 				tx, err = atom.swapperBinder.InitiateWithFees(tops, atom.id, common.HexToAddress(atom.swap.SpendingAddress), common.HexToAddress(atom.swap.BrokerAddress), atom.swap.BrokerFee, atom.swap.SecretHash, big.NewInt(atom.swap.TimeLock), atom.swap.Value)
 				if err != nil {
 					return tx, err
 				}
 				atom.cost[atom.swap.Token.Name] = new(big.Int).Add(atom.cost[atom.swap.Token.Name], atom.swap.BrokerFee)
 			} else {
+				if err := atom.setFees(ctx, tops, atom.swapperAddress, SwapperdERC20ABI, "initiate", atom.id, common.HexToAddress(atom.swap.SpendingAddress), atom.swap.SecretHash, big.NewInt(atom.swap.TimeLock), atom.swap.Value); err != nil {
+					return nil, err
+				}
 				tx, err = atom.swapperBinder.Initiate(tops, atom.id, common.HexToAddress(atom.swap.SpendingAddress), atom.swap.SecretHash, big.NewInt(atom.swap.TimeLock), atom.swap.Value)
 				if err != nil {
 					return tx, err
 				}
 			}
 
-			txFee := new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(tx.Gas())))
-			atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], txFee)
+			sent = append(sent, tx)
 
 			msg, _ := atom.account.FormatTransactionView("Initiated on Ethereum blockchain", tx.Hash().String())
 			atom.logger.Info(msg)
@@ -160,11 +153,64 @@ func (atom *erc20SwapContractBinder) Initiate() error {
 	)
 }
 
+// ensureAllowance approves the swapper contract to transfer the swap value,
+// unless the current allowance already covers it.
+func (atom *erc20SwapContractBinder) ensureAllowance(ctx context.Context) error {
+	allowance, err := atom.tokenBinder.Allowance(&bind.CallOpts{}, atom.account.Address(), atom.swapperAddress)
+	if err != nil {
+		return err
+	}
+	if allowance.Cmp(atom.swap.Value) >= 0 {
+		atom.logger.Info(fmt.Sprintf("Skipping approve on Ethereum blockchain, allowance = %v", allowance))
+		return nil
+	}
+
+	// Some tokens require the allowance to be reset to zero before it can be
+	// changed
+	if allowance.Cmp(big.NewInt(0)) > 0 {
+		if err := atom.approve(ctx, big.NewInt(0)); err != nil {
+			return err
+		}
+	}
+
+	value := atom.swap.Value
+	if atom.allowance != nil && atom.allowance.Cmp(value) > 0 {
+		value = atom.allowance
+	}
+	return atom.approve(ctx, value)
+}
+
+func (atom *erc20SwapContractBinder) approve(ctx context.Context, value *big.Int) error {
+	sent := []*types.Transaction{}
+	defer func() { atom.recordFees(sent) }()
+	return atom.account.Transact(
+		ctx,
+		nil,
+		func(tops *bind.TransactOpts) (*types.Transaction, error) {
+			if err := atom.setFees(ctx, tops, atom.tokenAddress, CompatibleERC20ABI, "approve", atom.swapperAddress, value); err != nil {
+				return nil, err
+			}
+			tx, err := atom.tokenBinder.Approve(tops, atom.swapperAddress, value)
+			if err != nil {
+				return tx, err
+			}
+			sent = append(sent, tx)
+			msg, _ := atom.account.FormatTransactionView("Approved on Ethereum blockchain", tx.Hash().String())
+			atom.logger.Info(msg)
+			return tx, nil
+		},
+		nil,
+		1,
+	)
+}
+
 // Refund an Atom swap by calling a function on ethereum
 func (atom *erc20SwapContractBinder) Refund() error {
 	atom.logger.Info("Refunding on Ethereum blockchain")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
+	sent := []*types.Transaction{}
+	defer func() { atom.recordFees(sent) }()
 	if err := atom.account.Transact(
 		ctx,
 		func() bool {
@@ -175,14 +221,15 @@ func (atom *erc20SwapContractBinder) Refund() error {
 			return refundable
 		},
 		func(tops *bind.TransactOpts) (*types.Transaction, error) {
-			tops.GasPrice = atom.swap.Fee
+			if err := atom.setFees(ctx, tops, atom.swapperAddress, SwapperdERC20ABI, "refund", atom.id); err != nil {
+				return nil, err
+			}
 			tx, err := atom.swapperBinder.Refund(tops, atom.id)
 			if err != nil {
 				return nil, err
 			}
 
-			txFee := new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(tx.Gas())))
-			atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], txFee)
+			sent = append(sent, tx)
 
 			msg, _ := atom.account.FormatTransactionView("Refunded on Ethereum blockchain", tx.Hash().String())
 			atom.logger.Info(msg)
@@ -205,6 +252,16 @@ func (atom *erc20SwapContractBinder) Refund() error {
 // AuditSecret audits the secret of an Atom swap by calling a function on ethereum
 func (atom *erc20SwapContractBinder) AuditSecret() ([32]byte, error) {
 	atom.logger.Info("Auditing secret on Ethereum blockchain")
+	if atom.watcher != nil && time.Now().Unix() <= atom.swap.TimeLock {
+		if secret, ok := atom.watcher.Secret(atom.swapperAddress, atom.id); ok {
+			atom.logger.Info(fmt.Sprintf("Audit succeeded on Ethereum blockchain secret = %s", base64.StdEncoding.EncodeToString(secret[:])))
+			return secret, nil
+		}
+		if atom.watcher.Covers(atom.swapperAddress, atom.id) {
+			return [32]byte{}, immediate.ErrAuditPending
+		}
+	}
+
 	redeemable, err := atom.swapperBinder.Redeemable(&bind.CallOpts{}, atom.id)
 	if err != nil {
 		atom.logger.Error(err)
@@ -230,7 +287,7 @@ func (atom *erc20SwapContractBinder) AuditSecret() ([32]byte, error) {
 // Audit an Atom swap by calling a function on ethereum
 func (atom *erc20SwapContractBinder) Audit() error {
 	atom.logger.Info(fmt.Sprintf("Waiting for initiation on Ethereum blockchain"))
-	initiatable, err := atom.swapperBinder.Initiatable(&bind.CallOpts{}, atom.id)
+	initiatable, err := atom.initiatable()
 	if err != nil {
 		atom.logger.Error(err)
 		return err
@@ -250,18 +307,42 @@ func (atom *erc20SwapContractBinder) Audit() error {
 		return err
 	}
 
-	value := new(big.Int).Sub(atom.swap.Value, atom.swap.BrokerFee)
-	if auditReport.Value.Cmp(value) != 0 {
-		return fmt.Errorf("Receive value mismatch: expected %v, got %v", atom.swap.Value, auditReport.Value)
+	if err := eth.VerifyAudit(atom.swap, eth.AuditReport{
+		Timelock:   auditReport.Timelock,
+		Value:      auditReport.Value,
+		To:         auditReport.To,
+		BrokerFee:  auditReport.BrokerFee,
+		Broker:     auditReport.Broker,
+		From:       auditReport.From,
+		SecretLock: auditReport.SecretLock,
+	}); err != nil {
+		atom.logger.Error(err)
+		if time.Now().Unix() > atom.swap.TimeLock {
+			return immediate.ErrSwapExpired
+		}
+		return err
 	}
 	atom.logger.Info(fmt.Sprintf("Audit successful on Ethereum blockchain"))
 	return nil
 }
 
+// initiatable uses the watcher, when there is one and it covers the swap, to
+// avoid polling the contract until the swap has been opened. Once the swap has
+// expired the contract is always checked, in case the watcher is lagging
+// behind.
+func (atom *erc20SwapContractBinder) initiatable() (bool, error) {
+	if atom.watcher != nil && atom.watcher.Covers(atom.swapperAddress, atom.id) && time.Now().Unix() <= atom.swap.TimeLock && !atom.watcher.Opened(atom.swapperAddress, atom.id) {
+		return true, nil
+	}
+	return atom.swapperBinder.Initiatable(&bind.CallOpts{}, atom.id)
+}
+
 // Redeem an Atom swap by calling a function on ethereum
 func (atom *erc20SwapContractBinder) Redeem(secret [32]byte) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
+	sent := []*types.Transaction{}
+	defer func() { atom.recordFees(sent) }()
 	if err := atom.account.Transact(
 		ctx,
 		func() bool {
@@ -272,14 +353,15 @@ func (atom *erc20SwapContractBinder) Redeem(secret [32]byte) error {
 			return redeemable
 		},
 		func(tops *bind.TransactOpts) (*types.Transaction, error) {
-			tops.GasPrice = atom.swap.Fee
+			if err := atom.setFees(ctx, tops, atom.swapperAddress, SwapperdERC20ABI, "redeem", atom.id, secret); err != nil {
+				return nil, err
+			}
 			tx, err := atom.swapperBinder.Redeem(tops, atom.id, secret)
 			if err != nil {
 				return nil, err
 			}
 
-			txFee := new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(tx.Gas())))
-			atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], txFee)
+			sent = append(sent, tx)
 
 			msg, _ := atom.account.FormatTransactionView("Redeemed the atomic swap on Ethereum blockchain", tx.Hash().String())
 			atom.logger.Info(msg)
@@ -305,3 +387,22 @@ func (atom *erc20SwapContractBinder) Redeem(secret [32]byte) error {
 func (atom *erc20SwapContractBinder) Cost() blockchain.Cost {
 	return atom.cost
 }
+
+// recordFees adds the fees paid by the mined transactions to the cost of the
+// swap.
+func (atom *erc20SwapContractBinder) recordFees(txs []*types.Transaction) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], eth.PaidFees(ctx, atom.account.EthClient(), txs))
+}
+
+// setFees sets the gas price and gas limit of a call to the swapper or token
+// contract.
+func (atom *erc20SwapContractBinder) setFees(ctx context.Context, tops *bind.TransactOpts, contract common.Address, contractABI, method string, args ...interface{}) error {
+	gasPrice, err := eth.SetFees(ctx, atom.oracle, atom.account.EthClient(), tops, contract, contractABI, method, args...)
+	if err != nil {
+		return err
+	}
+	atom.logger.Info(fmt.Sprintf("Chose gas price = %v and gas limit = %v for %s", gasPrice.GasPrice, tops.GasLimit, method))
+	return nil
+}
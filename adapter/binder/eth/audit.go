@@ -0,0 +1,50 @@
+package eth
+
+import (
+	"encoding/base64"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/republicprotocol/swapperd/core/swapper/immediate"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+)
+
+// An AuditReport is the state of a swap initiated in a Swapperd contract.
+type AuditReport struct {
+	Timelock   *big.Int
+	Value      *big.Int
+	To         common.Address
+	BrokerFee  *big.Int
+	Broker     common.Address
+	From       common.Address
+	SecretLock [32]byte
+}
+
+// VerifyAudit checks every field of the initiated swap against the agreed
+// swap details, so that the counterparty cannot underfund or redirect it. It
+// is shared by the ETH and ERC20 Swapperd contracts.
+func VerifyAudit(swap swap.Swap, report AuditReport) error {
+	if report.Timelock.Cmp(big.NewInt(swap.TimeLock)) != 0 {
+		return immediate.NewErrAuditMismatch("timelock", swap.TimeLock, report.Timelock)
+	}
+	expectedValue := new(big.Int).Sub(swap.Value, swap.BrokerFee)
+	if report.Value.Cmp(expectedValue) != 0 {
+		return immediate.NewErrAuditMismatch("value", expectedValue, report.Value)
+	}
+	if report.To != common.HexToAddress(swap.SpendingAddress) {
+		return immediate.NewErrAuditMismatch("spender", swap.SpendingAddress, report.To.String())
+	}
+	if report.From != common.HexToAddress(swap.FundingAddress) {
+		return immediate.NewErrAuditMismatch("funder", swap.FundingAddress, report.From.String())
+	}
+	if report.BrokerFee.Cmp(swap.BrokerFee) != 0 {
+		return immediate.NewErrAuditMismatch("broker fee", swap.BrokerFee, report.BrokerFee)
+	}
+	if swap.BrokerFee.Cmp(big.NewInt(0)) > 0 && report.Broker != common.HexToAddress(swap.BrokerAddress) {
+		return immediate.NewErrAuditMismatch("broker", swap.BrokerAddress, report.Broker.String())
+	}
+	if report.SecretLock != swap.SecretHash {
+		return immediate.NewErrAuditMismatch("secret lock", base64.StdEncoding.EncodeToString(swap.SecretHash[:]), base64.StdEncoding.EncodeToString(report.SecretLock[:]))
+	}
+	return nil
+}
@@ -18,21 +18,21 @@ import (
 )
 
 type ethSwapContractBinder struct {
-	id      [32]byte
-	account beth.Account
-	swap    swap.Swap
-	logger  logrus.FieldLogger
-	binder  *SwapperdEth
-	cost    blockchain.Cost
+	id             [32]byte
+	account        beth.Account
+	swap           swap.Swap
+	logger         logrus.FieldLogger
+	swapperAddress common.Address
+	binder         *SwapperdEth
+	watcher        Watcher
+	oracle         GasPriceOracle
+	cost           blockchain.Cost
 }
 
-// NewETHSwapContractBinder returns a new Ethereum RequestAtom instance
-func NewETHSwapContractBinder(account beth.Account, swap swap.Swap, cost blockchain.Cost, logger logrus.FieldLogger) (immediate.Contract, error) {
-	swapperAddr, err := account.ReadAddress("SwapperdETH")
-	if err != nil {
-		return nil, err
-	}
-
+// NewETHSwapContractBinder returns a new Ethereum RequestAtom instance. The
+// gas price of every transaction is chosen by the oracle. The watcher is
+// optional, when it is nil the contract is polled directly.
+func NewETHSwapContractBinder(account beth.Account, swapperAddr common.Address, swap swap.Swap, cost blockchain.Cost, oracle GasPriceOracle, watcher Watcher, logger logrus.FieldLogger) (immediate.Contract, error) {
 	contract, err := NewSwapperdEth(swapperAddr, bind.ContractBackend(account.EthClient()))
 	if err != nil {
 		return nil, err
@@ -49,18 +49,25 @@ func NewETHSwapContractBinder(account beth.Account, swap swap.Swap, cost blockch
 	fields["Token"] = swap.Token.Name
 	logger = logger.WithFields(fields)
 
+	if watcher != nil {
+		watcher.Watch(swapperAddr, id)
+	}
+
 	if _, ok := cost[blockchain.ETH]; !ok {
 		cost[blockchain.ETH] = big.NewInt(0)
 	}
 
 	logger.Info(swap.ID, fmt.Sprintf("Ethereum Atomic Swap ID: %s", base64.StdEncoding.EncodeToString(id[:])))
 	return &ethSwapContractBinder{
-		account: account,
-		binder:  contract,
-		logger:  logger,
-		swap:    swap,
-		id:      id,
-		cost:    cost,
+		account:        account,
+		swapperAddress: swapperAddr,
+		binder:         contract,
+		watcher:        watcher,
+		oracle:         oracle,
+		logger:         logger,
+		swap:           swap,
+		id:             id,
+		cost:           cost,
 	}, nil
 }
 
@@ -72,6 +79,8 @@ func (atom *ethSwapContractBinder) Initiate() error {
 	defer cancel()
 
 	// Initiate the Atomic Swap
+	sent := []*types.Transaction{}
+	defer func() { atom.recordFees(sent) }()
 	if err := atom.account.Transact(
 		ctx,
 		func() bool {
@@ -82,11 +91,13 @@ func (atom *ethSwapContractBinder) Initiate() error {
 			return initiatable
 		},
 		func(tops *bind.TransactOpts) (*types.Transaction, error) {
-			tops.GasPrice = atom.swap.Fee
 			tops.Value = atom.swap.Value
 			var tx *types.Transaction
 			var err error
 			if atom.swap.BrokerFee.Cmp(big.NewInt(0)) > 0 {
+				if err := atom.setFees(ctx, tops, "initiateWithFees", atom.id, common.HexToAddress(atom.swap.SpendingAddress), common.HexToAddress(atom.swap.BrokerAddress), atom.swap.BrokerFee, atom.swap.SecretHash, big.NewInt(atom.swap.TimeLock), atom.swap.Value); err != nil {
+					return nil, err
+				}
 				tx, err = atom.binder.InitiateWithFees(tops, atom.id, common.HexToAddress(atom.swap.SpendingAddress), common.HexToAddress(atom.swap.BrokerAddress), atom.swap.BrokerFee, atom.swap.SecretHash, big.NewInt(atom.swap.TimeLock), atom.swap.Value)
 				if err != nil {
 					return tx, err
@@ -94,14 +105,16 @@ func (atom *ethSwapContractBinder) Initiate() error {
 
 				atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], atom.swap.BrokerFee)
 			} else {
+				if err := atom.setFees(ctx, tops, "initiate", atom.id, common.HexToAddress(atom.swap.SpendingAddress), atom.swap.SecretHash, big.NewInt(atom.swap.TimeLock), atom.swap.Value); err != nil {
+					return nil, err
+				}
 				tx, err = atom.binder.Initiate(tops, atom.id, common.HexToAddress(atom.swap.SpendingAddress), atom.swap.SecretHash, big.NewInt(atom.swap.TimeLock), atom.swap.Value)
 				if err != nil {
 					return tx, err
 				}
 			}
 
-			txFee := new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(tx.Gas())))
-			atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], txFee)
+			sent = append(sent, tx)
 
 			tops.Value = big.NewInt(0)
 			msg, _ := atom.account.FormatTransactionView("Initiated the atomic swap", tx.Hash().String())
@@ -129,6 +142,8 @@ func (atom *ethSwapContractBinder) Refund() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
+	sent := []*types.Transaction{}
+	defer func() { atom.recordFees(sent) }()
 	if err := atom.account.Transact(
 		ctx,
 		func() bool {
@@ -139,14 +154,15 @@ func (atom *ethSwapContractBinder) Refund() error {
 			return refundable
 		},
 		func(tops *bind.TransactOpts) (*types.Transaction, error) {
-			tops.GasPrice = atom.swap.Fee
+			if err := atom.setFees(ctx, tops, "refund", atom.id); err != nil {
+				return nil, err
+			}
 			tx, err := atom.binder.Refund(tops, atom.id)
 			if err != nil {
 				return nil, err
 			}
 
-			txFee := new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(tx.Gas())))
-			atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], txFee)
+			sent = append(sent, tx)
 
 			msg, _ := atom.account.FormatTransactionView("Refunded the atomic swap", tx.Hash().String())
 			atom.logger.Info(msg)
@@ -169,6 +185,16 @@ func (atom *ethSwapContractBinder) Refund() error {
 // AuditSecret audits the secret of an Atom swap by calling a function on ethereum
 func (atom *ethSwapContractBinder) AuditSecret() ([32]byte, error) {
 	atom.logger.Info("Auditing secret on ethereum blockchain")
+	if atom.watcher != nil && time.Now().Unix() <= atom.swap.TimeLock {
+		if secret, ok := atom.watcher.Secret(atom.swapperAddress, atom.id); ok {
+			atom.logger.Info(fmt.Sprintf("Audit success on ethereum blockchain secret=%s", base64.StdEncoding.EncodeToString(secret[:])))
+			return secret, nil
+		}
+		if atom.watcher.Covers(atom.swapperAddress, atom.id) {
+			return [32]byte{}, immediate.ErrAuditPending
+		}
+	}
+
 	redeemable, err := atom.binder.Redeemable(&bind.CallOpts{}, atom.id)
 	if err != nil {
 		atom.logger.Error(err)
@@ -193,7 +219,7 @@ func (atom *ethSwapContractBinder) AuditSecret() ([32]byte, error) {
 // Audit an Atom swap by calling a function on ethereum
 func (atom *ethSwapContractBinder) Audit() error {
 	atom.logger.Info(fmt.Sprintf("Waiting for initiation on ethereum blockchain"))
-	initiatable, err := atom.binder.Initiatable(&bind.CallOpts{}, atom.id)
+	initiatable, err := atom.initiatable()
 	if err != nil {
 		atom.logger.Error(err)
 		return err
@@ -212,15 +238,36 @@ func (atom *ethSwapContractBinder) Audit() error {
 		return err
 	}
 
-	value := new(big.Int).Sub(atom.swap.Value, atom.swap.BrokerFee)
-	if auditReport.Value.Cmp(value) != 0 {
-		atom.logger.Error(fmt.Errorf("Receive Value Mismatch Expected: %v Actual: %v", atom.swap.Value, auditReport.Value))
-		return fmt.Errorf("Receive Value Mismatch Expected: %v Actual: %v", atom.swap.Value, auditReport.Value)
+	if err := VerifyAudit(atom.swap, AuditReport{
+		Timelock:   auditReport.Timelock,
+		Value:      auditReport.Value,
+		To:         auditReport.To,
+		BrokerFee:  auditReport.BrokerFee,
+		Broker:     auditReport.Broker,
+		From:       auditReport.From,
+		SecretLock: auditReport.SecretLock,
+	}); err != nil {
+		atom.logger.Error(err)
+		if time.Now().Unix() > atom.swap.TimeLock {
+			return immediate.ErrSwapExpired
+		}
+		return err
 	}
 	atom.logger.Info(fmt.Sprintf("Audit successful"))
 	return nil
 }
 
+// initiatable uses the watcher, when there is one and it covers the swap, to
+// avoid polling the contract until the swap has been opened. Once the swap has
+// expired the contract is always checked, in case the watcher is lagging
+// behind.
+func (atom *ethSwapContractBinder) initiatable() (bool, error) {
+	if atom.watcher != nil && atom.watcher.Covers(atom.swapperAddress, atom.id) && time.Now().Unix() <= atom.swap.TimeLock && !atom.watcher.Opened(atom.swapperAddress, atom.id) {
+		return true, nil
+	}
+	return atom.binder.Initiatable(&bind.CallOpts{}, atom.id)
+}
+
 // Redeem an Atom swap by calling a function on ethereum
 func (atom *ethSwapContractBinder) Redeem(secret [32]byte) error {
 	atom.logger.Info("Redeeming the atomic swap")
@@ -228,6 +275,8 @@ func (atom *ethSwapContractBinder) Redeem(secret [32]byte) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
+	sent := []*types.Transaction{}
+	defer func() { atom.recordFees(sent) }()
 	if err := atom.account.Transact(
 		ctx,
 		func() bool {
@@ -238,14 +287,15 @@ func (atom *ethSwapContractBinder) Redeem(secret [32]byte) error {
 			return redeemable
 		},
 		func(tops *bind.TransactOpts) (*types.Transaction, error) {
-			tops.GasPrice = atom.swap.Fee
+			if err := atom.setFees(ctx, tops, "redeem", atom.id, secret); err != nil {
+				return nil, err
+			}
 			tx, err := atom.binder.Redeem(tops, atom.id, secret)
 			if err != nil {
 				return nil, err
 			}
 
-			txFee := new(big.Int).Mul(tx.GasPrice(), big.NewInt(int64(tx.Gas())))
-			atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], txFee)
+			sent = append(sent, tx)
 
 			msg, _ := atom.account.FormatTransactionView("Redeemed the atomic swap on Ethereum blockchain", tx.Hash().String())
 			atom.logger.Info(msg)
@@ -265,6 +315,24 @@ func (atom *ethSwapContractBinder) Redeem(secret [32]byte) error {
 	return nil
 }
 
+// setFees sets the gas price and gas limit of a call to the swapper contract.
+func (atom *ethSwapContractBinder) setFees(ctx context.Context, tops *bind.TransactOpts, method string, args ...interface{}) error {
+	gasPrice, err := SetFees(ctx, atom.oracle, atom.account.EthClient(), tops, atom.swapperAddress, SwapperdEthABI, method, args...)
+	if err != nil {
+		return err
+	}
+	atom.logger.Info(fmt.Sprintf("Chose gas price = %v and gas limit = %v for %s", gasPrice.GasPrice, tops.GasLimit, method))
+	return nil
+}
+
 func (atom *ethSwapContractBinder) Cost() blockchain.Cost {
 	return atom.cost
 }
+
+// recordFees adds the fees paid by the mined transactions to the cost of the
+// swap.
+func (atom *ethSwapContractBinder) recordFees(txs []*types.Transaction) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	atom.cost[blockchain.ETH] = new(big.Int).Add(atom.cost[blockchain.ETH], PaidFees(ctx, atom.account.EthClient(), txs))
+}
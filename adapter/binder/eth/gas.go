@@ -0,0 +1,195 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// GasLimitMargin is the percentage added to the estimated gas limit, in case
+// the state of the contract changes between estimation and execution.
+const GasLimitMargin = 20
+
+// FeeHistoryBlocks is the number of recent blocks used to choose the priority
+// fee, and FeeHistoryPercentile is the percentile of their priority fees.
+const (
+	FeeHistoryBlocks     = 10
+	FeeHistoryPercentile = 50
+)
+
+// The sources of gas prices.
+const (
+	GasPriceOracleNode       = "node"
+	GasPriceOracleFeeHistory = "feeHistory"
+	GasPriceOracleStatic     = "static"
+)
+
+// ErrUnknownGasPriceOracle is returned when the gas price oracle in the config
+// is not one of the supported sources.
+type ErrUnknownGasPriceOracle string
+
+// NewErrUnknownGasPriceOracle returns a new ErrUnknownGasPriceOracle.
+func NewErrUnknownGasPriceOracle(oracle string) error {
+	return ErrUnknownGasPriceOracle(fmt.Sprintf("unknown gas price oracle: %s", oracle))
+}
+
+func (err ErrUnknownGasPriceOracle) Error() string {
+	return string(err)
+}
+
+// GasPrice is the fee chosen for a transaction. On chains that support
+// EIP-1559 the base fee, max fee and priority fee are also set. The contract
+// bindings send legacy transactions, so the GasPrice always covers the base
+// fee of the next block plus the priority fee.
+type GasPrice struct {
+	GasPrice             *big.Int `json:"gasPrice"`
+	BaseFee              *big.Int `json:"baseFee,omitempty"`
+	MaxFeePerGas         *big.Int `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *big.Int `json:"maxPriorityFeePerGas,omitempty"`
+}
+
+// A GasPriceOracle chooses the fee for the next transaction.
+type GasPriceOracle interface {
+	GasPrice(ctx context.Context) (GasPrice, error)
+}
+
+type nodeGasPriceOracle struct {
+	client *ethclient.Client
+}
+
+// NewNodeGasPriceOracle returns a GasPriceOracle that uses the eth_gasPrice
+// suggestion of the node.
+func NewNodeGasPriceOracle(client *ethclient.Client) GasPriceOracle {
+	return &nodeGasPriceOracle{client}
+}
+
+func (oracle *nodeGasPriceOracle) GasPrice(ctx context.Context) (GasPrice, error) {
+	gasPrice, err := oracle.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return GasPrice{}, err
+	}
+	return GasPrice{GasPrice: gasPrice}, nil
+}
+
+type feeHistoryGasPriceOracle struct {
+	client   *rpc.Client
+	fallback GasPriceOracle
+}
+
+// NewFeeHistoryGasPriceOracle returns a GasPriceOracle that uses the base fee
+// of the next block and the recent priority fees returned by eth_feeHistory.
+// It falls back to eth_gasPrice on chains that do not support EIP-1559.
+func NewFeeHistoryGasPriceOracle(client *rpc.Client) GasPriceOracle {
+	return &feeHistoryGasPriceOracle{
+		client:   client,
+		fallback: NewNodeGasPriceOracle(ethclient.NewClient(client)),
+	}
+}
+
+type feeHistory struct {
+	BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas"`
+	Reward        [][]*hexutil.Big `json:"reward"`
+}
+
+func (oracle *feeHistoryGasPriceOracle) GasPrice(ctx context.Context) (GasPrice, error) {
+	history := feeHistory{}
+	if err := oracle.client.CallContext(ctx, &history, "eth_feeHistory", hexutil.Uint64(FeeHistoryBlocks), "latest", []float64{FeeHistoryPercentile}); err != nil || len(history.BaseFeePerGas) == 0 {
+		return oracle.fallback.GasPrice(ctx)
+	}
+
+	// The last base fee is the base fee of the next block
+	baseFee := history.BaseFeePerGas[len(history.BaseFeePerGas)-1].ToInt()
+
+	rewards := []*big.Int{}
+	for _, reward := range history.Reward {
+		if len(reward) > 0 {
+			rewards = append(rewards, reward[0].ToInt())
+		}
+	}
+	tip := big.NewInt(0)
+	if len(rewards) > 0 {
+		sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+		tip = rewards[len(rewards)/2]
+	}
+
+	// The base fee can increase by at most 12.5% per block
+	nextBaseFee := new(big.Int).Div(new(big.Int).Mul(baseFee, big.NewInt(1125)), big.NewInt(1000))
+	return GasPrice{
+		GasPrice:             new(big.Int).Add(nextBaseFee, tip),
+		BaseFee:              baseFee,
+		MaxFeePerGas:         new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tip),
+		MaxPriorityFeePerGas: tip,
+	}, nil
+}
+
+type staticGasPriceOracle struct {
+	gasPrice *big.Int
+}
+
+// NewStaticGasPriceOracle returns a GasPriceOracle that always returns the
+// given gas price. It is used for fees chosen by the user, and for testing.
+func NewStaticGasPriceOracle(gasPrice *big.Int) GasPriceOracle {
+	return &staticGasPriceOracle{gasPrice}
+}
+
+func (oracle *staticGasPriceOracle) GasPrice(ctx context.Context) (GasPrice, error) {
+	return GasPrice{GasPrice: new(big.Int).Set(oracle.gasPrice)}, nil
+}
+
+// SetFees sets the gas price of the transaction using the oracle, and its gas
+// limit by estimating the gas used by calling the method of the contract.
+func SetFees(ctx context.Context, oracle GasPriceOracle, client *ethclient.Client, tops *bind.TransactOpts, contract common.Address, contractABI, method string, args ...interface{}) (GasPrice, error) {
+	gasPrice, err := oracle.GasPrice(ctx)
+	if err != nil {
+		return GasPrice{}, fmt.Errorf("failed to get gas price: %v", err)
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(contractABI))
+	if err != nil {
+		return GasPrice{}, err
+	}
+	input, err := parsed.Pack(method, args...)
+	if err != nil {
+		return GasPrice{}, err
+	}
+	gas, err := client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  tops.From,
+		To:    &contract,
+		Value: tops.Value,
+		Data:  input,
+	})
+	if err != nil {
+		return GasPrice{}, fmt.Errorf("failed to estimate gas for %s: %v", method, err)
+	}
+
+	tops.GasPrice = gasPrice.GasPrice
+	tops.GasLimit = gas + gas*GasLimitMargin/100
+	return gasPrice, nil
+}
+
+// PaidFees returns the fees paid by the transactions that were mined, which
+// is the gas that they used at their gas price. Only one of the transactions
+// sent at a nonce is mined, so the ones that were replaced or dropped pay
+// nothing.
+func PaidFees(ctx context.Context, client *ethclient.Client, txs []*types.Transaction) *big.Int {
+	fees := big.NewInt(0)
+	for _, tx := range txs {
+		receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			continue
+		}
+		fees.Add(fees, new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipt.GasUsed)))
+	}
+	return fees
+}
@@ -0,0 +1,79 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// SwapperdVersion is the version of the Swapperd contracts deployed by the
+// deployer. Contracts with the same major version are compatible.
+const SwapperdVersion = "1.0.0"
+
+// ErrContractNotDeployed is returned when there is no contract at the address
+// of a Swapperd contract.
+type ErrContractNotDeployed string
+
+// NewErrContractNotDeployed returns a new ErrContractNotDeployed.
+func NewErrContractNotDeployed(address common.Address) error {
+	return ErrContractNotDeployed(fmt.Sprintf("no contract deployed at %s", address.String()))
+}
+
+func (err ErrContractNotDeployed) Error() string {
+	return string(err)
+}
+
+// ErrIncompatibleVersion is returned when a Swapperd contract reports a
+// version that is not compatible with SwapperdVersion.
+type ErrIncompatibleVersion string
+
+// NewErrIncompatibleVersion returns a new ErrIncompatibleVersion.
+func NewErrIncompatibleVersion(address common.Address, version string) error {
+	return ErrIncompatibleVersion(fmt.Sprintf("contract at %s has version %q, expected version %s", address.String(), version, SwapperdVersion))
+}
+
+func (err ErrIncompatibleVersion) Error() string {
+	return string(err)
+}
+
+// IsCompatibleVersion returns true if the version has the same major version
+// as SwapperdVersion.
+func IsCompatibleVersion(version string) bool {
+	return majorVersion(version) != "" && majorVersion(version) == majorVersion(SwapperdVersion)
+}
+
+// VerifyContract checks that a Swapperd contract is deployed at the address,
+// and that its version is compatible. It returns the version of the contract.
+// The ETH and ERC20 Swapperd contracts share the VERSION method, so it can be
+// used for both.
+func VerifyContract(ctx context.Context, client *ethclient.Client, address common.Address) (string, error) {
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(code) == 0 {
+		return "", NewErrContractNotDeployed(address)
+	}
+
+	contract, err := NewSwapperdEthCaller(address, client)
+	if err != nil {
+		return "", err
+	}
+	version, err := contract.VERSION(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the version of %s: %v", address.String(), err)
+	}
+	if !IsCompatibleVersion(version) {
+		return version, NewErrIncompatibleVersion(address, version)
+	}
+	return version, nil
+}
+
+func majorVersion(version string) string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	return strings.SplitN(version, ".", 2)[0]
+}
@@ -0,0 +1,451 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sirupsen/logrus"
+)
+
+// BackfillBlocks is the number of blocks the Watcher back-fills for a swap
+// that it starts watching, or when it has no stored block height. It is about
+// 72 hours of Ethereum blocks, longer than the lifetime of any swap, and swaps
+// that have been watched for longer are pruned.
+const BackfillBlocks = uint64(20000)
+
+// FilterBlocks is the largest range of blocks the Watcher filters in one
+// query, so that nodes do not refuse the query or time out.
+const FilterBlocks = uint64(1000)
+
+// PruneBlocks is the number of blocks after which a settled swap is pruned.
+const PruneBlocks = uint64(1000)
+
+// StaleAfter is the time after the last successful sync at which the Watcher
+// is stale, and the binders poll the contracts instead.
+const StaleAfter = 2 * time.Minute
+
+// The types of events emitted by the Swapperd contracts.
+const (
+	LogOpen   = "LogOpen"
+	LogClose  = "LogClose"
+	LogExpire = "LogExpire"
+)
+
+// A LogEvent is an event emitted by a Swapperd contract.
+type LogEvent struct {
+	Contract    common.Address `json:"contract"`
+	Type        string         `json:"type"`
+	SwapID      [32]byte       `json:"swapID"`
+	SecretKey   [32]byte       `json:"secretKey"`
+	BlockNumber uint64         `json:"blockNumber"`
+}
+
+// WatcherStorage persists the events of the watched swaps, and the height of
+// the last block the Watcher has processed.
+type WatcherStorage interface {
+	WatcherHeight() (uint64, error)
+	PutWatcherHeight(height uint64) error
+	LogEvents() ([]LogEvent, error)
+	PutLogEvent(event LogEvent) error
+	DeleteLogEvents(contract common.Address, id [32]byte) error
+}
+
+// A Watcher follows the LogOpen, LogClose and LogExpire events of the swaps
+// it watches in the Swapperd contracts, so that the binders do not need to
+// poll the contracts on every tick.
+type Watcher interface {
+	Run(done <-chan struct{})
+
+	// Watch starts watching the swap. Its events are back-filled by the next
+	// sync.
+	Watch(contract common.Address, id [32]byte)
+
+	// Covers returns true if the swap has been back-filled and the Watcher is
+	// not stale, so that a missing event means it has not been emitted. The
+	// binders poll the contracts otherwise.
+	Covers(contract common.Address, id [32]byte) bool
+
+	Opened(contract common.Address, id [32]byte) bool
+	Secret(contract common.Address, id [32]byte) ([32]byte, bool)
+	Expired(contract common.Address, id [32]byte) bool
+}
+
+type eventKey struct {
+	contract common.Address
+	id       [32]byte
+}
+
+// A watchedSwap is a swap that is being watched since the block height, and
+// whether its events have been back-filled.
+type watchedSwap struct {
+	height     uint64
+	backfilled bool
+}
+
+type watcher struct {
+	mu        *sync.RWMutex
+	client    *ethclient.Client
+	filterers map[common.Address]*SwapperdEthFilterer
+	swaps     map[eventKey]watchedSwap
+	events    map[eventKey]map[string]LogEvent
+	height    uint64
+	syncedAt  time.Time
+	storage   WatcherStorage
+	logger    logrus.FieldLogger
+	onEvent   func()
+}
+
+// NewWatcher returns a Watcher for the given Swapperd contracts. The ERC20
+// swapper contracts emit the same events as the ETH swapper contract, so
+// they are all filtered using the SwapperdEth bindings. The onEvent function
+// is called whenever a new event has been seen.
+func NewWatcher(url string, contracts []common.Address, storage WatcherStorage, logger logrus.FieldLogger, onEvent func()) (Watcher, error) {
+	client, err := ethclient.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	filterers := map[common.Address]*SwapperdEthFilterer{}
+	for _, contract := range contracts {
+		filterer, err := NewSwapperdEthFilterer(contract, bind.ContractFilterer(client))
+		if err != nil {
+			return nil, err
+		}
+		filterers[contract] = filterer
+	}
+
+	return &watcher{
+		mu:        new(sync.RWMutex),
+		client:    client,
+		filterers: filterers,
+		swaps:     map[eventKey]watchedSwap{},
+		events:    map[eventKey]map[string]LogEvent{},
+		storage:   storage,
+		logger:    logger,
+		onEvent:   onEvent,
+	}, nil
+}
+
+func (watcher *watcher) Run(done <-chan struct{}) {
+	events, err := watcher.storage.LogEvents()
+	if err != nil {
+		watcher.logger.Error(err)
+	}
+	for _, event := range events {
+		watcher.write(event)
+	}
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		if err := watcher.sync(); err != nil {
+			watcher.logger.Error(fmt.Errorf("failed to sync ethereum events: %v", err))
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (watcher *watcher) Watch(contract common.Address, id [32]byte) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	key := eventKey{contract, id}
+	swap, ok := watcher.swaps[key]
+	swap.height = watcher.height
+	if !ok {
+		swap.backfilled = false
+	}
+	watcher.swaps[key] = swap
+}
+
+func (watcher *watcher) Covers(contract common.Address, id [32]byte) bool {
+	watcher.mu.RLock()
+	defer watcher.mu.RUnlock()
+	if time.Since(watcher.syncedAt) > StaleAfter {
+		return false
+	}
+	swap, ok := watcher.swaps[eventKey{contract, id}]
+	return ok && swap.backfilled
+}
+
+func (watcher *watcher) Opened(contract common.Address, id [32]byte) bool {
+	_, ok := watcher.read(contract, id, LogOpen)
+	return ok
+}
+
+func (watcher *watcher) Secret(contract common.Address, id [32]byte) ([32]byte, bool) {
+	event, ok := watcher.read(contract, id, LogClose)
+	return event.SecretKey, ok
+}
+
+func (watcher *watcher) Expired(contract common.Address, id [32]byte) bool {
+	_, ok := watcher.read(contract, id, LogExpire)
+	return ok
+}
+
+// sync filters the events of the watched swaps between the last processed
+// block and the current block, and back-fills the swaps that have started
+// being watched since the last sync. Only the events of watched swaps are
+// kept, because the swap ids are not indexed by the contracts and so cannot
+// be filtered by the node.
+func (watcher *watcher) sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	head, err := watcher.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	current := head.Number.Uint64()
+	backfillFrom := uint64(0)
+	if current > BackfillBlocks {
+		backfillFrom = current - BackfillBlocks
+	}
+
+	height, err := watcher.storage.WatcherHeight()
+	if err != nil || height == 0 || height < backfillFrom {
+		height = backfillFrom
+	}
+	if height > current {
+		height = current
+	}
+
+	watched, backfill := watcher.watchedSwaps()
+	events, err := watcher.filter(ctx, height+1, current, watched)
+	if err != nil {
+		return err
+	}
+	if len(backfill) > 0 {
+		backfilled, err := watcher.filter(ctx, backfillFrom, height, backfill)
+		if err != nil {
+			return err
+		}
+		events = append(events, backfilled...)
+	}
+
+	for _, event := range events {
+		if err := watcher.storage.PutLogEvent(event); err != nil {
+			return err
+		}
+		watcher.write(event)
+	}
+	if err := watcher.storage.PutWatcherHeight(current); err != nil {
+		return err
+	}
+	watcher.synced(current, backfill)
+	if err := watcher.prune(current); err != nil {
+		return err
+	}
+	if len(events) > 0 && watcher.onEvent != nil {
+		watcher.onEvent()
+	}
+	return nil
+}
+
+// watchedSwaps returns the swaps being watched, and those of them that have
+// not been back-filled.
+func (watcher *watcher) watchedSwaps() (map[eventKey]bool, map[eventKey]bool) {
+	watcher.mu.RLock()
+	defer watcher.mu.RUnlock()
+	watched, backfill := map[eventKey]bool{}, map[eventKey]bool{}
+	for key, swap := range watcher.swaps {
+		watched[key] = true
+		if !swap.backfilled {
+			backfill[key] = true
+		}
+	}
+	return watched, backfill
+}
+
+// filter returns the events of the swaps between the blocks, filtering at
+// most FilterBlocks blocks at a time.
+func (watcher *watcher) filter(ctx context.Context, from, to uint64, swaps map[eventKey]bool) ([]LogEvent, error) {
+	events := []LogEvent{}
+	if len(swaps) == 0 {
+		return events, nil
+	}
+	for start := from; start <= to; start += FilterBlocks {
+		end := start + FilterBlocks - 1
+		if end > to {
+			end = to
+		}
+		opts := &bind.FilterOpts{Start: start, End: &end, Context: ctx}
+		for contract, filterer := range watcher.filterers {
+			contractEvents, err := filterEvents(contract, filterer, opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, event := range contractEvents {
+				if swaps[eventKey{event.Contract, event.SwapID}] {
+					events = append(events, event)
+				}
+			}
+		}
+	}
+	return events, nil
+}
+
+// synced marks the swaps as back-filled at the height.
+func (watcher *watcher) synced(height uint64, backfilled map[eventKey]bool) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	for key := range backfilled {
+		if swap, ok := watcher.swaps[key]; ok {
+			swap.backfilled = true
+			if swap.height == 0 {
+				swap.height = height
+			}
+			watcher.swaps[key] = swap
+		}
+	}
+	watcher.height = height
+	watcher.syncedAt = time.Now()
+}
+
+// prune stops watching the swaps that were settled more than PruneBlocks ago,
+// or that have been watched for longer than BackfillBlocks, and deletes their
+// events. Pruned swaps are polled by their binders. The events of swaps that
+// are not watched, such as the events loaded from the storage before the
+// swaps are watched again after a restart, are kept until they are settled or
+// their last event is older than BackfillBlocks.
+func (watcher *watcher) prune(height uint64) error {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	for key, events := range watcher.events {
+		swap, watched := watcher.swaps[key]
+		if watched && !expired(swap.height, BackfillBlocks, height) && !settled(events, height) {
+			continue
+		}
+		if !watched && !expired(lastEvent(events), BackfillBlocks, height) && !settled(events, height) {
+			continue
+		}
+		if err := watcher.storage.DeleteLogEvents(key.contract, key.id); err != nil {
+			return err
+		}
+		delete(watcher.events, key)
+		delete(watcher.swaps, key)
+	}
+	for key, swap := range watcher.swaps {
+		if expired(swap.height, BackfillBlocks, height) {
+			delete(watcher.swaps, key)
+		}
+	}
+	return nil
+}
+
+// lastEvent returns the block number of the last event of the swap.
+func lastEvent(events map[string]LogEvent) uint64 {
+	last := uint64(0)
+	for _, event := range events {
+		if event.BlockNumber > last {
+			last = event.BlockNumber
+		}
+	}
+	return last
+}
+
+// settled returns true if the swap was closed, or expired, more than
+// PruneBlocks before the height.
+func settled(events map[string]LogEvent, height uint64) bool {
+	for _, eventType := range []string{LogClose, LogExpire} {
+		if event, ok := events[eventType]; ok && expired(event.BlockNumber, PruneBlocks, height) {
+			return true
+		}
+	}
+	return false
+}
+
+func expired(since, blocks, height uint64) bool {
+	return since+blocks < height
+}
+
+func (watcher *watcher) write(event LogEvent) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	key := eventKey{event.Contract, event.SwapID}
+	if _, ok := watcher.events[key]; !ok {
+		watcher.events[key] = map[string]LogEvent{}
+	}
+	watcher.events[key][event.Type] = event
+}
+
+func (watcher *watcher) read(contract common.Address, id [32]byte, eventType string) (LogEvent, bool) {
+	watcher.mu.RLock()
+	defer watcher.mu.RUnlock()
+	event, ok := watcher.events[eventKey{contract, id}][eventType]
+	return event, ok
+}
+
+// filterEvents returns the events of the contract in the blocks of the
+// options. Logs that were removed by a reorganisation are dropped.
+func filterEvents(contract common.Address, filterer *SwapperdEthFilterer, opts *bind.FilterOpts) ([]LogEvent, error) {
+	events := []LogEvent{}
+
+	openIter, err := filterer.FilterLogOpen(opts)
+	if err != nil {
+		return nil, err
+	}
+	for openIter.Next() {
+		if openIter.Event.Raw.Removed {
+			continue
+		}
+		events = append(events, LogEvent{
+			Contract:    contract,
+			Type:        LogOpen,
+			SwapID:      openIter.Event.SwapID,
+			BlockNumber: openIter.Event.Raw.BlockNumber,
+		})
+	}
+	if err := openIter.Error(); err != nil {
+		return nil, err
+	}
+
+	closeIter, err := filterer.FilterLogClose(opts)
+	if err != nil {
+		return nil, err
+	}
+	for closeIter.Next() {
+		if closeIter.Event.Raw.Removed {
+			continue
+		}
+		events = append(events, LogEvent{
+			Contract:    contract,
+			Type:        LogClose,
+			SwapID:      closeIter.Event.SwapID,
+			SecretKey:   closeIter.Event.SecretKey,
+			BlockNumber: closeIter.Event.Raw.BlockNumber,
+		})
+	}
+	if err := closeIter.Error(); err != nil {
+		return nil, err
+	}
+
+	expireIter, err := filterer.FilterLogExpire(opts)
+	if err != nil {
+		return nil, err
+	}
+	for expireIter.Next() {
+		if expireIter.Event.Raw.Removed {
+			continue
+		}
+		events = append(events, LogEvent{
+			Contract:    contract,
+			Type:        LogExpire,
+			SwapID:      expireIter.Event.SwapID,
+			BlockNumber: expireIter.Event.Raw.BlockNumber,
+		})
+	}
+	if err := expireIter.Error(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,9 @@
+package binder
+
+import "github.com/republicprotocol/swapperd/foundation/blockchain"
+
+// TimeLocks exports the timelocks of the native and the foreign legs of a
+// swap between the blockchains to the tests.
+func TimeLocks(timelock int64, initiateFirst bool, send, receive blockchain.BlockchainName) (int64, int64) {
+	return timeLocks(timelock, initiateFirst, chainSpecs[send], chainSpecs[receive])
+}
@@ -0,0 +1,57 @@
+package ltc
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/core/swapper/immediate"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+	"github.com/sirupsen/logrus"
+)
+
+// Litecoin is the Litecoin blockchain. It uses the same scripts and
+// signatures as Bitcoin, with different address prefixes.
+var Litecoin = btc.Chain{
+	Name:          blockchain.Litecoin,
+	DecodeAddress: btcutil.DecodeAddress,
+	Sign:          btc.LegacySignature,
+}
+
+// MainNetParams are the network parameters of the Litecoin mainnet.
+var MainNetParams = chaincfg.Params{
+	Name:             "mainnet",
+	Net:              wire.BitcoinNet(0xdbb6c0fb),
+	PubKeyHashAddrID: 0x30,
+	ScriptHashAddrID: 0x32,
+	PrivateKeyID:     0xb0,
+	Bech32HRPSegwit:  "ltc",
+	HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4},
+	HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e},
+	HDCoinType:       2,
+
+	TargetTimePerBlock: 150 * time.Second,
+}
+
+// TestNet4Params are the network parameters of the Litecoin testnet.
+var TestNet4Params = chaincfg.Params{
+	Name:             "testnet4",
+	Net:              wire.BitcoinNet(0xf1c8d2fd),
+	PubKeyHashAddrID: 0x6f,
+	ScriptHashAddrID: 0x3a,
+	PrivateKeyID:     0xef,
+	Bech32HRPSegwit:  "tltc",
+	HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94},
+	HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf},
+	HDCoinType:       1,
+
+	TargetTimePerBlock: 150 * time.Second,
+}
+
+// NewLTCSwapContractBinder returns a new Litecoin Atom instance
+func NewLTCSwapContractBinder(account btc.Account, swap swap.Swap, cost blockchain.Cost, logger logrus.FieldLogger) (immediate.Contract, error) {
+	return btc.NewSwapContractBinder(Litecoin, account, swap, cost, logger)
+}
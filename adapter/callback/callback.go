@@ -98,6 +98,12 @@ func verifyDelaySwap(partialSwap, filledSwap swap.SwapBlob) (swap.SwapBlob, erro
 		return partialSwap, fmt.Errorf("invalid filled swap unfavorable price")
 	}
 
+	// The address of the swap was checked against the address book when it
+	// was posted
+	if partialSwap.SendTo != "" && filledSwap.SendTo != partialSwap.SendTo {
+		return partialSwap, fmt.Errorf("invalid filled swap send address %s, expected %s", filledSwap.SendTo, partialSwap.SendTo)
+	}
+
 	filledSwap.Delay = false
 	return filledSwap, nil
 }
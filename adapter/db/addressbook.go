@@ -0,0 +1,42 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/republicprotocol/swapperd/adapter/wallet"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var TableAddressBook = [8]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08}
+
+func (db *dbStorage) AddressBook() ([]wallet.AddressBookEntry, error) {
+	iterator := db.db.NewIterator(util.BytesPrefix(TableAddressBook[:]), nil)
+	defer iterator.Release()
+
+	entries := []wallet.AddressBookEntry{}
+	for iterator.Next() {
+		entry := wallet.AddressBookEntry{}
+		if err := json.Unmarshal(iterator.Value(), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, iterator.Error()
+}
+
+func (db *dbStorage) PutAddressBookEntry(entry wallet.AddressBookEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return db.db.Put(addressBookKey(entry.Account, entry.Token, entry.Address), data, nil)
+}
+
+func (db *dbStorage) DeleteAddressBookEntry(account string, token blockchain.TokenName, address string) error {
+	return db.db.Delete(addressBookKey(account, token, address), nil)
+}
+
+func addressBookKey(account string, token blockchain.TokenName, address string) []byte {
+	return append(TableAddressBook[:], []byte(account+"/"+string(token)+"/"+address)...)
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
+	"github.com/republicprotocol/swapperd/adapter/policy"
+	"github.com/republicprotocol/swapperd/adapter/wallet"
 	"github.com/republicprotocol/swapperd/core/transfer"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
 	"github.com/republicprotocol/swapperd/foundation/swap"
@@ -40,6 +43,11 @@ type Storage interface {
 	Receipts() ([]swap.SwapReceipt, error)
 	Receipt(swapID swap.SwapID) (swap.SwapReceipt, error)
 	LoadCosts(swapID swap.SwapID) (blockchain.Cost, blockchain.Cost)
+	Reserved(id string) (bool, error)
+
+	eth.WatcherStorage
+	wallet.Storage
+	policy.Storage
 }
 
 type dbStorage struct {
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"encoding/binary"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+var TableAddressIndex = [8]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x06}
+
+func (db *dbStorage) AddressIndex() (uint32, error) {
+	indexBytes, err := db.db.Get(TableAddressIndex[:], nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(indexBytes), nil
+}
+
+func (db *dbStorage) PutAddressIndex(index uint32) error {
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+	return db.db.Put(TableAddressIndex[:], indexBytes, nil)
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/republicprotocol/swapperd/adapter/policy"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var TableSpends = [8]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09}
+
+func (db *dbStorage) Spends() (map[string]policy.Spend, error) {
+	iterator := db.db.NewIterator(util.BytesPrefix(TableSpends[:]), nil)
+	defer iterator.Release()
+
+	spends := map[string]policy.Spend{}
+	for iterator.Next() {
+		spend := policy.Spend{}
+		if err := json.Unmarshal(iterator.Value(), &spend); err != nil {
+			return nil, err
+		}
+		spends[string(iterator.Key()[len(TableSpends):])] = spend
+	}
+	return spends, iterator.Error()
+}
+
+func (db *dbStorage) PutSpend(id string, spend policy.Spend) error {
+	data, err := json.Marshal(spend)
+	if err != nil {
+		return err
+	}
+	return db.db.Put(append(TableSpends[:], []byte(id)...), data, nil)
+}
+
+func (db *dbStorage) DeleteSpend(id string) error {
+	return db.db.Delete(append(TableSpends[:], []byte(id)...), nil)
+}
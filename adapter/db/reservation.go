@@ -0,0 +1,42 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/republicprotocol/swapperd/adapter/wallet"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var TableReservations = [8]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07}
+
+func (db *dbStorage) Reservations() (map[string]wallet.Reservation, error) {
+	iterator := db.db.NewIterator(util.BytesPrefix(TableReservations[:]), nil)
+	defer iterator.Release()
+
+	reservations := map[string]wallet.Reservation{}
+	for iterator.Next() {
+		reservation := wallet.Reservation{}
+		if err := json.Unmarshal(iterator.Value(), &reservation); err != nil {
+			return nil, err
+		}
+		reservations[string(iterator.Key()[len(TableReservations):])] = reservation
+	}
+	return reservations, iterator.Error()
+}
+
+func (db *dbStorage) PutReservation(id string, reservation wallet.Reservation) error {
+	data, err := json.Marshal(reservation)
+	if err != nil {
+		return err
+	}
+	return db.db.Put(append(TableReservations[:], []byte(id)...), data, nil)
+}
+
+// Reserved returns true if there is a reservation of the id.
+func (db *dbStorage) Reserved(id string) (bool, error) {
+	return db.db.Has(append(TableReservations[:], []byte(id)...), nil)
+}
+
+func (db *dbStorage) DeleteReservation(id string) error {
+	return db.db.Delete(append(TableReservations[:], []byte(id)...), nil)
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var (
+	TableWatcherHeight = [8]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04}
+
+	TableLogEvents      = [8]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05}
+	TableLogEventsStart = [40]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	TableLogEventsLimit = [40]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+)
+
+func (db *dbStorage) WatcherHeight() (uint64, error) {
+	heightBytes, err := db.db.Get(TableWatcherHeight[:], nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(heightBytes), nil
+}
+
+func (db *dbStorage) PutWatcherHeight(height uint64) error {
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, height)
+	return db.db.Put(TableWatcherHeight[:], heightBytes, nil)
+}
+
+func (db *dbStorage) LogEvents() ([]eth.LogEvent, error) {
+	iterator := db.db.NewIterator(&util.Range{Start: TableLogEventsStart[:], Limit: TableLogEventsLimit[:]}, nil)
+	defer iterator.Release()
+	events := []eth.LogEvent{}
+	for iterator.Next() {
+		value := iterator.Value()
+		event := eth.LogEvent{}
+		if err := json.Unmarshal(value, &event); err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	return events, iterator.Error()
+}
+
+func (db *dbStorage) PutLogEvent(event eth.LogEvent) error {
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return db.db.Put(logEventKey(event.Contract, event.SwapID, event.Type), eventData, nil)
+}
+
+func (db *dbStorage) DeleteLogEvents(contract common.Address, id [32]byte) error {
+	for _, eventType := range []string{eth.LogOpen, eth.LogClose, eth.LogExpire} {
+		if err := db.db.Delete(logEventKey(contract, id, eventType), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func logEventKey(contract common.Address, id [32]byte, eventType string) []byte {
+	key := append(TableLogEvents[:], id[:]...)
+	key = append(key, contract.Bytes()...)
+	return append(key, []byte(eventType)...)
+}
@@ -0,0 +1,403 @@
+package policy
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// SpendWindow is the window of the daily limits. Spends older than the window
+// no longer count towards them.
+const SpendWindow = 24 * time.Hour
+
+// The rules of a policy, which are reported by its violations.
+const (
+	RuleTransactionLimit = "transactionLimit"
+	RuleDailyLimit       = "dailyLimit"
+	RuleAllowedPairs     = "allowedPairs"
+	RuleMaxBrokerFee     = "maxBrokerFee"
+	RuleMinPrice         = "minPrice"
+)
+
+// PriceUnknown is the value of a minimum price violation by a swap whose
+// receive amount is not known.
+const PriceUnknown = "unknown"
+
+// A Policy limits the transfers and swaps of an account. The limits are
+// decimal strings in the smallest unit of their token, and tokens without a
+// limit are not limited. The daily limits cover both the transfers and the
+// send amounts of the swaps of the account over the last SpendWindow.
+//
+// AllowedPairs are the swaps that can be made, such as "BTC/ETH" to send BTC
+// and receive ETH, and any swap can be made when there are none. MaxBrokerFee
+// is in BIPs. MinPrices are the least amount of the receive token, in whole
+// units, that must be received for each whole unit of the send token, such
+// as {"BTC/ETH": "30.5"}.
+type Policy struct {
+	TransactionLimits map[blockchain.TokenName]string `json:"transactionLimits,omitempty"`
+	DailyLimits       map[blockchain.TokenName]string `json:"dailyLimits,omitempty"`
+	AllowedPairs      []string                        `json:"allowedPairs,omitempty"`
+	MaxBrokerFee      *int64                          `json:"maxBrokerFee,omitempty"`
+	MinPrices         map[string]string               `json:"minPrices,omitempty"`
+}
+
+// Config is the policy of each account, identified by the id returned by
+// GET /id for its password. Accounts without their own policy have the
+// Default policy, which allows everything unless it is set.
+type Config struct {
+	Default  Policy            `json:"default"`
+	Accounts map[string]Policy `json:"accounts,omitempty"`
+}
+
+// Policy returns the policy of the account.
+func (config Config) Policy(account string) Policy {
+	if policy, ok := config.Accounts[account]; ok {
+		return policy
+	}
+	return config.Default
+}
+
+// normalized returns the config with the pairs of its policies in upper case,
+// so that they are matched whatever their case.
+func (config Config) normalized() Config {
+	accounts := make(map[string]Policy, len(config.Accounts))
+	for account, policy := range config.Accounts {
+		accounts[account] = policy.normalized()
+	}
+	return Config{
+		Default:  config.Default.normalized(),
+		Accounts: accounts,
+	}
+}
+
+func (policy Policy) normalized() Policy {
+	if policy.AllowedPairs != nil {
+		pairs := make([]string, len(policy.AllowedPairs))
+		for i, pair := range policy.AllowedPairs {
+			pairs[i] = strings.ToUpper(pair)
+		}
+		policy.AllowedPairs = pairs
+	}
+	if policy.MinPrices != nil {
+		minPrices := make(map[string]string, len(policy.MinPrices))
+		for pair, price := range policy.MinPrices {
+			minPrices[strings.ToUpper(pair)] = price
+		}
+		policy.MinPrices = minPrices
+	}
+	return policy
+}
+
+// A Violation is the error returned for a transfer or a swap that breaks a
+// rule of the policy of its account. Limit is the limit of the rule, and
+// Value is the value of the request that breaks it.
+type Violation struct {
+	Rule  string               `json:"rule"`
+	Token blockchain.TokenName `json:"token,omitempty"`
+	Pair  string               `json:"pair,omitempty"`
+	Limit string               `json:"limit"`
+	Value string               `json:"value"`
+}
+
+func (violation Violation) Error() string {
+	switch violation.Rule {
+	case RuleTransactionLimit:
+		return fmt.Sprintf("policy violation: %s %s exceeds the transaction limit of %s %s", violation.Value, violation.Token, violation.Limit, violation.Token)
+	case RuleDailyLimit:
+		return fmt.Sprintf("policy violation: %s %s in the last 24 hours exceeds the daily limit of %s %s", violation.Value, violation.Token, violation.Limit, violation.Token)
+	case RuleAllowedPairs:
+		return fmt.Sprintf("policy violation: %s is not one of the allowed pairs %s", violation.Value, violation.Limit)
+	case RuleMaxBrokerFee:
+		return fmt.Sprintf("policy violation: broker fee of %s bips exceeds the maximum of %s bips", violation.Value, violation.Limit)
+	case RuleMinPrice:
+		if violation.Value == PriceUnknown {
+			return fmt.Sprintf("policy violation: price for %s is unknown, it must have a minimum receive amount to meet the minimum price of %s", violation.Pair, violation.Limit)
+		}
+		return fmt.Sprintf("policy violation: price of %s for %s is below the minimum of %s", violation.Value, violation.Pair, violation.Limit)
+	default:
+		return fmt.Sprintf("policy violation: %s", violation.Rule)
+	}
+}
+
+// A Transfer to be authorized. The ID identifies its spend, so that it can be
+// revoked.
+type Transfer struct {
+	ID      string
+	Account string
+	Token   blockchain.TokenName
+	Amount  *big.Int
+}
+
+// A Swap to be authorized. The ReceiveAmount is nil when it is not known yet,
+// such as for delayed swaps without a minimum receive amount.
+type Swap struct {
+	ID            string
+	Account       string
+	SendToken     blockchain.TokenConfig
+	ReceiveToken  blockchain.TokenConfig
+	SendAmount    *big.Int
+	ReceiveAmount *big.Int
+	BrokerFee     int64
+}
+
+// A Spend is an amount of a token that has been authorized for an account, at
+// a unix timestamp.
+type Spend struct {
+	Account   string               `json:"account"`
+	Token     blockchain.TokenName `json:"token"`
+	Amount    string               `json:"amount"`
+	Timestamp int64                `json:"timestamp"`
+}
+
+// Storage persists the spends of the accounts, so that the daily limits
+// survive restarts.
+type Storage interface {
+	Spends() (map[string]Spend, error)
+	PutSpend(id string, spend Spend) error
+	DeleteSpend(id string) error
+}
+
+// The Engine evaluates the policies of the accounts before their transfers and
+// swaps are executed.
+type Engine interface {
+	// AuthorizeTransfer returns a Violation if the transfer breaks the policy
+	// of its account, otherwise its amount is spent.
+	AuthorizeTransfer(transfer Transfer) error
+
+	// AuthorizeSwap returns a Violation if the swap breaks the policy of its
+	// account, otherwise its send amount is spent.
+	AuthorizeSwap(swap Swap) error
+
+	// Revoke deletes the spend of the id, when its transfer or swap fails
+	// before it is executed. Revoking an id that has no spend does nothing.
+	Revoke(id string) error
+}
+
+type engine struct {
+	mu      *sync.Mutex
+	config  Config
+	storage Storage
+}
+
+// New returns an Engine of the policies in the config, which keeps the spends
+// in the storage. Without storage the spends are kept in memory. The pairs of
+// the policies are matched whatever their case.
+func New(config Config, storage Storage) Engine {
+	if storage == nil {
+		storage = newMemorySpends()
+	}
+	return &engine{
+		mu:      new(sync.Mutex),
+		config:  config.normalized(),
+		storage: storage,
+	}
+}
+
+func (engine *engine) AuthorizeTransfer(transfer Transfer) error {
+	policy := engine.config.Policy(transfer.Account)
+	return engine.spend(policy, transfer.ID, transfer.Account, transfer.Token, transfer.Amount)
+}
+
+func (engine *engine) AuthorizeSwap(swap Swap) error {
+	policy := engine.config.Policy(swap.Account)
+	pair := strings.ToUpper(fmt.Sprintf("%s/%s", swap.SendToken.Name, swap.ReceiveToken.Name))
+	if !policy.allows(pair) {
+		return Violation{
+			Rule:  RuleAllowedPairs,
+			Pair:  pair,
+			Limit: strings.Join(policy.AllowedPairs, ","),
+			Value: pair,
+		}
+	}
+	if policy.MaxBrokerFee != nil && swap.BrokerFee > *policy.MaxBrokerFee {
+		return Violation{
+			Rule:  RuleMaxBrokerFee,
+			Pair:  pair,
+			Limit: fmt.Sprintf("%d", *policy.MaxBrokerFee),
+			Value: fmt.Sprintf("%d", swap.BrokerFee),
+		}
+	}
+	if err := verifyPrice(policy, pair, swap); err != nil {
+		return err
+	}
+	return engine.spend(policy, swap.ID, swap.Account, swap.SendToken.Name, swap.SendAmount)
+}
+
+func (engine *engine) Revoke(id string) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	return engine.storage.DeleteSpend(id)
+}
+
+// spend checks the amount against the transaction and daily limits of the
+// token, and spends it for the id. The check and the spend are atomic, so
+// concurrent requests cannot exceed the daily limit together.
+func (engine *engine) spend(policy Policy, id, account string, token blockchain.TokenName, amount *big.Int) error {
+	if limit, ok := policy.TransactionLimits[token]; ok {
+		max, ok := new(big.Int).SetString(limit, 10)
+		if !ok {
+			return fmt.Errorf("invalid transaction limit of %s: %s", token, limit)
+		}
+		if amount.Cmp(max) > 0 {
+			return Violation{
+				Rule:  RuleTransactionLimit,
+				Token: token,
+				Limit: limit,
+				Value: amount.String(),
+			}
+		}
+	}
+
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	if limit, ok := policy.DailyLimits[token]; ok {
+		max, ok := new(big.Int).SetString(limit, 10)
+		if !ok {
+			return fmt.Errorf("invalid daily limit of %s: %s", token, limit)
+		}
+		spent, err := engine.spent(account, token)
+		if err != nil {
+			return err
+		}
+		total := spent.Add(spent, amount)
+		if total.Cmp(max) > 0 {
+			return Violation{
+				Rule:  RuleDailyLimit,
+				Token: token,
+				Limit: limit,
+				Value: total.String(),
+			}
+		}
+	}
+	return engine.storage.PutSpend(id, Spend{
+		Account:   account,
+		Token:     token,
+		Amount:    amount.String(),
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// spent returns the amount of the token spent by the account over the spend
+// window, and deletes the spends that have left it.
+func (engine *engine) spent(account string, token blockchain.TokenName) (*big.Int, error) {
+	spends, err := engine.storage.Spends()
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().Add(-SpendWindow).Unix()
+	spent := big.NewInt(0)
+	for id, spend := range spends {
+		if spend.Timestamp < since {
+			if err := engine.storage.DeleteSpend(id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if spend.Account != account || spend.Token != token {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(spend.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount of spend %s: %s", id, spend.Amount)
+		}
+		spent.Add(spent, amount)
+	}
+	return spent, nil
+}
+
+// allows returns true if the swaps of the pair can be made. The pair and the
+// allowed pairs are in upper case.
+func (policy Policy) allows(pair string) bool {
+	if len(policy.AllowedPairs) == 0 {
+		return true
+	}
+	for _, allowed := range policy.AllowedPairs {
+		if allowed == pair {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyPrice checks that the swap receives at least the minimum price of its
+// pair, which is in upper case. The price is in whole units, so it is scaled by the decimals of the
+// tokens.
+func verifyPrice(policy Policy, pair string, swap Swap) error {
+	limit, ok := policy.MinPrices[pair]
+	if !ok {
+		return nil
+	}
+	minPrice, ok := new(big.Rat).SetString(limit)
+	if !ok {
+		return fmt.Errorf("invalid minimum price of %s: %s", pair, limit)
+	}
+	if swap.ReceiveAmount == nil {
+		return Violation{
+			Rule:  RuleMinPrice,
+			Pair:  pair,
+			Limit: limit,
+			Value: PriceUnknown,
+		}
+	}
+	if swap.SendAmount.Sign() <= 0 {
+		return nil
+	}
+	receive := new(big.Rat).SetFrac(swap.ReceiveAmount, pow10(swap.ReceiveToken.Decimals))
+	send := new(big.Rat).SetFrac(swap.SendAmount, pow10(swap.SendToken.Decimals))
+	price := new(big.Rat).Quo(receive, send)
+	if price.Cmp(minPrice) < 0 {
+		return Violation{
+			Rule:  RuleMinPrice,
+			Pair:  pair,
+			Limit: limit,
+			Value: price.FloatString(8),
+		}
+	}
+	return nil
+}
+
+func pow10(decimals int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+}
+
+// memorySpends keeps the spends of an engine without storage.
+type memorySpends struct {
+	mu     *sync.Mutex
+	spends map[string]Spend
+}
+
+func newMemorySpends() Storage {
+	return &memorySpends{
+		mu:     new(sync.Mutex),
+		spends: map[string]Spend{},
+	}
+}
+
+func (memory *memorySpends) Spends() (map[string]Spend, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	spends := make(map[string]Spend, len(memory.spends))
+	for id, spend := range memory.spends {
+		spends[id] = spend
+	}
+	return spends, nil
+}
+
+func (memory *memorySpends) PutSpend(id string, spend Spend) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	memory.spends[id] = spend
+	return nil
+}
+
+func (memory *memorySpends) DeleteSpend(id string) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	delete(memory.spends, id)
+	return nil
+}
@@ -0,0 +1,181 @@
+package policy_test
+
+import (
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/adapter/policy"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// mockStorage keeps the spends in a map, so that the tests can add spends
+// from the past.
+type mockStorage struct {
+	spends map[string]Spend
+}
+
+func (storage *mockStorage) Spends() (map[string]Spend, error) {
+	spends := map[string]Spend{}
+	for id, spend := range storage.spends {
+		spends[id] = spend
+	}
+	return spends, nil
+}
+
+func (storage *mockStorage) PutSpend(id string, spend Spend) error {
+	storage.spends[id] = spend
+	return nil
+}
+
+func (storage *mockStorage) DeleteSpend(id string) error {
+	delete(storage.spends, id)
+	return nil
+}
+
+var _ = Describe("Policies", func() {
+	const account = "account"
+
+	btc := blockchain.TokenConfig{Name: blockchain.BTC, Blockchain: blockchain.Bitcoin, Decimals: 8}
+	eth := blockchain.TokenConfig{Name: blockchain.ETH, Blockchain: blockchain.Ethereum, Decimals: 18}
+
+	transfer := func(id string, amount int64) Transfer {
+		return Transfer{ID: id, Account: account, Token: blockchain.BTC, Amount: big.NewInt(amount)}
+	}
+	swap := func(id string, amount int64) Swap {
+		return Swap{ID: id, Account: account, SendToken: btc, ReceiveToken: eth, SendAmount: big.NewInt(amount)}
+	}
+	violation := func(err error) Violation {
+		Expect(err).Should(BeAssignableToTypeOf(Violation{}))
+		return err.(Violation)
+	}
+
+	Context("when the token has a transaction limit", func() {
+		It("should only authorize transfers and swaps within it", func() {
+			engine := New(Config{Default: Policy{
+				TransactionLimits: map[blockchain.TokenName]string{blockchain.BTC: "1000"},
+			}}, nil)
+			Expect(engine.AuthorizeTransfer(transfer("a", 1000))).Should(Succeed())
+			Expect(violation(engine.AuthorizeTransfer(transfer("b", 1001))).Rule).Should(Equal(RuleTransactionLimit))
+			Expect(violation(engine.AuthorizeSwap(swap("c", 1001))).Rule).Should(Equal(RuleTransactionLimit))
+		})
+	})
+
+	Context("when the token has a daily limit", func() {
+		var engine Engine
+
+		BeforeEach(func() {
+			engine = New(Config{Default: Policy{
+				DailyLimits: map[blockchain.TokenName]string{blockchain.BTC: "1000"},
+			}}, nil)
+		})
+
+		It("should count the transfers and the swaps of the account towards it", func() {
+			Expect(engine.AuthorizeTransfer(transfer("a", 600))).Should(Succeed())
+			err := engine.AuthorizeSwap(swap("b", 500))
+			Expect(violation(err).Rule).Should(Equal(RuleDailyLimit))
+			Expect(violation(err).Value).Should(Equal("1100"))
+			Expect(engine.AuthorizeSwap(swap("c", 400))).Should(Succeed())
+			Expect(violation(engine.AuthorizeTransfer(transfer("d", 1))).Rule).Should(Equal(RuleDailyLimit))
+		})
+
+		It("should not count the spends of other accounts", func() {
+			Expect(engine.AuthorizeTransfer(transfer("a", 1000))).Should(Succeed())
+			other := transfer("b", 1000)
+			other.Account = "other"
+			Expect(engine.AuthorizeTransfer(other)).Should(Succeed())
+		})
+
+		It("should not count revoked spends", func() {
+			Expect(engine.AuthorizeTransfer(transfer("a", 1000))).Should(Succeed())
+			Expect(engine.AuthorizeTransfer(transfer("b", 1))).Should(HaveOccurred())
+			Expect(engine.Revoke("a")).Should(Succeed())
+			Expect(engine.AuthorizeTransfer(transfer("b", 1000))).Should(Succeed())
+			Expect(engine.Revoke("unknown")).Should(Succeed())
+		})
+
+		It("should not count spends that have left the spend window", func() {
+			storage := &mockStorage{spends: map[string]Spend{
+				"old": {Account: account, Token: blockchain.BTC, Amount: "1000", Timestamp: time.Now().Add(-SpendWindow - time.Minute).Unix()},
+			}}
+			engine = New(Config{Default: Policy{
+				DailyLimits: map[blockchain.TokenName]string{blockchain.BTC: "1000"},
+			}}, storage)
+			Expect(engine.AuthorizeTransfer(transfer("a", 1000))).Should(Succeed())
+			Expect(storage.spends).ShouldNot(HaveKey("old"))
+			Expect(storage.spends).Should(HaveKey("a"))
+		})
+	})
+
+	Context("when the policy has allowed pairs", func() {
+		It("should only authorize swaps of the allowed pairs", func() {
+			engine := New(Config{Default: Policy{AllowedPairs: []string{"btc/eth"}}}, nil)
+			Expect(engine.AuthorizeSwap(swap("a", 1))).Should(Succeed())
+			reverse := Swap{ID: "b", Account: account, SendToken: eth, ReceiveToken: btc, SendAmount: big.NewInt(1)}
+			Expect(violation(engine.AuthorizeSwap(reverse)).Rule).Should(Equal(RuleAllowedPairs))
+		})
+	})
+
+	Context("when the policy has a maximum broker fee", func() {
+		It("should only authorize swaps within it", func() {
+			maxBrokerFee := int64(20)
+			engine := New(Config{Default: Policy{MaxBrokerFee: &maxBrokerFee}}, nil)
+			within := swap("a", 1)
+			within.BrokerFee = 20
+			Expect(engine.AuthorizeSwap(within)).Should(Succeed())
+			above := swap("b", 1)
+			above.BrokerFee = 21
+			Expect(violation(engine.AuthorizeSwap(above)).Rule).Should(Equal(RuleMaxBrokerFee))
+		})
+	})
+
+	Context("when the pair has a minimum price", func() {
+		var engine Engine
+
+		BeforeEach(func() {
+			engine = New(Config{Default: Policy{MinPrices: map[string]string{"BTC/ETH": "30.5"}}}, nil)
+		})
+
+		It("should only authorize swaps at or above it", func() {
+			// 1 BTC for 30.5 ETH
+			at := swap("a", 100000000)
+			at.ReceiveAmount, _ = new(big.Int).SetString("30500000000000000000", 10)
+			Expect(engine.AuthorizeSwap(at)).Should(Succeed())
+
+			below := swap("b", 100000000)
+			below.ReceiveAmount, _ = new(big.Int).SetString("30000000000000000000", 10)
+			err := engine.AuthorizeSwap(below)
+			Expect(violation(err).Rule).Should(Equal(RuleMinPrice))
+			Expect(violation(err).Value).Should(Equal("30.00000000"))
+		})
+
+		It("should match the pair whatever its case", func() {
+			engine = New(Config{Default: Policy{MinPrices: map[string]string{"btc/eth": "30.5"}}}, nil)
+			below := swap("a", 100000000)
+			below.ReceiveAmount, _ = new(big.Int).SetString("30000000000000000000", 10)
+			Expect(violation(engine.AuthorizeSwap(below)).Rule).Should(Equal(RuleMinPrice))
+		})
+
+		It("should not authorize swaps whose receive amount is unknown", func() {
+			err := engine.AuthorizeSwap(swap("a", 100000000))
+			Expect(violation(err).Rule).Should(Equal(RuleMinPrice))
+			Expect(violation(err).Value).Should(Equal(PriceUnknown))
+		})
+	})
+
+	Context("when the account has its own policy", func() {
+		It("should use it instead of the default policy", func() {
+			engine := New(Config{
+				Default: Policy{TransactionLimits: map[blockchain.TokenName]string{blockchain.BTC: "1"}},
+				Accounts: map[string]Policy{
+					account: {TransactionLimits: map[blockchain.TokenName]string{blockchain.BTC: "1000"}},
+				},
+			}, nil)
+			Expect(engine.AuthorizeTransfer(transfer("a", 1000))).Should(Succeed())
+			other := transfer("b", 2)
+			other.Account = "other"
+			Expect(violation(engine.AuthorizeTransfer(other)).Rule).Should(Equal(RuleTransactionLimit))
+		})
+	})
+})
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/republicprotocol/swapperd/adapter/policy"
 	"github.com/republicprotocol/swapperd/adapter/wallet"
 	"github.com/republicprotocol/swapperd/core/swapper"
 	"github.com/republicprotocol/swapperd/core/swapper/status"
@@ -34,6 +35,7 @@ type handler struct {
 	swapperTask tau.Task
 	walletTask  tau.Task
 	wallet      wallet.Wallet
+	policies    policy.Engine
 	logger      logrus.FieldLogger
 }
 
@@ -41,22 +43,33 @@ type handler struct {
 type Handler interface {
 	GetID(password string) (GetIDResponse, error)
 	GetInfo(password string) GetInfoResponse
+	PatchToken(token string) (blockchain.Token, error)
 	GetSwap(password string, id swap.SwapID) (GetSwapResponse, error)
 	GetSwaps(password string) (GetSwapsResponse, error)
+	GetRefund(password string, id swap.SwapID) (GetRefundResponse, error)
 	GetBalances(password string) (GetBalancesResponse, error)
 	GetAddresses(password string) (GetAddressesResponse, error)
 	GetTransfers(password string) (GetTransfersResponse, error)
+	GetAllowances(password string) (GetAllowancesResponse, error)
+	GetAddressBook(password string) (GetAddressBookResponse, error)
+	PostAddressBook(PostAddressBookRequest) (PostAddressBookResponse, error)
+	PostDeleteAddressBook(PostDeleteAddressBookRequest) error
 	GetJSONSignature(password string, message json.RawMessage) (GetSignatureResponseJSON, error)
 	GetBase64Signature(password string, message string) (GetSignatureResponseString, error)
 	GetHexSignature(password string, message string) (GetSignatureResponseString, error)
 	PostTransfers(PostTransfersRequest) (PostTransfersResponse, error)
 	PostSwaps(PostSwapRequest) (PostSwapResponse, error)
+	PostRevokeAllowance(PostRevokeAllowanceRequest) (PostRevokeAllowanceResponse, error)
+	GetBrokerFees(password string) (GetBrokerFeesResponse, error)
+	PostBrokerWithdraw(PostBrokerWithdrawRequest) (PostBrokerWithdrawResponse, error)
+	PostRebroadcastTransfer(PostRebroadcastTransferRequest) (PostRebroadcastTransferResponse, error)
+	PostCancelTransfer(PostCancelTransferRequest) (PostCancelTransferResponse, error)
 	PostDelayedSwaps(PostSwapRequest) error
-	PostBootload(password string) error
+	PostBootload(PostBootloadRequest) error
 }
 
-func NewHandler(swapperTask, walletTask tau.Task, wallet wallet.Wallet, logger logrus.FieldLogger) Handler {
-	return &handler{map[string]bool{}, swapperTask, walletTask, wallet, logger}
+func NewHandler(swapperTask, walletTask tau.Task, wallet wallet.Wallet, policies policy.Engine, logger logrus.FieldLogger) Handler {
+	return &handler{map[string]bool{}, swapperTask, walletTask, wallet, policies, logger}
 }
 
 func (handler *handler) GetInfo(password string) GetInfoResponse {
@@ -64,9 +77,15 @@ func (handler *handler) GetInfo(password string) GetInfoResponse {
 		Version:         "0.3.0",
 		Bootloaded:      handler.bootloaded[passwordHash(password)],
 		SupportedTokens: handler.wallet.SupportedTokens(),
+		Contracts:       handler.wallet.ContractStatuses(),
+		WatchOnly:       handler.wallet.WatchOnly(),
 	}
 }
 
+func (handler *handler) PatchToken(token string) (blockchain.Token, error) {
+	return handler.wallet.PatchToken(token)
+}
+
 func (handler *handler) GetAddresses(password string) (GetAddressesResponse, error) {
 	return handler.wallet.Addresses(password)
 }
@@ -110,6 +129,30 @@ func (handler *handler) GetSwap(password string, id swap.SwapID) (GetSwapRespons
 	return GetSwapResponse(receipt), nil
 }
 
+func (handler *handler) GetRefund(password string, id swap.SwapID) (GetRefundResponse, error) {
+	swapReceipts, err := handler.getSwapReceipts(password)
+	if err != nil {
+		return GetRefundResponse{}, err
+	}
+
+	receipt, ok := swapReceipts[id]
+	if !ok {
+		return GetRefundResponse{}, fmt.Errorf("swap receipt not found")
+	}
+	if receipt.RefundTx == "" {
+		if receipt.RefundTxError != "" {
+			return GetRefundResponse{}, fmt.Errorf("swap has no pre-signed refund: %s", receipt.RefundTxError)
+		}
+		return GetRefundResponse{}, fmt.Errorf("swap has no pre-signed refund")
+	}
+
+	return GetRefundResponse{
+		ID:       receipt.ID,
+		TimeLock: receipt.TimeLock,
+		RefundTx: receipt.RefundTx,
+	}, nil
+}
+
 func (handler *handler) getSwapReceipts(password string) (map[swap.SwapID]swap.SwapReceipt, error) {
 	if !handler.bootloaded[passwordHash(password)] {
 		return nil, NewErrBootloadRequired("get swaps")
@@ -148,15 +191,162 @@ func (handler *handler) GetTransfers(password string) (GetTransfersResponse, err
 	return MarshalGetTransfersResponse(receiptMap), nil
 }
 
+func (handler *handler) GetAllowances(password string) (GetAllowancesResponse, error) {
+	allowances, err := handler.wallet.Allowances(password)
+	return GetAllowancesResponse(allowances), err
+}
+
+func (handler *handler) GetAddressBook(password string) (GetAddressBookResponse, error) {
+	if !handler.bootloaded[passwordHash(password)] {
+		return GetAddressBookResponse{}, NewErrBootloadRequired("get address book")
+	}
+	account, err := handler.account(password)
+	if err != nil {
+		return GetAddressBookResponse{}, err
+	}
+	entries, err := handler.wallet.AddressBook(account)
+	return GetAddressBookResponse(entries), err
+}
+
+func (handler *handler) PostAddressBook(req PostAddressBookRequest) (PostAddressBookResponse, error) {
+	if !handler.bootloaded[passwordHash(req.Password)] {
+		return PostAddressBookResponse{}, NewErrBootloadRequired("post address book")
+	}
+	account, err := handler.account(req.Password)
+	if err != nil {
+		return PostAddressBookResponse{}, err
+	}
+	token, err := handler.wallet.PatchToken(req.Token)
+	if err != nil {
+		return PostAddressBookResponse{}, err
+	}
+	entry, err := handler.wallet.AddAddressBookEntry(account, token, req.Address, req.Label)
+	return PostAddressBookResponse(entry), err
+}
+
+func (handler *handler) PostDeleteAddressBook(req PostDeleteAddressBookRequest) error {
+	if !handler.bootloaded[passwordHash(req.Password)] {
+		return NewErrBootloadRequired("delete address book")
+	}
+	account, err := handler.account(req.Password)
+	if err != nil {
+		return err
+	}
+	token, err := handler.wallet.PatchToken(req.Token)
+	if err != nil {
+		return err
+	}
+	return handler.wallet.DeleteAddressBookEntry(account, token, req.Address)
+}
+
+func (handler *handler) PostRevokeAllowance(req PostRevokeAllowanceRequest) (PostRevokeAllowanceResponse, error) {
+	if !handler.bootloaded[passwordHash(req.Password)] {
+		return PostRevokeAllowanceResponse{}, NewErrBootloadRequired("revoke allowance")
+	}
+	token, err := handler.wallet.PatchToken(req.Token)
+	if err != nil {
+		return PostRevokeAllowanceResponse{}, err
+	}
+
+	txHash, err := handler.wallet.RevokeAllowance(req.Password, token)
+	if err != nil {
+		return PostRevokeAllowanceResponse{}, err
+	}
+	return PostRevokeAllowanceResponse{
+		Token:  token,
+		TxHash: txHash,
+	}, nil
+}
+
+func (handler *handler) GetBrokerFees(password string) (GetBrokerFeesResponse, error) {
+	fees, err := handler.wallet.BrokerFees(password)
+	return GetBrokerFeesResponse(fees), err
+}
+
+func (handler *handler) PostBrokerWithdraw(req PostBrokerWithdrawRequest) (PostBrokerWithdrawResponse, error) {
+	if !handler.bootloaded[passwordHash(req.Password)] {
+		return PostBrokerWithdrawResponse{}, NewErrBootloadRequired("withdraw broker fees")
+	}
+	if handler.wallet.WatchOnly() {
+		return PostBrokerWithdrawResponse{}, wallet.ErrWatchOnly
+	}
+	token, err := handler.wallet.PatchToken(req.Token)
+	if err != nil {
+		return PostBrokerWithdrawResponse{}, err
+	}
+	if token.Blockchain != blockchain.Ethereum {
+		return PostBrokerWithdrawResponse{}, fmt.Errorf("broker fees are only accrued in ethereum swapperd contracts, not %s", token.Blockchain)
+	}
+
+	var amount *big.Int
+	if req.Amount != "" {
+		var ok bool
+		amount, ok = new(big.Int).SetString(req.Amount, 10)
+		if !ok {
+			return PostBrokerWithdrawResponse{}, fmt.Errorf("invalid amount %s", req.Amount)
+		}
+	}
+
+	responder := make(chan transfer.WithdrawResponse, 1)
+	handler.walletTask.IO().InputWriter() <- transfer.NewWithdrawRequest(req.Password, token, amount, responder)
+	response := <-responder
+	if response.Err != nil {
+		return PostBrokerWithdrawResponse{}, response.Err
+	}
+	response.Receipt.PasswordHash = ""
+	return PostBrokerWithdrawResponse(response.Receipt), nil
+}
+
+func (handler *handler) PostRebroadcastTransfer(req PostRebroadcastTransferRequest) (PostRebroadcastTransferResponse, error) {
+	if !handler.bootloaded[passwordHash(req.Password)] {
+		return PostRebroadcastTransferResponse{}, NewErrBootloadRequired("rebroadcast transfer")
+	}
+	responder := make(chan transfer.TransferResponse, 1)
+	handler.walletTask.IO().InputWriter() <- transfer.NewRebroadcastRequest(req.Password, req.TxHash, responder)
+	response := <-responder
+	if response.Err != nil {
+		return PostRebroadcastTransferResponse{}, response.Err
+	}
+	response.Receipt.PasswordHash = ""
+	return PostRebroadcastTransferResponse(response.Receipt), nil
+}
+
+func (handler *handler) PostCancelTransfer(req PostCancelTransferRequest) (PostCancelTransferResponse, error) {
+	if !handler.bootloaded[passwordHash(req.Password)] {
+		return PostCancelTransferResponse{}, NewErrBootloadRequired("cancel transfer")
+	}
+	responder := make(chan transfer.TransferResponse, 1)
+	handler.walletTask.IO().InputWriter() <- transfer.NewCancelRequest(req.Password, req.TxHash, responder)
+	response := <-responder
+	if response.Err != nil {
+		return PostCancelTransferResponse{}, response.Err
+	}
+	response.Receipt.PasswordHash = ""
+	return PostCancelTransferResponse(response.Receipt), nil
+}
+
 func (handler *handler) PostSwaps(swapReq PostSwapRequest) (PostSwapResponse, error) {
 	if !handler.bootloaded[passwordHash(swapReq.Password)] {
 		return PostSwapResponse{}, NewErrBootloadRequired("post swaps")
 	}
+	if handler.wallet.WatchOnly() {
+		return PostSwapResponse{}, wallet.ErrWatchOnly
+	}
 
 	blob, err := handler.patchSwap(swap.SwapBlob(swapReq))
 	if err != nil {
 		return PostSwapResponse{}, err
 	}
+	if err := handler.authorizeSwap(blob, blob.ReceiveAmount); err != nil {
+		return PostSwapResponse{}, err
+	}
+	if err := handler.reserveSendAmount(blob); err != nil {
+		handler.revoke(string(blob.ID))
+		return PostSwapResponse{}, err
+	}
+	if blob, err = handler.allocateAddresses(blob); err != nil {
+		return PostSwapResponse{}, err
+	}
 
 	handler.swapperTask.IO().InputWriter() <- swapper.SwapRequest(blob)
 	return handler.buildSwapResponse(blob)
@@ -166,6 +356,9 @@ func (handler *handler) PostDelayedSwaps(swapReq PostSwapRequest) error {
 	if !handler.bootloaded[passwordHash(swapReq.Password)] {
 		return NewErrBootloadRequired("post swaps")
 	}
+	if handler.wallet.WatchOnly() {
+		return wallet.ErrWatchOnly
+	}
 
 	blob, err := handler.patchDelayedSwap(swap.SwapBlob(swapReq))
 	if err != nil {
@@ -177,13 +370,29 @@ func (handler *handler) PostDelayedSwaps(swapReq PostSwapRequest) error {
 		return err
 	}
 
-	handler.swapperTask.IO().InputWriter() <- swapper.SwapRequest(swapReq)
+	// The price of a delayed swap is only known once it is filled, so the
+	// minimum receive amount is the worst price it can be filled at
+	if err := handler.authorizeSwap(blob, blob.MinimumReceiveAmount); err != nil {
+		return err
+	}
+	if err := handler.reserveSendAmount(blob); err != nil {
+		handler.revoke(string(blob.ID))
+		return err
+	}
+	if blob, err = handler.allocateAddresses(blob); err != nil {
+		return err
+	}
+
+	handler.swapperTask.IO().InputWriter() <- swapper.SwapRequest(blob)
 	return nil
 }
 
 func (handler *handler) PostTransfers(req PostTransfersRequest) (PostTransfersResponse, error) {
 	response := PostTransfersResponse{}
-	token, err := blockchain.PatchToken(req.Token)
+	if handler.wallet.WatchOnly() {
+		return response, wallet.ErrWatchOnly
+	}
+	token, err := handler.wallet.PatchToken(req.Token)
 	if err != nil {
 		return response, err
 	}
@@ -192,30 +401,91 @@ func (handler *handler) PostTransfers(req PostTransfersRequest) (PostTransfersRe
 	if err := handler.wallet.VerifyAddress(token.Blockchain, req.To); err != nil {
 		return response, err
 	}
-
-	amount, ok := big.NewInt(0).SetString(req.Amount, 10)
-	if !ok {
-		return response, fmt.Errorf("invalid amount %s", req.Amount)
+	if err := handler.verifyWhitelisted(req.Password, token, req.To); err != nil {
+		return response, err
 	}
 
-	if err := handler.wallet.VerifyBalance(req.Password, token, amount); err != nil {
+	fee, err := handler.transferFee(req, token)
+	if err != nil {
 		return response, err
 	}
 
-	fee, err := handler.wallet.DefaultFee(token.Blockchain)
+	var amount *big.Int
+	if req.SendMax {
+		if req.Amount != "" {
+			return response, fmt.Errorf("cannot send the max amount and an amount of %s", req.Amount)
+		}
+		if amount, err = handler.wallet.MaxTransferAmount(req.Password, token, fee); err != nil {
+			return response, err
+		}
+	} else {
+		var ok bool
+		if amount, ok = big.NewInt(0).SetString(req.Amount, 10); !ok {
+			return response, fmt.Errorf("invalid amount %s", req.Amount)
+		}
+	}
+
+	// The amount and the fee stay reserved until the transfer is confirmed
+	account, err := handler.account(req.Password)
 	if err != nil {
 		return response, err
 	}
+	reservationID := string(swap.RandomID())
+	if err := handler.policies.AuthorizeTransfer(policy.Transfer{
+		ID:      reservationID,
+		Account: account,
+		Token:   token.Name,
+		Amount:  amount,
+	}); err != nil {
+		return response, err
+	}
+	if err := handler.wallet.ReserveTransfer(req.Password, reservationID, token, amount, fee); err != nil {
+		handler.revoke(reservationID)
+		return response, err
+	}
 
-	handler.walletTask.IO().InputWriter() <- transfer.NewTransferRequest(req.Password, token, req.To, amount, fee, responder)
+	transferReq := transfer.NewTransferRequest(req.Password, token, req.To, amount, fee, responder)
+	transferReq.ReservationID = reservationID
+	handler.walletTask.IO().InputWriter() <- transferReq
 	transferReceipt := <-responder
 	return PostTransfersResponse(transferReceipt), nil
 }
 
-func (handler *handler) PostBootload(password string) error {
+// transferFee returns the fee in the request, or the fee of its priority.
+func (handler *handler) transferFee(req PostTransfersRequest, token blockchain.Token) (*big.Int, error) {
+	if req.Fee == "" {
+		return handler.wallet.PriorityFee(token.Blockchain, req.Priority)
+	}
+	if req.Priority != "" {
+		return nil, fmt.Errorf("cannot set both a fee and a priority")
+	}
+	fee, ok := big.NewInt(0).SetString(req.Fee, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid fee %s", req.Fee)
+	}
+	if err := handler.wallet.VerifyFee(token.Blockchain, fee); err != nil {
+		return nil, err
+	}
+	return fee, nil
+}
+
+// ErrPassphraseRequired is returned when the keystore is locked and the
+// bootload has no keystore passphrase.
+var ErrPassphraseRequired = fmt.Errorf("keystore is locked, bootload with the keystore passphrase")
+
+func (handler *handler) PostBootload(req PostBootloadRequest) error {
+	password := req.Password
 	if handler.bootloaded[passwordHash(password)] {
 		return fmt.Errorf("already bootloaded")
 	}
+	if handler.wallet.Locked() {
+		if req.Passphrase == "" {
+			return ErrPassphraseRequired
+		}
+		if err := handler.wallet.Unlock(req.Passphrase); err != nil {
+			return fmt.Errorf("failed to unlock the keystore: %v", err)
+		}
+	}
 	handler.swapperTask.IO().InputWriter() <- swapper.Bootload{password}
 	handler.bootloaded[passwordHash(password)] = true
 	return nil
@@ -281,7 +551,7 @@ func (handler *handler) GetHexSignature(password string, message string) (GetSig
 }
 
 func (handler *handler) patchSwap(swapBlob swap.SwapBlob) (swap.SwapBlob, error) {
-	sendToken, err := blockchain.PatchToken(swapBlob.SendToken)
+	sendToken, err := handler.wallet.PatchToken(swapBlob.SendToken)
 	if err != nil {
 		return swapBlob, err
 	}
@@ -290,7 +560,15 @@ func (handler *handler) patchSwap(swapBlob swap.SwapBlob) (swap.SwapBlob, error)
 		return swapBlob, err
 	}
 
-	receiveToken, err := blockchain.PatchToken(swapBlob.ReceiveToken)
+	if err := handler.verifyWhitelisted(swapBlob.Password, sendToken, swapBlob.SendTo); err != nil {
+		return swapBlob, err
+	}
+
+	if err := handler.wallet.VerifyContract(sendToken); err != nil {
+		return swapBlob, err
+	}
+
+	receiveToken, err := handler.wallet.PatchToken(swapBlob.ReceiveToken)
 	if err != nil {
 		return swapBlob, err
 	}
@@ -299,6 +577,10 @@ func (handler *handler) patchSwap(swapBlob swap.SwapBlob) (swap.SwapBlob, error)
 		return swapBlob, err
 	}
 
+	if err := handler.wallet.VerifyContract(receiveToken); err != nil {
+		return swapBlob, err
+	}
+
 	if err := handler.verifySendAmount(swapBlob.Password, sendToken, swapBlob.SendAmount); err != nil {
 		return swapBlob, err
 	}
@@ -307,6 +589,14 @@ func (handler *handler) patchSwap(swapBlob swap.SwapBlob) (swap.SwapBlob, error)
 		return swapBlob, err
 	}
 
+	if err := handler.verifySwapFees(swapBlob, sendToken, receiveToken); err != nil {
+		return swapBlob, err
+	}
+
+	if !swap.ValidTimeLockMode(swapBlob.TimeLockMode) {
+		return swapBlob, fmt.Errorf("invalid timelock mode: %s", swapBlob.TimeLockMode)
+	}
+
 	swapID := [32]byte{}
 	rand.Read(swapID[:])
 	swapBlob.ID = swap.SwapID(base64.StdEncoding.EncodeToString(swapID[:]))
@@ -316,7 +606,7 @@ func (handler *handler) patchSwap(swapBlob swap.SwapBlob) (swap.SwapBlob, error)
 		secret = genereateSecret(swapBlob.Password, swapBlob.ID)
 		hash := sha256.Sum256(secret[:])
 		swapBlob.SecretHash = base64.StdEncoding.EncodeToString(hash[:])
-		return swapBlob, nil
+		return handler.patchTimeLockBlocks(swapBlob, sendToken, receiveToken)
 	}
 
 	secretHash, err := base64.StdEncoding.DecodeString(swapBlob.SecretHash)
@@ -326,9 +616,67 @@ func (handler *handler) patchSwap(swapBlob swap.SwapBlob) (swap.SwapBlob, error)
 	if time.Now().Unix()+2*swap.ExpiryUnit > swapBlob.TimeLock {
 		return swapBlob, fmt.Errorf("not enough time to do the atomic swap")
 	}
+	if err := handler.verifyTimeLockBlocks(swapBlob, sendToken, receiveToken); err != nil {
+		return swapBlob, err
+	}
 	return swapBlob, nil
 }
 
+// patchTimeLockBlocks sets the blocks of the timelocks of the legs on UTXO
+// blockchains, when the initiator uses block based timelocks. The leg of the
+// responder expires one expiry unit before the leg of the initiator. Relative
+// timelocks start when each leg is funded, so the responder is expected to
+// fund within an expiry unit of the initiator.
+func (handler *handler) patchTimeLockBlocks(swapBlob swap.SwapBlob, sendToken, receiveToken blockchain.Token) (swap.SwapBlob, error) {
+	if swapBlob.SendTimeLockBlocks == 0 {
+		blocks, err := handler.wallet.TimeLockBlocks(sendToken.Blockchain, swapBlob.TimeLockMode, swapBlob.TimeLock)
+		if err != nil {
+			return swapBlob, fmt.Errorf("failed to calculate the timelock of %s: %v", sendToken.Name, err)
+		}
+		swapBlob.SendTimeLockBlocks = blocks
+	}
+	if swapBlob.ReceiveTimeLockBlocks == 0 {
+		blocks, err := handler.wallet.TimeLockBlocks(receiveToken.Blockchain, swapBlob.TimeLockMode, swapBlob.TimeLock-swap.ExpiryUnit)
+		if err != nil {
+			return swapBlob, fmt.Errorf("failed to calculate the timelock of %s: %v", receiveToken.Name, err)
+		}
+		swapBlob.ReceiveTimeLockBlocks = blocks
+	}
+	return swapBlob, nil
+}
+
+// verifyTimeLockBlocks checks that the initiator has set the blocks of the
+// timelocks of the legs on UTXO blockchains, when it uses block based
+// timelocks, and that the leg of the initiator expires TimeLockMargin after
+// the leg of the responder. The expiries are estimated from the current
+// height and the block time of the blockchains, as if both legs were funded
+// now. A leg without blocks expires at its timelock, which is at the latest
+// half an expiry unit before the timelock of the swap for the responder.
+func (handler *handler) verifyTimeLockBlocks(swapBlob swap.SwapBlob, sendToken, receiveToken blockchain.Token) error {
+	if swapBlob.TimeLockMode == "" || swapBlob.TimeLockMode == swap.TimeLockModeTime {
+		return nil
+	}
+	if sendToken.Blockchain != blockchain.Ethereum && swapBlob.SendTimeLockBlocks <= 0 {
+		return fmt.Errorf("missing %s timelock blocks of %s", swapBlob.TimeLockMode, sendToken.Name)
+	}
+	if receiveToken.Blockchain != blockchain.Ethereum && swapBlob.ReceiveTimeLockBlocks <= 0 {
+		return fmt.Errorf("missing %s timelock blocks of %s", swapBlob.TimeLockMode, receiveToken.Name)
+	}
+
+	sendExpiry, err := handler.wallet.TimeLockExpiry(sendToken.Blockchain, swapBlob.TimeLockMode, swapBlob.TimeLock-swap.ExpiryUnit/2, swapBlob.SendTimeLockBlocks)
+	if err != nil {
+		return fmt.Errorf("failed to estimate the expiry of %s: %v", sendToken.Name, err)
+	}
+	receiveExpiry, err := handler.wallet.TimeLockExpiry(receiveToken.Blockchain, swapBlob.TimeLockMode, swapBlob.TimeLock, swapBlob.ReceiveTimeLockBlocks)
+	if err != nil {
+		return fmt.Errorf("failed to estimate the expiry of %s: %v", receiveToken.Name, err)
+	}
+	if receiveExpiry-sendExpiry < swap.TimeLockMargin {
+		return fmt.Errorf("%s timelock expires %ds after the %s timelock, at least %ds is required", receiveToken.Name, receiveExpiry-sendExpiry, sendToken.Name, swap.TimeLockMargin)
+	}
+	return nil
+}
+
 func (handler *handler) patchDelayedSwap(blob swap.SwapBlob) (swap.SwapBlob, error) {
 	if blob.DelayCallbackURL == "" {
 		return blob, fmt.Errorf("delay url cannot be empty")
@@ -338,21 +686,34 @@ func (handler *handler) patchDelayedSwap(blob swap.SwapBlob) (swap.SwapBlob, err
 	rand.Read(swapID[:])
 	blob.ID = swap.SwapID(base64.StdEncoding.EncodeToString(swapID[:]))
 
-	sendToken, err := blockchain.PatchToken(blob.SendToken)
+	sendToken, err := handler.wallet.PatchToken(blob.SendToken)
 	if err != nil {
 		return blob, err
 	}
+	if blob.SendTo != "" {
+		if err := handler.wallet.VerifyAddress(sendToken.Blockchain, blob.SendTo); err != nil {
+			return blob, err
+		}
+	}
+	// The callback cannot change the address of a delayed swap, so whitelist
+	// only wallets need it to be set when the swap is posted
+	if err := handler.verifyWhitelisted(blob.Password, sendToken, blob.SendTo); err != nil {
+		return blob, err
+	}
 	if err := handler.verifySendAmount(blob.Password, sendToken, blob.SendAmount); err != nil {
 		return blob, err
 	}
 
-	receiveToken, err := blockchain.PatchToken(blob.ReceiveToken)
+	receiveToken, err := handler.wallet.PatchToken(blob.ReceiveToken)
 	if err != nil {
 		return blob, err
 	}
 	if err := handler.verifyReceiveAmount(blob.Password, receiveToken); err != nil {
 		return blob, err
 	}
+	if err := handler.verifySwapFees(blob, sendToken, receiveToken); err != nil {
+		return blob, err
+	}
 
 	secret := genereateSecret(blob.Password, blob.ID)
 	secretHash := sha256.Sum256(secret[:])
@@ -369,6 +730,143 @@ func (handler *handler) verifySendAmount(password string, token blockchain.Token
 	return handler.wallet.VerifyBalance(password, token, sendAmount)
 }
 
+// verifySwapFees verifies the send and receive fees of the swap, when they
+// are set.
+func (handler *handler) verifySwapFees(blob swap.SwapBlob, sendToken, receiveToken blockchain.Token) error {
+	if err := handler.verifySwapFee(sendToken, blob.SendFee); err != nil {
+		return fmt.Errorf("invalid send fee: %v", err)
+	}
+	if err := handler.verifySwapFee(receiveToken, blob.ReceiveFee); err != nil {
+		return fmt.Errorf("invalid receive fee: %v", err)
+	}
+	return nil
+}
+
+func (handler *handler) verifySwapFee(token blockchain.Token, fee string) error {
+	if fee == "" {
+		return nil
+	}
+	amount, ok := new(big.Int).SetString(fee, 10)
+	if !ok {
+		return fmt.Errorf("%s is not a number", fee)
+	}
+	return handler.wallet.VerifyFee(token.Blockchain, amount)
+}
+
+// reserveSendAmount reserves the send amount of the swap, and the fee of
+// funding its native contract, until the contract is funded, so that the same
+// funds cannot be committed to another swap in the meantime.
+func (handler *handler) reserveSendAmount(blob swap.SwapBlob) error {
+	sendToken, err := handler.wallet.PatchToken(blob.SendToken)
+	if err != nil {
+		return err
+	}
+	sendAmount, ok := new(big.Int).SetString(blob.SendAmount, 10)
+	if !ok {
+		return fmt.Errorf("invalid send amount")
+	}
+	var sendFee *big.Int
+	if blob.SendFee != "" {
+		if sendFee, ok = new(big.Int).SetString(blob.SendFee, 10); !ok {
+			return fmt.Errorf("invalid send fee %s", blob.SendFee)
+		}
+	}
+	return handler.wallet.ReserveSwap(blob.Password, string(blob.ID), sendToken, sendAmount, sendFee)
+}
+
+// allocateAddresses allocates the address index of the swap once it has been
+// authorized and its send amount reserved, so that rejected swaps do not leave
+// unused addresses behind. The reservation and the spend of the swap are
+// undone when no index can be allocated.
+func (handler *handler) allocateAddresses(blob swap.SwapBlob) (swap.SwapBlob, error) {
+	addressIndex, err := handler.wallet.NextAddressIndex()
+	if err != nil {
+		if err := handler.wallet.ReleaseBalance(string(blob.ID)); err != nil {
+			handler.logger.Errorf("failed to release the reservation of %s: %v", blob.ID, err)
+		}
+		handler.revoke(string(blob.ID))
+		return blob, fmt.Errorf("failed to allocate swap addresses: %v", err)
+	}
+	blob.AddressIndex = addressIndex
+	return blob, nil
+}
+
+// authorizeSwap checks the swap against the policy of its account, with the
+// receive amount that its price is checked at. An empty receive amount means
+// the price is not known.
+func (handler *handler) authorizeSwap(blob swap.SwapBlob, receiveAmount string) error {
+	sendToken, err := handler.tokenConfig(blob.SendToken)
+	if err != nil {
+		return err
+	}
+	receiveToken, err := handler.tokenConfig(blob.ReceiveToken)
+	if err != nil {
+		return err
+	}
+	sendAmount, ok := new(big.Int).SetString(blob.SendAmount, 10)
+	if !ok {
+		return fmt.Errorf("invalid send amount")
+	}
+	var amount *big.Int
+	if receiveAmount != "" {
+		if amount, ok = new(big.Int).SetString(receiveAmount, 10); !ok {
+			return fmt.Errorf("invalid receive amount")
+		}
+	}
+	account, err := handler.account(blob.Password)
+	if err != nil {
+		return err
+	}
+	return handler.policies.AuthorizeSwap(policy.Swap{
+		ID:            string(blob.ID),
+		Account:       account,
+		SendToken:     sendToken,
+		ReceiveToken:  receiveToken,
+		SendAmount:    sendAmount,
+		ReceiveAmount: amount,
+		BrokerFee:     blob.BrokerFee,
+	})
+}
+
+func (handler *handler) tokenConfig(token string) (blockchain.TokenConfig, error) {
+	patchedToken, err := handler.wallet.PatchToken(token)
+	if err != nil {
+		return blockchain.TokenConfig{}, err
+	}
+	return handler.wallet.TokenConfig(patchedToken)
+}
+
+// account returns the id of the account of the password, which its policy and
+// its address book belong to. Requests whose account cannot be derived fail,
+// instead of falling back to the default policy.
+func (handler *handler) account(password string) (string, error) {
+	id, err := handler.wallet.ID(password)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("cannot derive the account of the password")
+	}
+	return id, nil
+}
+
+// verifyWhitelisted checks that the address book of the account of the
+// password allows the address to receive the token.
+func (handler *handler) verifyWhitelisted(password string, token blockchain.Token, address string) error {
+	account, err := handler.account(password)
+	if err != nil {
+		return err
+	}
+	return handler.wallet.VerifyWhitelisted(account, token, address)
+}
+
+// revoke revokes the spend of a request that failed after it was authorized.
+func (handler *handler) revoke(id string) {
+	if err := handler.policies.Revoke(id); err != nil {
+		handler.logger.Errorf("failed to revoke the policy spend of %s: %v", id, err)
+	}
+}
+
 func (handler *handler) verifyReceiveAmount(password string, token blockchain.Token) error {
 	return handler.wallet.VerifyBalance(password, token, nil)
 }
@@ -402,22 +900,22 @@ func (handler *handler) buildSwapResponse(blob swap.SwapBlob) (PostSwapResponse,
 	responseBlob.ReceiveAmount = blob.SendAmount
 	swapResponse := PostSwapResponse{}
 
-	sendToken, err := blockchain.PatchToken(responseBlob.SendToken)
+	sendToken, err := handler.wallet.PatchToken(responseBlob.SendToken)
 	if err != nil {
 		return swapResponse, err
 	}
 
-	receiveToken, err := blockchain.PatchToken(responseBlob.ReceiveToken)
+	receiveToken, err := handler.wallet.PatchToken(responseBlob.ReceiveToken)
 	if err != nil {
 		return swapResponse, err
 	}
 
-	sendTo, err := handler.wallet.GetAddress(blob.Password, sendToken.Blockchain)
+	sendTo, err := handler.wallet.GetAddressAt(blob.Password, sendToken.Blockchain, blob.AddressIndex)
 	if err != nil {
 		return swapResponse, err
 	}
 
-	receiveFrom, err := handler.wallet.GetAddress(blob.Password, receiveToken.Blockchain)
+	receiveFrom, err := handler.wallet.GetAddressAt(blob.Password, receiveToken.Blockchain, blob.AddressIndex)
 	if err != nil {
 		return swapResponse, err
 	}
@@ -426,6 +924,9 @@ func (handler *handler) buildSwapResponse(blob swap.SwapBlob) (PostSwapResponse,
 	responseBlob.ReceiveFrom = receiveFrom
 	responseBlob.SecretHash = blob.SecretHash
 	responseBlob.TimeLock = blob.TimeLock
+	responseBlob.TimeLockMode = blob.TimeLockMode
+	responseBlob.SendTimeLockBlocks = blob.ReceiveTimeLockBlocks
+	responseBlob.ReceiveTimeLockBlocks = blob.SendTimeLockBlocks
 
 	responseBlob.BrokerFee = blob.BrokerFee
 	responseBlob.BrokerSendTokenAddr = blob.BrokerReceiveTokenAddr
@@ -4,16 +4,17 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/republicprotocol/swapperd/adapter/policy"
 	"github.com/republicprotocol/swapperd/adapter/wallet"
 	"github.com/republicprotocol/swapperd/core/swapper"
 	"github.com/republicprotocol/swapperd/core/transfer"
-	"github.com/republicprotocol/swapperd/foundation/blockchain"
 	"github.com/republicprotocol/swapperd/foundation/swap"
 	"github.com/republicprotocol/tau"
 	"github.com/rs/cors"
@@ -27,6 +28,7 @@ type Storage interface {
 }
 type httpServer struct {
 	wallet      wallet.Wallet
+	policies    policy.Engine
 	logger      logrus.FieldLogger
 	port        string
 	loggedin    bool
@@ -34,8 +36,8 @@ type httpServer struct {
 	walletTask  tau.Task
 }
 
-func NewHttpServer(wallet wallet.Wallet, logger logrus.FieldLogger, swapperTask, walletTask tau.Task, port string) Server {
-	return &httpServer{wallet, logger, port, false, swapperTask, walletTask}
+func NewHttpServer(wallet wallet.Wallet, policies policy.Engine, logger logrus.FieldLogger, swapperTask, walletTask tau.Task, port string) Server {
+	return &httpServer{wallet, policies, logger, port, false, swapperTask, walletTask}
 }
 
 // NewHttpListener creates a new http listener
@@ -43,12 +45,22 @@ func (listener *httpServer) Run(done <-chan struct{}) {
 	go listener.swapperTask.Run(done)
 	go listener.walletTask.Run(done)
 
-	reqHandler := NewHandler(listener.swapperTask, listener.walletTask, listener.wallet, listener.logger)
+	reqHandler := NewHandler(listener.swapperTask, listener.walletTask, listener.wallet, listener.policies, listener.logger)
 	r := mux.NewRouter()
 	r.HandleFunc("/swaps", postSwapsHandler(reqHandler)).Methods("POST")
 	r.HandleFunc("/swaps", getSwapsHandler(reqHandler)).Queries("id", "{id}").Methods("GET")
+	r.HandleFunc("/swaps/refund", getRefundHandler(reqHandler)).Methods("GET")
 	r.HandleFunc("/transfers", postTransfersHandler(reqHandler)).Methods("POST")
 	r.HandleFunc("/transfers", getTransfersHandler(reqHandler)).Methods("GET")
+	r.HandleFunc("/transfers/rebroadcast", postRebroadcastTransferHandler(reqHandler)).Methods("POST")
+	r.HandleFunc("/transfers/cancel", postCancelTransferHandler(reqHandler)).Methods("POST")
+	r.HandleFunc("/allowances", getAllowancesHandler(reqHandler)).Methods("GET")
+	r.HandleFunc("/addressbook", getAddressBookHandler(reqHandler)).Methods("GET")
+	r.HandleFunc("/addressbook", postAddressBookHandler(reqHandler)).Methods("POST")
+	r.HandleFunc("/addressbook/delete", postDeleteAddressBookHandler(reqHandler)).Methods("POST")
+	r.HandleFunc("/allowances/revoke", postRevokeAllowanceHandler(reqHandler)).Methods("POST")
+	r.HandleFunc("/broker/fees", getBrokerFeesHandler(reqHandler)).Methods("GET")
+	r.HandleFunc("/broker/withdraw", postBrokerWithdrawHandler(reqHandler)).Methods("POST")
 	r.HandleFunc("/balances", getBalancesHandler(reqHandler)).Methods("GET")
 	r.HandleFunc("/balances/{token}", getBalancesHandler(reqHandler)).Methods("GET")
 	r.HandleFunc("/addresses", getAddressesHandler(reqHandler)).Methods("GET")
@@ -98,6 +110,22 @@ func writeError(w http.ResponseWriter, statusCode int, err string) {
 	w.Write([]byte(fmt.Sprintf("{ \"error\": \"%s\" }", err)))
 }
 
+// writeViolation writes a policy violation as a forbidden response, with the
+// rule that was violated, and returns true. It returns false if the error is
+// not a policy violation.
+func writeViolation(w http.ResponseWriter, err error) bool {
+	violation, ok := err.(policy.Violation)
+	if !ok {
+		return false
+	}
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(struct {
+		Error     string           `json:"error"`
+		Violation policy.Violation `json:"violation"`
+	}{violation.Error(), violation})
+	return true
+}
+
 // recoveryHandler handles errors while processing the requests and populates
 // the errors in the response.
 func recoveryHandler(h http.Handler) http.Handler {
@@ -121,7 +149,14 @@ func postBootloadHandler(reqHandler Handler) http.HandlerFunc {
 			return
 		}
 
-		if err := reqHandler.PostBootload(password); err != nil {
+		bootloadReq := PostBootloadRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&bootloadReq); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot decode bootload request: %v", err))
+			return
+		}
+		bootloadReq.Password = password
+
+		if err := reqHandler.PostBootload(bootloadReq); err != nil {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -207,6 +242,34 @@ func getSwapsHandler(reqHandler Handler) http.HandlerFunc {
 	}
 }
 
+// getRefundHandler handles the get refund request, it returns the pre-signed
+// refund transaction of the swap with the given id.
+func getRefundHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		swapID := r.FormValue("id")
+		if swapID == "" {
+			writeError(w, http.StatusBadRequest, "swap id required")
+			return
+		}
+
+		resp, err := reqHandler.GetRefund(password, swap.SwapID(swapID))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot get refund of swap with id (%s): %v", swapID, err))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode get refund response: %v", err))
+			return
+		}
+	}
+}
+
 // postSwapsHandler handles the post swaps request, it fills incomplete
 // information and starts the Atomic Swap.
 func postSwapsHandler(reqHandler Handler) http.HandlerFunc {
@@ -233,6 +296,9 @@ func postSwapsHandler(reqHandler Handler) http.HandlerFunc {
 
 		if swapReq.Delay {
 			if err := reqHandler.PostDelayedSwaps(swapReq); err != nil {
+				if writeViolation(w, err) {
+					return
+				}
 				writeError(w, http.StatusBadRequest, err.Error())
 				return
 			}
@@ -241,6 +307,9 @@ func postSwapsHandler(reqHandler Handler) http.HandlerFunc {
 		}
 		patchedSwap, err := reqHandler.PostSwaps(swapReq)
 		if err != nil {
+			if writeViolation(w, err) {
+				return
+			}
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -279,6 +348,9 @@ func postTransfersHandler(reqHandler Handler) http.HandlerFunc {
 
 		transferResp, err := reqHandler.PostTransfers(transferReq)
 		if err != nil {
+			if writeViolation(w, err) {
+				return
+			}
 			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot decode transfers request: %v", err))
 			return
 		}
@@ -292,6 +364,68 @@ func postTransfersHandler(reqHandler Handler) http.HandlerFunc {
 	}
 }
 
+// postRebroadcastTransferHandler handles the rebroadcast transfer request, and
+// broadcasts a pending, or dropped, transfer again.
+func postRebroadcastTransferHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		rebroadcastReq := PostRebroadcastTransferRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&rebroadcastReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot decode rebroadcast transfer request: %v", err))
+			return
+		}
+		rebroadcastReq.Password = password
+
+		rebroadcastResp, err := reqHandler.PostRebroadcastTransfer(rebroadcastReq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot rebroadcast transfer: %v", err))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(rebroadcastResp); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode rebroadcast transfer response: %v", err))
+			return
+		}
+	}
+}
+
+// postCancelTransferHandler handles the cancel transfer request, and replaces a
+// pending, or dropped, transfer with a transaction that sends nothing.
+func postCancelTransferHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		cancelReq := PostCancelTransferRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&cancelReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot decode cancel transfer request: %v", err))
+			return
+		}
+		cancelReq.Password = password
+
+		cancelResp, err := reqHandler.PostCancelTransfer(cancelReq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot cancel transfer: %v", err))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(cancelResp); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode cancel transfer response: %v", err))
+			return
+		}
+	}
+}
+
 // getBalancesHandler handles the get balances request, and returns the balances
 // of the accounts held by the swapper.
 func getBalancesHandler(reqHandler Handler) http.HandlerFunc {
@@ -314,7 +448,7 @@ func getBalancesHandler(reqHandler Handler) http.HandlerFunc {
 			return
 		}
 
-		token, err := blockchain.PatchToken(tokenName)
+		token, err := reqHandler.PatchToken(tokenName)
 		if err != nil {
 			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid token name: %s", tokenName))
 		}
@@ -350,6 +484,195 @@ func getTransfersHandler(reqHandler Handler) http.HandlerFunc {
 	}
 }
 
+// getAllowancesHandler handles the get allowances request, and returns the
+// allowances granted to the Swapperd contracts of the ERC20 tokens.
+func getAllowancesHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		allowances, err := reqHandler.GetAllowances(password)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot get allowances: %v", err))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(allowances); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode allowances response: %v", err))
+			return
+		}
+	}
+}
+
+// getAddressBookHandler handles the get address book request, and returns the
+// labelled addresses of every token.
+func getAddressBookHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		addressBook, err := reqHandler.GetAddressBook(password)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot get address book: %v", err))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(addressBook); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode address book response: %v", err))
+			return
+		}
+	}
+}
+
+// postAddressBookHandler handles the post address book request, and adds an
+// address to the address book. The address can only receive transfers once
+// it is active.
+func postAddressBookHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		addressBookReq := PostAddressBookRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&addressBookReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot decode address book request: %v", err))
+			return
+		}
+		addressBookReq.Password = password
+
+		addressBookResp, err := reqHandler.PostAddressBook(addressBookReq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot add address: %v", err))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(addressBookResp); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode address book response: %v", err))
+			return
+		}
+	}
+}
+
+// postDeleteAddressBookHandler handles the delete address book request, and
+// deletes an address from the address book.
+func postDeleteAddressBookHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		deleteReq := PostDeleteAddressBookRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&deleteReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot decode delete address request: %v", err))
+			return
+		}
+		deleteReq.Password = password
+
+		if err := reqHandler.PostDeleteAddressBook(deleteReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot delete address: %v", err))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// postRevokeAllowanceHandler handles the post revoke allowance request, it
+// resets the allowance granted to the Swapperd contract of an ERC20 token.
+func postRevokeAllowanceHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		revokeReq := PostRevokeAllowanceRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&revokeReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot decode revoke allowance request: %v", err))
+			return
+		}
+		revokeReq.Password = password
+
+		revokeResp, err := reqHandler.PostRevokeAllowance(revokeReq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot revoke allowance: %v", err))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(revokeResp); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode revoke allowance response: %v", err))
+			return
+		}
+	}
+}
+
+// getBrokerFeesHandler handles the get broker fees request, and returns the fees
+// accrued by the wallet in the Swapperd contracts of the Ethereum tokens.
+func getBrokerFeesHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		fees, err := reqHandler.GetBrokerFees(password)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot get broker fees: %v", err))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(fees); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode broker fees response: %v", err))
+			return
+		}
+	}
+}
+
+// postBrokerWithdrawHandler handles the post broker withdraw request, it
+// withdraws the fees accrued in the Swapperd contract of a token and records
+// the withdrawal as a transfer.
+func postBrokerWithdrawHandler(reqHandler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		withdrawReq := PostBrokerWithdrawRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&withdrawReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot decode broker withdraw request: %v", err))
+			return
+		}
+		withdrawReq.Password = password
+
+		withdrawResp, err := reqHandler.PostBrokerWithdraw(withdrawReq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot withdraw broker fees: %v", err))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(withdrawResp); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot encode broker withdraw response: %v", err))
+			return
+		}
+	}
+}
+
 // getAddressesHandler handles the get addresses request, and returns the addresses
 // of the accounts held by the swapper.
 func getAddressesHandler(reqHandler Handler) http.HandlerFunc {
@@ -377,7 +700,7 @@ func getAddressesHandler(reqHandler Handler) http.HandlerFunc {
 			return
 		}
 
-		token, err := blockchain.PatchToken(tokenName)
+		token, err := reqHandler.PatchToken(tokenName)
 		if err != nil {
 			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid token name: %s", tokenName))
 			return
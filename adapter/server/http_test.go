@@ -18,6 +18,7 @@ import (
 	"github.com/republicprotocol/swapperd/adapter/binder"
 	"github.com/republicprotocol/swapperd/adapter/callback"
 	"github.com/republicprotocol/swapperd/adapter/db"
+	"github.com/republicprotocol/swapperd/adapter/policy"
 	"github.com/republicprotocol/swapperd/core/swapper"
 	"github.com/republicprotocol/swapperd/core/transfer"
 	"github.com/republicprotocol/swapperd/driver/keystore"
@@ -29,23 +30,23 @@ var _ = Describe("Server Adapter", func() {
 	done := make(chan struct{})
 
 	startServer := func() {
-		Expect(keystore.Generate("../../secrets", "testnet", "weird")).Should(BeNil())
-		blockchain, err := keystore.Wallet("../../secrets", "testnet")
+		Expect(keystore.Generate("../../secrets", "testnet", "passphrase", "weird")).Should(BeNil())
+		blockchain, err := keystore.Wallet("../../secrets", "testnet", "passphrase", nil)
 		Expect(err).Should(BeNil())
 		ldb, err := leveldb.NewStore("../../secrets", "testnet")
 		Expect(err).Should(BeNil())
 		storage := db.New(ldb)
 		logger := logger.NewStdOut()
-		swapperdTask := swapper.New(128, storage, binder.NewBuilder(blockchain, logger), callback.New())
+		swapperdTask := swapper.New(128, storage, binder.NewBuilder(blockchain, nil, logger), callback.New())
 		walletTask := transfer.New(128, blockchain, storage, logger)
 		go func() {
-			httpServer := NewHttpServer(blockchain, logger, swapperdTask, walletTask, "27927")
+			httpServer := NewHttpServer(blockchain, policy.New(policy.Config{}, nil), logger, swapperdTask, walletTask, "27927")
 			httpServer.Run(done)
 		}()
 	}
 
 	buildSwap := func(password string) swap.SwapBlob {
-		wallet, err := keystore.Wallet("../../secrets", "testnet")
+		wallet, err := keystore.Wallet("../../secrets", "testnet", "passphrase", nil)
 		Expect(err).Should(BeNil())
 		ethAddr, err := wallet.GetAddress(password, blockchain.Ethereum)
 		Expect(err).Should(BeNil())
@@ -3,6 +3,7 @@ package server
 import (
 	"encoding/json"
 
+	"github.com/republicprotocol/swapperd/adapter/wallet"
 	"github.com/republicprotocol/swapperd/core/transfer"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
 	"github.com/republicprotocol/swapperd/foundation/swap"
@@ -13,6 +14,14 @@ type GetInfoResponse struct {
 	Bootloaded           bool                    `json:"bootloaded"`
 	SupportedBlockchains []blockchain.Blockchain `json:"supportedBlockchains"`
 	SupportedTokens      []blockchain.Token      `json:"supportedTokens"`
+
+	// Contracts are the verification results of the Swapperd contracts of
+	// the Ethereum tokens.
+	Contracts map[blockchain.TokenName]wallet.ContractStatus `json:"contracts"`
+
+	// WatchOnly is set when the wallet only has extended public keys, and so
+	// cannot transfer, swap or sign.
+	WatchOnly bool `json:"watchOnly,omitempty"`
 }
 
 type GetSwapsResponse struct {
@@ -21,6 +30,14 @@ type GetSwapsResponse struct {
 
 type GetSwapResponse swap.SwapReceipt
 
+// GetRefundResponse is the pre-signed refund transaction of a swap, which can
+// be published once the timelock has passed.
+type GetRefundResponse struct {
+	ID       swap.SwapID `json:"id"`
+	TimeLock int64       `json:"timeLock"`
+	RefundTx string      `json:"refundTx"`
+}
+
 type GetBalancesResponse map[blockchain.TokenName]blockchain.Balance
 
 type GetAddressesResponse map[blockchain.TokenName]string
@@ -46,10 +63,90 @@ type PostTransfersRequest struct {
 	To       string `json:"to"`
 	Amount   string `json:"amount"`
 	Password string `json:"password"`
+
+	// Fee is the fee of the transfer, in satoshis for UTXO blockchains or as a
+	// gas price in wei for Ethereum. When it is empty the fee of the priority,
+	// one of "slow", "normal" or "fast", is paid. SendMax transfers the whole
+	// balance that is not reserved, less the fee, instead of the amount.
+	Fee      string `json:"fee,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	SendMax  bool   `json:"sendMax,omitempty"`
 }
 
 type PostTransfersResponse transfer.TransferReceipt
 
+type GetAllowancesResponse map[blockchain.TokenName]string
+
+type GetAddressBookResponse []wallet.AddressBookEntry
+
+// PostBootloadRequest bootloads the swaps of the password. The passphrase
+// unlocks an encrypted keystore, and is only needed by the first bootload
+// when the keystore was not unlocked at startup. It is never the password.
+type PostBootloadRequest struct {
+	Passphrase string `json:"passphrase,omitempty"`
+	Password   string `json:"password"`
+}
+
+// PostAddressBookRequest adds the address of the token to the address book,
+// or relabels it if it is already there.
+type PostAddressBookRequest struct {
+	Token    string `json:"token"`
+	Address  string `json:"address"`
+	Label    string `json:"label"`
+	Password string `json:"password"`
+}
+
+type PostAddressBookResponse wallet.AddressBookEntry
+
+// PostDeleteAddressBookRequest deletes the address of the token from the
+// address book.
+type PostDeleteAddressBookRequest struct {
+	Token    string `json:"token"`
+	Address  string `json:"address"`
+	Password string `json:"password"`
+}
+
+type PostRevokeAllowanceRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type PostRevokeAllowanceResponse struct {
+	Token  blockchain.Token `json:"token"`
+	TxHash string           `json:"txHash"`
+}
+
+type GetBrokerFeesResponse map[blockchain.TokenName]wallet.BrokerFee
+
+// PostBrokerWithdrawRequest withdraws the broker fees of the token. All the
+// fees are withdrawn when the amount is empty.
+type PostBrokerWithdrawRequest struct {
+	Token    string `json:"token"`
+	Amount   string `json:"amount,omitempty"`
+	Password string `json:"password"`
+}
+
+type PostBrokerWithdrawResponse transfer.TransferReceipt
+
+// PostRebroadcastTransferRequest broadcasts a pending, or dropped, transfer
+// again.
+type PostRebroadcastTransferRequest struct {
+	TxHash   string `json:"txHash"`
+	Password string `json:"password"`
+}
+
+type PostRebroadcastTransferResponse transfer.TransferReceipt
+
+// PostCancelTransferRequest replaces a pending, or dropped, Ethereum transfer
+// with a transaction that sends nothing.
+type PostCancelTransferRequest struct {
+	TxHash   string `json:"txHash"`
+	Password string `json:"password"`
+}
+
+// PostCancelTransferResponse is the receipt of the cancellation.
+type PostCancelTransferResponse transfer.TransferReceipt
+
 type GetSignatureResponseJSON struct {
 	Message   json.RawMessage `json:"message"`
 	Signature string          `json:"signature"`
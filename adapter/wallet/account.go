@@ -4,23 +4,29 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"crypto/rsa"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/republicprotocol/beth-go"
 	"github.com/republicprotocol/libbtc-go"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
 	"github.com/tyler-smith/go-bip32"
 	"github.com/tyler-smith/go-bip39"
 )
 
+// EthereumAccount returns the Ethereum account, whose key is the first key of
+// the external chain of its derivation.
 func (wallet *wallet) EthereumAccount(password string) (beth.Account, error) {
-	var derivationPath []uint32
-	switch wallet.config.Ethereum.Network.Name {
-	case "kovan", "ropsten":
-		derivationPath = []uint32{44, 1, 0, 0, 0}
-	case "mainnet":
-		derivationPath = []uint32{44, 60, 0, 0, 0}
+	return wallet.ethereumAccount(password)
+}
+
+func (wallet *wallet) ethereumAccount(password string) (*nonceManagedAccount, error) {
+	derivation, err := wallet.derivation(blockchain.Ethereum, nil)
+	if err != nil {
+		return nil, err
 	}
-	privKey, err := wallet.loadECDSAKey(password, derivationPath)
+	privKey, err := wallet.loadECDSAKey(password, append(derivation.addressPath(), 0))
 	if err != nil {
 		return nil, err
 	}
@@ -28,19 +34,44 @@ func (wallet *wallet) EthereumAccount(password string) (beth.Account, error) {
 	if err != nil {
 		return nil, err
 	}
-	return ethAccount, nil
+	return wallet.nonces.account(ethAccount), nil
+}
+
+// BitcoinAccount returns the bitcoin account. It uses the JSON-RPC API of a
+// bitcoind or btcd node when the url of the network is set, and blockchain.info
+// otherwise.
+func (wallet *wallet) BitcoinAccount(password string) (btc.Account, error) {
+	if wallet.config.Bitcoin.Network.URL != "" {
+		return wallet.utxoAccount(password, blockchain.Bitcoin, 0)
+	}
+
+	return wallet.libbtcAccount(password, 0)
+}
+
+// bitcoinAccountAt returns the libbtc account of the main address, which
+// signs the swap scripts with the key of the address index.
+func (wallet *wallet) bitcoinAccountAt(password string, index uint32) (btc.Account, error) {
+	account, err := wallet.libbtcAccount(password, 0)
+	if err != nil || index == 0 {
+		return account, err
+	}
+	swapAccount, err := wallet.libbtcAccount(password, index)
+	if err != nil {
+		return nil, err
+	}
+	return btc.NewSwapAccount(account, swapAccount), nil
 }
 
-// BitcoinAccount returns the bitcoin account
-func (wallet *wallet) BitcoinAccount(password string) (libbtc.Account, error) {
-	var derivationPath []uint32
-	switch wallet.config.Bitcoin.Network.Name {
-	case "testnet", "testnet3":
-		derivationPath = []uint32{44, 1, 0, 0, 0}
-	case "mainnet":
-		derivationPath = []uint32{44, 0, 0, 0, 0}
+func (wallet *wallet) libbtcAccount(password string, index uint32) (btc.Account, error) {
+	params, err := bitcoinParams(wallet.config.Bitcoin.Network.Name)
+	if err != nil {
+		return nil, err
 	}
-	privKey, err := wallet.loadECDSAKey(password, derivationPath)
+	derivation, err := wallet.derivation(blockchain.Bitcoin, params)
+	if err != nil {
+		return nil, err
+	}
+	privKey, err := wallet.loadECDSAKey(password, append(derivation.addressPath(), index))
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +79,92 @@ func (wallet *wallet) BitcoinAccount(password string) (libbtc.Account, error) {
 }
 
 func (wallet *wallet) loadECDSAKey(password string, path []uint32) (*ecdsa.PrivateKey, error) {
-	seed := bip39.NewSeed(wallet.config.Mnemonic, password)
+	key, err := wallet.loadExtendedKey(password, path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(key.Key)
+}
+
+// loadECDSAKeys returns the children of the key at the path, at each of the
+// indices. The seed and the parent key are only derived once.
+func (wallet *wallet) loadECDSAKeys(password string, path []uint32, indices []uint32) ([]*ecdsa.PrivateKey, error) {
+	parent, err := wallet.loadExtendedKey(password, path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*ecdsa.PrivateKey, len(indices))
+	for i, index := range indices {
+		key, err := parent.NewChildKey(index)
+		if err != nil {
+			return nil, err
+		}
+		if keys[i], err = crypto.ToECDSA(key.Key); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// loadPublicKeys returns the public keys of the external chain of the
+// derivation, at each of the indices. Watch-only wallets derive them from the
+// extended public key of the blockchain, so they are the same for every
+// password.
+func (wallet *wallet) loadPublicKeys(password string, blockchainName blockchain.BlockchainName, derivation derivation, indices []uint32) ([]*ecdsa.PublicKey, error) {
+	pubKeys := make([]*ecdsa.PublicKey, len(indices))
+	if !wallet.watchOnly {
+		keys, err := wallet.loadECDSAKeys(password, derivation.addressPath(), indices)
+		if err != nil {
+			return nil, err
+		}
+		for i, key := range keys {
+			pubKeys[i] = &key.PublicKey
+		}
+		return pubKeys, nil
+	}
+
+	parent, err := wallet.loadExtendedPublicKey(blockchainName)
+	if err != nil {
+		return nil, err
+	}
+	for i, index := range indices {
+		key, err := parent.NewChildKey(index)
+		if err != nil {
+			return nil, err
+		}
+		if pubKeys[i], err = crypto.DecompressPubkey(key.Key); err != nil {
+			return nil, err
+		}
+	}
+	return pubKeys, nil
+}
+
+// loadExtendedPublicKey returns the parent of the address keys of the
+// blockchain, the external chain of its extended public key.
+func (wallet *wallet) loadExtendedPublicKey(blockchainName blockchain.BlockchainName) (*bip32.Key, error) {
+	config, err := wallet.blockchainConfig(blockchainName)
+	if err != nil {
+		return nil, err
+	}
+	if config.XPub == "" {
+		return nil, fmt.Errorf("watch-only wallet has no extended public key of %s", blockchainName)
+	}
+	key, err := bip32.B58Deserialize(config.XPub)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extended public key of %s: %v", blockchainName, err)
+	}
+	if key.IsPrivate {
+		return nil, fmt.Errorf("extended key of %s must be public in a watch-only wallet", blockchainName)
+	}
+	return key.NewChildKey(0)
+}
+
+func (wallet *wallet) loadExtendedKey(password string, path []uint32) (*bip32.Key, error) {
+	mnemonic, err := wallet.loadMnemonic()
+	if err != nil {
+		return nil, err
+	}
+	seed := bip39.NewSeed(mnemonic, password)
 	key, err := bip32.NewMasterKey(seed)
 	if err != nil {
 		return nil, err
@@ -59,7 +175,7 @@ func (wallet *wallet) loadECDSAKey(password string, path []uint32) (*ecdsa.Priva
 			return nil, err
 		}
 	}
-	return crypto.ToECDSA(key.Key)
+	return key, nil
 }
 
 func (wallet *wallet) ECDSASigner(password string) (ECDSASigner, error) {
@@ -71,6 +187,10 @@ func (wallet *wallet) ECDSASigner(password string) (ECDSASigner, error) {
 }
 
 func (wallet *wallet) loadRSAKey(password string) (*rsa.PrivateKey, error) {
-	seed := bip39.NewSeed(wallet.config.Mnemonic, password)
+	mnemonic, err := wallet.loadMnemonic()
+	if err != nil {
+		return nil, err
+	}
+	seed := bip39.NewSeed(mnemonic, password)
 	return rsa.GenerateKey(bytes.NewReader(seed), 2048)
 }
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
 )
 
@@ -22,35 +25,72 @@ func (wallet *wallet) Addresses(password string) (map[blockchain.TokenName]strin
 	return addresses, nil
 }
 
+// GetAddress returns the main address of the wallet, which receives transfers
+// and holds the balance.
 func (wallet *wallet) GetAddress(password string, blockchainName blockchain.BlockchainName) (string, error) {
+	switch blockchainName {
+	case blockchain.Bitcoin:
+		if wallet.config.Bitcoin.Network.URL != "" {
+			return wallet.getUTXOAddress(password, blockchainName, 0)
+		}
+	case blockchain.Litecoin, blockchain.BitcoinCash:
+		return wallet.getUTXOAddress(password, blockchainName, 0)
+	}
+	return wallet.GetAddressAt(password, blockchainName, 0)
+}
+
+// GetAddressAt returns the address used in swaps at the address index. Only
+// UTXO blockchains have an address per index, so Ethereum always returns the
+// main address.
+func (wallet *wallet) GetAddressAt(password string, blockchainName blockchain.BlockchainName, index uint32) (string, error) {
 	switch blockchainName {
 	case blockchain.Ethereum:
 		return wallet.getEthereumAddress(password)
 	case blockchain.Bitcoin:
-		return wallet.getBitcoinAddress(password)
+		if wallet.config.Bitcoin.Network.URL != "" {
+			return wallet.getUTXOSwapAddress(password, blockchainName, index)
+		}
+		return wallet.getBitcoinAddress(password, index)
+	case blockchain.Litecoin, blockchain.BitcoinCash:
+		return wallet.getUTXOSwapAddress(password, blockchainName, index)
 	default:
 		return "", blockchain.NewErrUnsupportedToken("unsupported blockchain")
 	}
 }
 
 func (wallet *wallet) getEthereumAddress(password string) (string, error) {
-	ethAccount, err := wallet.EthereumAccount(password)
+	derivation, err := wallet.derivation(blockchain.Ethereum, nil)
+	if err != nil {
+		return "", err
+	}
+	pubKeys, err := wallet.loadPublicKeys(password, blockchain.Ethereum, derivation, []uint32{0})
 	if err != nil {
 		return "", err
 	}
-	return ethAccount.Address().String(), nil
+	return crypto.PubkeyToAddress(*pubKeys[0]).String(), nil
 }
 
-func (wallet *wallet) getBitcoinAddress(password string) (string, error) {
-	btcAccount, err := wallet.BitcoinAccount(password)
+// getBitcoinAddress returns the libbtc address of the index, which is the
+// P2PKH address of its key. Watch-only wallets derive it from their extended
+// public key.
+func (wallet *wallet) getBitcoinAddress(password string, index uint32) (string, error) {
+	params, err := bitcoinParams(wallet.config.Bitcoin.Network.Name)
 	if err != nil {
 		return "", err
 	}
-	btcAddr, err := btcAccount.Address()
+	derivation, err := wallet.derivation(blockchain.Bitcoin, params)
 	if err != nil {
 		return "", err
 	}
-	return btcAddr.String(), nil
+	pubKeys, err := wallet.loadPublicKeys(password, blockchain.Bitcoin, derivation, []uint32{index})
+	if err != nil {
+		return "", err
+	}
+	address, err := btc.PubKeyAddress(btc.AddressTypeP2PKH, (*btcec.PublicKey)(pubKeys[0]), params)
+	if err != nil {
+		return "", err
+	}
+	return address.String(), nil
 }
 
 func (wallet *wallet) VerifyAddress(blockchainName blockchain.BlockchainName, address string) error {
@@ -59,6 +99,8 @@ func (wallet *wallet) VerifyAddress(blockchainName blockchain.BlockchainName, ad
 		return wallet.verifyEthereumAddress(address)
 	case blockchain.Bitcoin:
 		return wallet.verifyBitcoinAddress(address)
+	case blockchain.Litecoin, blockchain.BitcoinCash:
+		return wallet.verifyUTXOAddress(blockchainName, address)
 	default:
 		return blockchain.NewErrUnsupportedToken("unsupported blockchain")
 	}
@@ -97,6 +139,11 @@ func (wallet *wallet) verifyBitcoinAddress(address string) error {
 		if _, err := btcutil.DecodeAddress(address, &chaincfg.TestNet3Params); err != nil {
 			return fmt.Errorf("Invalid %s bitcoin address: %s", network, address)
 		}
+
+	case "regtest":
+		if _, err := btcutil.DecodeAddress(address, &chaincfg.RegressionNetParams); err != nil {
+			return fmt.Errorf("Invalid %s bitcoin address: %s", network, address)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,243 @@
+package wallet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// DefaultWhitelistDelay is the time after which new address book entries can
+// receive transfers, when the config does not set one.
+const DefaultWhitelistDelay = 24 * time.Hour
+
+// AddressBookConfig configures the address book of the wallet. When
+// WhitelistOnly is set, transfers can only be sent to the addresses in the
+// address book, once they are active. New addresses become active after the
+// Delay, such as "48h", so that a leaked password cannot be used to drain the
+// wallet straight away.
+type AddressBookConfig struct {
+	WhitelistOnly bool   `json:"whitelistOnly,omitempty"`
+	Delay         string `json:"delay,omitempty"`
+}
+
+// An AddressBookEntry is a labelled address of a token, in the address book of
+// an account. It can receive transfers from ActiveAt, a unix timestamp.
+type AddressBookEntry struct {
+	Account  string               `json:"account,omitempty"`
+	Token    blockchain.TokenName `json:"token"`
+	Address  string               `json:"address"`
+	Label    string               `json:"label"`
+	AddedAt  int64                `json:"addedAt"`
+	ActiveAt int64                `json:"activeAt"`
+}
+
+// Active returns true if the entry can receive transfers.
+func (entry AddressBookEntry) Active() bool {
+	return time.Now().Unix() >= entry.ActiveAt
+}
+
+// AddressBookStorage persists the address books of the accounts of the wallet.
+// Entries are identified by their account, token and address.
+type AddressBookStorage interface {
+	AddressBook() ([]AddressBookEntry, error)
+	PutAddressBookEntry(entry AddressBookEntry) error
+	DeleteAddressBookEntry(account string, token blockchain.TokenName, address string) error
+}
+
+// ErrNoAccount is returned when the address book is used without the id of
+// an account.
+var ErrNoAccount = fmt.Errorf("address book requires the id of an account")
+
+// NewErrNotWhitelisted returns an error for a transfer to an address that is
+// not an active entry of the address book.
+func NewErrNotWhitelisted(token blockchain.TokenName, address string) error {
+	return fmt.Errorf("cannot transfer %s to %s, only active addresses in the address book can receive transfers", token, address)
+}
+
+// NewErrWhitelistPending returns an error for a transfer to an address that is
+// in the address book, but is not active yet.
+func NewErrWhitelistPending(token blockchain.TokenName, address string, activeAt int64) error {
+	return fmt.Errorf("cannot transfer %s to %s before it becomes active at %s", token, address, time.Unix(activeAt, 0).UTC().Format(time.RFC3339))
+}
+
+// AddressBook returns the entries of the address book of the account, sorted
+// by token and label.
+func (wallet *wallet) AddressBook(account string) ([]AddressBookEntry, error) {
+	entries, err := wallet.accountAddressBook(account)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Token != entries[j].Token {
+			return entries[i].Token < entries[j].Token
+		}
+		return entries[i].Label < entries[j].Label
+	})
+	return entries, nil
+}
+
+// AddAddressBookEntry adds the address of the token to the address book of the
+// account, or relabels it if it is already there. New addresses only become
+// active after the delay in the config, and relabelling does not change when
+// an address becomes active.
+func (wallet *wallet) AddAddressBookEntry(account string, token blockchain.Token, address, label string) (AddressBookEntry, error) {
+	if account == "" {
+		return AddressBookEntry{}, ErrNoAccount
+	}
+	if err := wallet.VerifyAddress(token.Blockchain, address); err != nil {
+		return AddressBookEntry{}, err
+	}
+	delay, err := wallet.whitelistDelay()
+	if err != nil {
+		return AddressBookEntry{}, err
+	}
+
+	wallet.addressBookMu.Lock()
+	defer wallet.addressBookMu.Unlock()
+
+	if entry, ok, err := wallet.addressBookEntry(account, token, address); err != nil || ok {
+		if err != nil {
+			return AddressBookEntry{}, err
+		}
+		entry.Label = label
+		return entry, wallet.addressBook.PutAddressBookEntry(entry)
+	}
+
+	now := time.Now()
+	entry := AddressBookEntry{
+		Account:  account,
+		Token:    token.Name,
+		Address:  normalizeAddress(token.Blockchain, address),
+		Label:    label,
+		AddedAt:  now.Unix(),
+		ActiveAt: now.Add(delay).Unix(),
+	}
+	return entry, wallet.addressBook.PutAddressBookEntry(entry)
+}
+
+// DeleteAddressBookEntry deletes the address of the token from the address
+// book of the account. Deleting an address that is not in the address book
+// does nothing.
+func (wallet *wallet) DeleteAddressBookEntry(account string, token blockchain.Token, address string) error {
+	if account == "" {
+		return ErrNoAccount
+	}
+	wallet.addressBookMu.Lock()
+	defer wallet.addressBookMu.Unlock()
+	return wallet.addressBook.DeleteAddressBookEntry(account, token.Name, normalizeAddress(token.Blockchain, address))
+}
+
+// VerifyWhitelisted checks that the address can receive transfers of the
+// token from the account. Any address can receive transfers unless the wallet
+// is whitelist only, and then only the active entries of the address book of
+// the account can.
+func (wallet *wallet) VerifyWhitelisted(account string, token blockchain.Token, address string) error {
+	if !wallet.config.AddressBook.WhitelistOnly {
+		return nil
+	}
+	entry, ok, err := wallet.addressBookEntry(account, token, address)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewErrNotWhitelisted(token.Name, address)
+	}
+	if !entry.Active() {
+		return NewErrWhitelistPending(token.Name, address, entry.ActiveAt)
+	}
+	return nil
+}
+
+func (wallet *wallet) addressBookEntry(account string, token blockchain.Token, address string) (AddressBookEntry, bool, error) {
+	entries, err := wallet.accountAddressBook(account)
+	if err != nil {
+		return AddressBookEntry{}, false, err
+	}
+	address = normalizeAddress(token.Blockchain, address)
+	for _, entry := range entries {
+		if entry.Token == token.Name && entry.Address == address {
+			return entry, true, nil
+		}
+	}
+	return AddressBookEntry{}, false, nil
+}
+
+// accountAddressBook returns the entries of the address book of the account.
+// Entries without an account are not in any address book.
+func (wallet *wallet) accountAddressBook(account string) ([]AddressBookEntry, error) {
+	if account == "" {
+		return nil, ErrNoAccount
+	}
+	entries, err := wallet.addressBook.AddressBook()
+	if err != nil {
+		return nil, err
+	}
+	accountEntries := []AddressBookEntry{}
+	for _, entry := range entries {
+		if entry.Account == account {
+			accountEntries = append(accountEntries, entry)
+		}
+	}
+	return accountEntries, nil
+}
+
+func (wallet *wallet) whitelistDelay() (time.Duration, error) {
+	if wallet.config.AddressBook.Delay == "" {
+		return DefaultWhitelistDelay, nil
+	}
+	delay, err := time.ParseDuration(wallet.config.AddressBook.Delay)
+	if err != nil || delay < 0 {
+		return 0, fmt.Errorf("invalid address book delay: %s", wallet.config.AddressBook.Delay)
+	}
+	return delay, nil
+}
+
+// normalizeAddress lower cases Ethereum addresses, whose checksums are only
+// in the case of their letters.
+func normalizeAddress(blockchainName blockchain.BlockchainName, address string) string {
+	if blockchainName == blockchain.Ethereum {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// memoryAddressBook keeps the address book of a wallet without storage.
+type memoryAddressBook struct {
+	mu      *sync.Mutex
+	entries map[string]AddressBookEntry
+}
+
+func newMemoryAddressBook() AddressBookStorage {
+	return &memoryAddressBook{
+		mu:      new(sync.Mutex),
+		entries: map[string]AddressBookEntry{},
+	}
+}
+
+func (memory *memoryAddressBook) AddressBook() ([]AddressBookEntry, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	entries := make([]AddressBookEntry, 0, len(memory.entries))
+	for _, entry := range memory.entries {
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (memory *memoryAddressBook) PutAddressBookEntry(entry AddressBookEntry) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	memory.entries[entry.Account+"/"+string(entry.Token)+"/"+entry.Address] = entry
+	return nil
+}
+
+func (memory *memoryAddressBook) DeleteAddressBookEntry(account string, token blockchain.TokenName, address string) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	delete(memory.entries, account+"/"+string(token)+"/"+address)
+	return nil
+}
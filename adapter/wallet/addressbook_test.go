@@ -0,0 +1,98 @@
+package wallet_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/adapter/wallet"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+var _ = Describe("Address book", func() {
+	const address = "0x5E4b2AE1Bd4D6D5C0a3fB0C7cD53A1c8a9DfC5aB"
+	const account, otherAccount = "account", "other"
+
+	newWallet := func(whitelistOnly bool, delay string) Wallet {
+		return New(Config{
+			Ethereum:    BlockchainConfig{Network: Network{Name: "kovan"}},
+			AddressBook: AddressBookConfig{WhitelistOnly: whitelistOnly, Delay: delay},
+		}, nil)
+	}
+
+	Context("when the wallet is whitelist only", func() {
+		It("should not transfer to addresses that are not in the address book", func() {
+			w := newWallet(true, "")
+			Expect(w.VerifyWhitelisted(account, blockchain.TokenETH, address)).Should(HaveOccurred())
+		})
+
+		It("should only transfer to a new address once it is active", func() {
+			w := newWallet(true, "1h")
+			entry, err := w.AddAddressBookEntry(account, blockchain.TokenETH, address, "exchange")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(entry.Active()).Should(BeFalse())
+			Expect(entry.ActiveAt - entry.AddedAt).Should(Equal(int64(time.Hour / time.Second)))
+			Expect(w.VerifyWhitelisted(account, blockchain.TokenETH, address)).Should(HaveOccurred())
+		})
+
+		It("should transfer to an active address, whatever the case of its letters", func() {
+			w := newWallet(true, "0s")
+			_, err := w.AddAddressBookEntry(account, blockchain.TokenETH, address, "exchange")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(w.VerifyWhitelisted(account, blockchain.TokenETH, address)).Should(Succeed())
+			Expect(w.VerifyWhitelisted(account, blockchain.TokenETH, "0x5e4b2ae1bd4d6d5c0a3fb0c7cd53a1c8a9dfc5ab")).Should(Succeed())
+			Expect(w.VerifyWhitelisted(account, blockchain.TokenWBTC, address)).Should(HaveOccurred())
+		})
+
+		It("should not change when an address becomes active when it is relabelled", func() {
+			w := newWallet(true, "1h")
+			entry, err := w.AddAddressBookEntry(account, blockchain.TokenETH, address, "exchange")
+			Expect(err).ShouldNot(HaveOccurred())
+			relabelled, err := w.AddAddressBookEntry(account, blockchain.TokenETH, address, "cold storage")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(relabelled.Label).Should(Equal("cold storage"))
+			Expect(relabelled.ActiveAt).Should(Equal(entry.ActiveAt))
+		})
+
+		It("should not transfer to a deleted address", func() {
+			w := newWallet(true, "0s")
+			_, err := w.AddAddressBookEntry(account, blockchain.TokenETH, address, "exchange")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(w.DeleteAddressBookEntry(account, blockchain.TokenETH, address)).Should(Succeed())
+			Expect(w.VerifyWhitelisted(account, blockchain.TokenETH, address)).Should(HaveOccurred())
+		})
+	})
+
+	Context("when the wallet has many accounts", func() {
+		It("should only use the address book of the account", func() {
+			w := newWallet(true, "0s")
+			_, err := w.AddAddressBookEntry(account, blockchain.TokenETH, address, "exchange")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(w.VerifyWhitelisted(otherAccount, blockchain.TokenETH, address)).Should(HaveOccurred())
+			entries, err := w.AddressBook(otherAccount)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(entries).Should(BeEmpty())
+			entries, err = w.AddressBook(account)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(entries).Should(HaveLen(1))
+
+			Expect(w.DeleteAddressBookEntry(otherAccount, blockchain.TokenETH, address)).Should(Succeed())
+			Expect(w.VerifyWhitelisted(account, blockchain.TokenETH, address)).Should(Succeed())
+		})
+
+		It("should not use an address book without an account", func() {
+			w := newWallet(true, "0s")
+			_, err := w.AddAddressBookEntry("", blockchain.TokenETH, address, "exchange")
+			Expect(err).Should(Equal(ErrNoAccount))
+			Expect(w.VerifyWhitelisted("", blockchain.TokenETH, address)).Should(Equal(ErrNoAccount))
+		})
+	})
+
+	Context("when the wallet is not whitelist only", func() {
+		It("should transfer to any address", func() {
+			w := newWallet(false, "")
+			Expect(w.VerifyWhitelisted(account, blockchain.TokenETH, address)).Should(Succeed())
+		})
+	})
+})
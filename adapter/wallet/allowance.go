@@ -0,0 +1,124 @@
+package wallet
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/republicprotocol/beth-go"
+	"github.com/republicprotocol/swapperd/adapter/binder/erc20"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// StandingAllowance returns the opt-in standing allowance configured for the
+// token, or nil if the swapper contract should only be approved per swap.
+func (wallet *wallet) StandingAllowance(token blockchain.Token) *big.Int {
+	allowance, ok := wallet.config.Ethereum.Allowances[string(token.Name)]
+	if !ok {
+		return nil
+	}
+	value, ok := new(big.Int).SetString(allowance, 10)
+	if !ok {
+		return nil
+	}
+	return value
+}
+
+// Allowances returns the allowances granted to the Swapperd contracts of all
+// the supported ERC20 tokens.
+func (wallet *wallet) Allowances(password string) (map[blockchain.TokenName]string, error) {
+	account, err := wallet.EthereumAccount(password)
+	if err != nil {
+		return nil, err
+	}
+
+	allowances := map[blockchain.TokenName]string{}
+	for _, token := range wallet.SupportedTokens() {
+		config, err := wallet.tokens.Config(token.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !config.IsERC20() {
+			continue
+		}
+		tokenContract, _, swapperAddress, err := wallet.erc20Contracts(account, token)
+		if err != nil {
+			return nil, err
+		}
+		allowance, err := tokenContract.Allowance(&bind.CallOpts{}, account.Address(), swapperAddress)
+		if err != nil {
+			return nil, err
+		}
+		allowances[token.Name] = allowance.String()
+	}
+	return allowances, nil
+}
+
+// RevokeAllowance sets the allowance granted to the Swapperd contract of the
+// token back to zero, and returns the transaction hash.
+func (wallet *wallet) RevokeAllowance(password string, token blockchain.Token) (string, error) {
+	config, err := wallet.tokens.Config(token.Name)
+	if err != nil {
+		return "", err
+	}
+	if !config.IsERC20() {
+		return "", blockchain.NewErrUnsupportedToken(token.Name)
+	}
+
+	var txHash string
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	account, err := wallet.EthereumAccount(password)
+	if err != nil {
+		return txHash, err
+	}
+	tokenContract, tokenAddress, swapperAddress, err := wallet.erc20Contracts(account, token)
+	if err != nil {
+		return txHash, err
+	}
+	oracle, err := wallet.GasPriceOracle()
+	if err != nil {
+		return txHash, err
+	}
+
+	if err := account.Transact(
+		ctx,
+		nil,
+		func(tops *bind.TransactOpts) (*types.Transaction, error) {
+			if _, err := eth.SetFees(ctx, oracle, account.EthClient(), tops, tokenAddress, erc20.CompatibleERC20ABI, "approve", swapperAddress, big.NewInt(0)); err != nil {
+				return nil, err
+			}
+			tx, err := tokenContract.Approve(tops, swapperAddress, big.NewInt(0))
+			if err != nil {
+				return tx, err
+			}
+			txHash = tx.Hash().String()
+			return tx, nil
+		},
+		nil,
+		1,
+	); err != nil {
+		return txHash, err
+	}
+	return txHash, nil
+}
+
+func (wallet *wallet) erc20Contracts(account beth.Account, token blockchain.Token) (*erc20.CompatibleERC20, common.Address, common.Address, error) {
+	tokenAddress, err := wallet.tokenAddress(account, token)
+	if err != nil {
+		return nil, common.Address{}, common.Address{}, err
+	}
+	swapperAddress, err := wallet.swapperAddress(account, token)
+	if err != nil {
+		return nil, common.Address{}, common.Address{}, err
+	}
+	tokenContract, err := erc20.NewCompatibleERC20(tokenAddress, bind.ContractBackend(account.EthClient()))
+	if err != nil {
+		return nil, common.Address{}, common.Address{}, err
+	}
+	return tokenContract, tokenAddress, swapperAddress, nil
+}
@@ -9,32 +9,32 @@ import (
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
 )
 
+// VerifyBalance checks that the balance of the token, less the reserved
+// amounts, covers the amount and leaves enough to pay the fees.
 func (wallet *wallet) VerifyBalance(password string, token blockchain.Token, amount *big.Int) error {
-	switch token.Name {
-	case blockchain.ETH:
+	config, err := wallet.tokens.Config(token.Name)
+	if err != nil {
+		return err
+	}
+	switch config.Blockchain {
+	case blockchain.Bitcoin, blockchain.Litecoin, blockchain.BitcoinCash:
+		return wallet.verifyUTXOBalance(password, token, amount)
+	case blockchain.Ethereum:
+		if config.IsERC20() {
+			return wallet.verifyERC20Balance(password, token, amount)
+		}
 		return wallet.verifyEthereumBalance(password, amount)
-	case blockchain.REN, blockchain.DGX, blockchain.TUSD, blockchain.OMG,
-		blockchain.ZRX, blockchain.WBTC, blockchain.GUSD,
-		blockchain.DAI, blockchain.USDC:
-		return wallet.verifyERC20Balance(password, token, amount)
-	case blockchain.BTC:
-		return wallet.verifyBitcoinBalance(password, amount)
 	default:
-		return blockchain.NewErrUnsupportedToken("unsupported blockchain")
+		return blockchain.NewErrUnsupportedToken(token.Name)
 	}
 }
 
 func (wallet *wallet) verifyEthereumBalance(password string, amount *big.Int) error {
-	balance, err := wallet.balance(password, blockchain.TokenETH)
+	balanceAmount, err := wallet.availableBalance(password, blockchain.TokenETH)
 	if err != nil {
 		return err
 	}
 
-	balanceAmount, ok := big.NewInt(0).SetString(balance.Amount, 10)
-	if !ok {
-		return fmt.Errorf("Invalid balance amount: %s", balance.Amount)
-	}
-
 	if amount != nil {
 		balanceAmount = new(big.Int).Sub(balanceAmount, amount)
 	}
@@ -50,27 +50,17 @@ func (wallet *wallet) verifyEthereumBalance(password string, amount *big.Int) er
 }
 
 func (wallet *wallet) verifyERC20Balance(password string, token blockchain.Token, amount *big.Int) error {
-	ethBalance, err := wallet.balance(password, blockchain.TokenETH)
+	ethAmount, err := wallet.availableBalance(password, blockchain.TokenETH)
 	if err != nil {
 		return err
 	}
 
-	ethAmount, ok := big.NewInt(0).SetString(ethBalance.Amount, 10)
-	if !ok {
-		return fmt.Errorf("Invalid balance amount: %s", ethBalance.Amount)
-	}
-
 	if amount != nil {
-		erc20Balance, err := wallet.balance(password, token)
+		erc20Amount, err := wallet.availableBalance(password, token)
 		if err != nil {
 			return err
 		}
 
-		erc20Amount, ok := big.NewInt(0).SetString(erc20Balance.Amount, 10)
-		if !ok {
-			return fmt.Errorf("Invalid balance amount: %s", erc20Balance.Amount)
-		}
-
 		if erc20Amount.Cmp(amount) < 0 {
 			return fmt.Errorf("You must have at least %s %s remaining in your wallet to execute the swap. You have %s %s", amount, token.Name, erc20Amount, token.Name)
 		}
@@ -88,24 +78,19 @@ func (wallet *wallet) verifyERC20Balance(password string, token blockchain.Token
 	return nil
 }
 
-func (wallet *wallet) verifyBitcoinBalance(password string, amount *big.Int) error {
+func (wallet *wallet) verifyUTXOBalance(password string, token blockchain.Token, amount *big.Int) error {
 	if amount == nil {
 		return nil
 	}
 
-	balance, err := wallet.balance(password, blockchain.TokenBTC)
+	balanceAmount, err := wallet.availableBalance(password, token)
 	if err != nil {
 		return err
 	}
 
-	balanceAmount, ok := big.NewInt(0).SetString(balance.Amount, 10)
-	if !ok {
-		return fmt.Errorf("Invalid balance amount: %s", balance.Amount)
-	}
-
-	leftover := balanceAmount.Sub(balanceAmount, amount)
+	leftover := new(big.Int).Sub(balanceAmount, amount)
 	if leftover.Cmp(big.NewInt(10000)) < 0 {
-		return fmt.Errorf("You need at least 10000 SAT (or 0.0001 BTC) remaining in your wallet to cover transaction fees. You have: %v", balanceAmount)
+		return fmt.Errorf("You need at least 10000 SAT (or 0.0001 %s) remaining in your wallet to cover transaction fees. You have: %v", token.Name, balanceAmount)
 	}
 	return nil
 }
@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -9,52 +10,207 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/republicprotocol/beth-go"
+	"github.com/republicprotocol/co-go"
 	"github.com/republicprotocol/libbtc-go"
 	"github.com/republicprotocol/swapperd/adapter/binder/erc20"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
 )
 
+// BalanceTimeout is the time allowed to fetch the balance of each token.
+const BalanceTimeout = 20 * time.Second
+
+// BalanceCacheTTL is the time for which a fetched balance is reused.
+const BalanceCacheTTL = 10 * time.Second
+
+// Balances returns the balance of every supported token, with the amounts that
+// are reserved and available. The balances are fetched concurrently, and the
+// tokens whose balance cannot be fetched have an error instead. An error is
+// returned when the reservations cannot be read, or when no balance can be
+// fetched at all.
 func (wallet *wallet) Balances(password string) (map[blockchain.TokenName]blockchain.Balance, error) {
-	balanceMap := map[blockchain.TokenName]blockchain.Balance{}
-	for _, token := range wallet.SupportedTokens() {
-		balance, err := wallet.balance(password, token)
+	if _, err := wallet.reservations.Reservations(); err != nil {
+		return nil, fmt.Errorf("failed to read the reservations: %v", err)
+	}
+
+	tokens := wallet.SupportedTokens()
+	balances := make([]blockchain.Balance, len(tokens))
+	errs := make([]error, len(tokens))
+	co.ParForAll(tokens, func(i int) {
+		balance, err := wallet.balance(password, tokens[i])
+		if err == nil {
+			balance, err = wallet.withReserved(tokens[i], balance)
+		}
 		if err != nil {
-			return balanceMap, err
+			balance = blockchain.Balance{Address: balance.Address, Error: err.Error()}
 		}
-		balanceMap[token.Name] = balance
+		balances[i], errs[i] = balance, err
+	})
+
+	balanceMap := map[blockchain.TokenName]blockchain.Balance{}
+	var fetchErr error
+	fetched := false
+	for i, token := range tokens {
+		balanceMap[token.Name] = balances[i]
+		if errs[i] == nil {
+			fetched = true
+		} else if fetchErr == nil {
+			fetchErr = fmt.Errorf("failed to fetch the %s balance: %v", token.Name, errs[i])
+		}
+	}
+	if !fetched && fetchErr != nil {
+		return balanceMap, fetchErr
 	}
 	return balanceMap, nil
 }
 
+// A cachedBalance is a fetched balance, with the reservations of its token at
+// the time it was fetched.
+type cachedBalance struct {
+	balance      blockchain.Balance
+	reservations map[string]bool
+	expiry       time.Time
+}
+
+// valid returns true until the cached balance expires, or the reservations of
+// its token change. Reservations are released once their funds have left the
+// wallet, and a reservation can be made and released between two reads, so
+// any change means that the balance may have changed since.
+func (cached cachedBalance) valid(reservations map[string]bool) bool {
+	if time.Now().After(cached.expiry) {
+		return false
+	}
+	if len(cached.reservations) != len(reservations) {
+		return false
+	}
+	for id := range cached.reservations {
+		if !reservations[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// balance returns the balance of the token, from the cache when it is still
+// valid.
 func (wallet *wallet) balance(password string, token blockchain.Token) (blockchain.Balance, error) {
+	return wallet.readBalance(password, token, true)
+}
+
+// uncachedBalance fetches the balance of the token, and caches it. It is used
+// to check the balance before funds are committed, since the cache cannot see
+// the reservations that were made and released since it was filled.
+func (wallet *wallet) uncachedBalance(password string, token blockchain.Token) (blockchain.Balance, error) {
+	return wallet.readBalance(password, token, false)
+}
+
+func (wallet *wallet) readBalance(password string, token blockchain.Token, useCache bool) (blockchain.Balance, error) {
 	address, err := wallet.GetAddress(password, token.Blockchain)
 	if err != nil {
 		return blockchain.Balance{}, err
 	}
+	reservations, err := wallet.reservationIDs(token)
+	if err != nil {
+		return blockchain.Balance{Address: address}, err
+	}
+
+	key := string(token.Name) + "/" + address
+	if useCache {
+		wallet.balancesMu.Lock()
+		cached, ok := wallet.balances[key]
+		wallet.balancesMu.Unlock()
+		if ok && cached.valid(reservations) {
+			return cached.balance, nil
+		}
+	}
 
-	switch token.Name {
-	case blockchain.BTC:
-		return wallet.balanceBTC(address)
-	case blockchain.ETH:
-		return wallet.balanceETH(address)
-	case blockchain.REN, blockchain.DGX, blockchain.TUSD, blockchain.OMG,
-		blockchain.ZRX, blockchain.WBTC, blockchain.GUSD,
-		blockchain.DAI, blockchain.USDC:
-		return wallet.balanceERC20(token.Name, address)
+	balance, err := wallet.fetchBalance(password, token, address)
+	if err != nil {
+		return blockchain.Balance{Address: address}, err
+	}
+	wallet.balancesMu.Lock()
+	wallet.balances[key] = cachedBalance{balance, reservations, time.Now().Add(BalanceCacheTTL)}
+	wallet.balancesMu.Unlock()
+	return balance, nil
+}
+
+// fetchBalance fetches the balance of the token from its blockchain, giving up
+// after the balance timeout.
+func (wallet *wallet) fetchBalance(password string, token blockchain.Token, address string) (blockchain.Balance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), BalanceTimeout)
+	defer cancel()
+
+	balance, err := wallet.queryBalance(ctx, password, token, address)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return blockchain.Balance{}, fmt.Errorf("timed out fetching the %s balance of %s", token.Name, address)
+	}
+	return balance, err
+}
+
+// reservationIDs returns the ids of the reservations of the token.
+func (wallet *wallet) reservationIDs(token blockchain.Token) (map[string]bool, error) {
+	reservations, err := wallet.reservations.Reservations()
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]bool{}
+	for id, reservation := range reservations {
+		if reservation.Token == token.Name {
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
+
+// withReserved sets the reserved and available amounts of the balance. The
+// available amount is zero when the reservations exceed the balance, which
+// happens while funds are leaving the wallet.
+func (wallet *wallet) withReserved(token blockchain.Token, balance blockchain.Balance) (blockchain.Balance, error) {
+	amount, ok := new(big.Int).SetString(balance.Amount, 10)
+	if !ok {
+		return balance, fmt.Errorf("Invalid balance amount: %s", balance.Amount)
+	}
+	reserved, err := wallet.ReservedBalance(token)
+	if err != nil {
+		return balance, err
+	}
+	available := new(big.Int).Sub(amount, reserved)
+	if available.Sign() < 0 {
+		available = big.NewInt(0)
+	}
+	balance.Reserved = reserved.String()
+	balance.Available = available.String()
+	return balance, nil
+}
+
+func (wallet *wallet) queryBalance(ctx context.Context, password string, token blockchain.Token, address string) (blockchain.Balance, error) {
+	config, err := wallet.tokens.Config(token.Name)
+	if err != nil {
+		return blockchain.Balance{}, err
+	}
+	switch config.Blockchain {
+	case blockchain.Bitcoin:
+		if wallet.config.Bitcoin.Network.URL != "" {
+			return wallet.balanceUTXO(ctx, password, config.Blockchain, address)
+		}
+		return wallet.balanceBTC(ctx, address)
+	case blockchain.Litecoin, blockchain.BitcoinCash:
+		return wallet.balanceUTXO(ctx, password, config.Blockchain, address)
+	case blockchain.Ethereum:
+		if config.IsERC20() {
+			return wallet.balanceERC20(ctx, token, address)
+		}
+		return wallet.balanceETH(ctx, address)
 	default:
 		return blockchain.Balance{}, blockchain.NewErrUnsupportedToken(token.Name)
 	}
 }
 
-func (wallet *wallet) balanceBTC(address string) (blockchain.Balance, error) {
+func (wallet *wallet) balanceBTC(ctx context.Context, address string) (blockchain.Balance, error) {
 	randomKey, err := crypto.GenerateKey()
 	if err != nil {
 		return blockchain.Balance{}, err
 	}
 	btcAccount := libbtc.NewAccount(libbtc.NewBlockchainInfoClient(wallet.config.Bitcoin.Network.Name), randomKey)
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer cancel()
-
 	balance, err := btcAccount.Balance(ctx, address, 0)
 	if err != nil {
 		return blockchain.Balance{}, err
@@ -66,15 +222,12 @@ func (wallet *wallet) balanceBTC(address string) (blockchain.Balance, error) {
 	}, nil
 }
 
-func (wallet *wallet) balanceETH(address string) (blockchain.Balance, error) {
+func (wallet *wallet) balanceETH(ctx context.Context, address string) (blockchain.Balance, error) {
 	client, err := beth.Connect(wallet.config.Ethereum.Network.URL)
 	if err != nil {
 		return blockchain.Balance{}, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer cancel()
-
 	balance, err := client.BalanceOf(ctx, common.HexToAddress(address))
 	if err != nil {
 		return blockchain.Balance{}, err
@@ -86,12 +239,12 @@ func (wallet *wallet) balanceETH(address string) (blockchain.Balance, error) {
 	}, nil
 }
 
-func (wallet *wallet) balanceERC20(token blockchain.TokenName, address string) (blockchain.Balance, error) {
+func (wallet *wallet) balanceERC20(ctx context.Context, token blockchain.Token, address string) (blockchain.Balance, error) {
 	client, err := beth.Connect(wallet.config.Ethereum.Network.URL)
 	if err != nil {
 		return blockchain.Balance{}, err
 	}
-	tokenAddr, err := client.ReadAddress(string(token))
+	tokenAddr, err := wallet.tokenAddress(client, token)
 	if err != nil {
 		return blockchain.Balance{}, err
 	}
@@ -100,14 +253,11 @@ func (wallet *wallet) balanceERC20(token blockchain.TokenName, address string) (
 		return blockchain.Balance{}, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	var balance *big.Int
 	if err := client.Get(
 		ctx,
 		func() error {
-			balance, err = erc20Contract.BalanceOf(&bind.CallOpts{}, common.HexToAddress(address))
+			balance, err = erc20Contract.BalanceOf(&bind.CallOpts{Context: ctx}, common.HexToAddress(address))
 			return err
 		},
 	); err != nil {
@@ -0,0 +1,147 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/republicprotocol/beth-go"
+	"github.com/republicprotocol/swapperd/adapter/binder/erc20"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
+	"github.com/republicprotocol/swapperd/core/transfer"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// BrokerFee is the fee accrued by the broker in the Swapperd contract of a
+// token, in the smallest unit of the token. The error is set when the fee of
+// the token could not be read.
+type BrokerFee struct {
+	SwapperAddress string `json:"swapperAddress,omitempty"`
+	Amount         string `json:"amount,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// brokerContract is implemented by the ETH and ERC20 Swapperd contracts.
+type brokerContract interface {
+	BrokerFees(opts *bind.CallOpts, arg0 common.Address) (*big.Int, error)
+	WithdrawBrokerFees(opts *bind.TransactOpts, _amount *big.Int) (*types.Transaction, error)
+}
+
+// BrokerFees returns the fees accrued by the Ethereum address of the wallet in
+// the Swapperd contracts of all the supported Ethereum tokens. A token whose
+// fee cannot be read is listed with the error, instead of failing the others.
+func (wallet *wallet) BrokerFees(password string) (map[blockchain.TokenName]BrokerFee, error) {
+	account, err := wallet.EthereumAccount(password)
+	if err != nil {
+		return nil, err
+	}
+
+	fees := map[blockchain.TokenName]BrokerFee{}
+	for _, token := range wallet.SupportedTokens() {
+		if token.Blockchain != blockchain.Ethereum {
+			continue
+		}
+		fees[token.Name] = wallet.brokerFee(account, token)
+	}
+	return fees, nil
+}
+
+func (wallet *wallet) brokerFee(account beth.Account, token blockchain.Token) BrokerFee {
+	contract, swapperAddress, _, err := wallet.brokerContract(account, token)
+	if err != nil {
+		return BrokerFee{Error: err.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	fee, err := contract.BrokerFees(&bind.CallOpts{Context: ctx}, account.Address())
+	if err != nil {
+		return BrokerFee{SwapperAddress: swapperAddress.String(), Error: err.Error()}
+	}
+	return BrokerFee{
+		SwapperAddress: swapperAddress.String(),
+		Amount:         fee.String(),
+	}
+}
+
+// WithdrawBrokerFees withdraws the fees accrued in the Swapperd contract of the
+// token to the Ethereum address of the wallet, and returns the transaction
+// and the amount withdrawn. All the fees are withdrawn when the amount is nil.
+func (wallet *wallet) WithdrawBrokerFees(password string, token blockchain.Token, amount *big.Int) (transfer.Transaction, *big.Int, error) {
+	if token.Blockchain != blockchain.Ethereum {
+		return transfer.Transaction{}, nil, blockchain.NewErrUnsupportedToken(token.Name)
+	}
+
+	var signedTx *types.Transaction
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	account, err := wallet.EthereumAccount(password)
+	if err != nil {
+		return transfer.Transaction{}, nil, err
+	}
+	contract, swapperAddress, contractABI, err := wallet.brokerContract(account, token)
+	if err != nil {
+		return transfer.Transaction{}, nil, err
+	}
+
+	accrued, err := contract.BrokerFees(&bind.CallOpts{Context: ctx}, account.Address())
+	if err != nil {
+		return transfer.Transaction{}, nil, err
+	}
+	if amount == nil {
+		amount = accrued
+	}
+	if amount.Sign() <= 0 {
+		return transfer.Transaction{}, nil, fmt.Errorf("no %s broker fees to withdraw", token.Name)
+	}
+	if amount.Cmp(accrued) > 0 {
+		return transfer.Transaction{}, nil, fmt.Errorf("cannot withdraw %v %s, only %v has been accrued", amount, token.Name, accrued)
+	}
+
+	oracle, err := wallet.GasPriceOracle()
+	if err != nil {
+		return transfer.Transaction{}, nil, err
+	}
+
+	if err := account.Transact(
+		ctx,
+		nil,
+		func(tops *bind.TransactOpts) (*types.Transaction, error) {
+			if _, err := eth.SetFees(ctx, oracle, account.EthClient(), tops, swapperAddress, contractABI, "withdrawBrokerFees", amount); err != nil {
+				return nil, err
+			}
+			tx, err := contract.WithdrawBrokerFees(tops, amount)
+			if err != nil {
+				return tx, err
+			}
+			signedTx = tx
+			return tx, nil
+		},
+		nil,
+		1,
+	); err != nil {
+		return transfer.Transaction{}, nil, err
+	}
+	tx, err := ethereumTransaction(signedTx)
+	return tx, amount, err
+}
+
+// brokerContract returns the Swapperd contract of the token, its address and
+// its ABI.
+func (wallet *wallet) brokerContract(account beth.Account, token blockchain.Token) (brokerContract, common.Address, string, error) {
+	swapperAddress, err := wallet.swapperAddress(account, token)
+	if err != nil {
+		return nil, common.Address{}, "", err
+	}
+	if token.Name == blockchain.ETH {
+		contract, err := eth.NewSwapperdEth(swapperAddress, bind.ContractBackend(account.EthClient()))
+		return contract, swapperAddress, eth.SwapperdEthABI, err
+	}
+	contract, err := erc20.NewSwapperdERC20(swapperAddress, bind.ContractBackend(account.EthClient()))
+	return contract, swapperAddress, erc20.SwapperdERC20ABI, err
+}
@@ -0,0 +1,177 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/republicprotocol/beth-go"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// ContractStatus is the result of verifying the Swapperd contract of a token.
+type ContractStatus struct {
+	Address string `json:"address,omitempty"`
+	Version string `json:"version,omitempty"`
+	Ok      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+type addressBook interface {
+	ReadAddress(key string) (common.Address, error)
+}
+
+// SwapperAddresses returns the addresses of the Swapperd contracts of all the
+// supported Ethereum tokens.
+func (wallet *wallet) SwapperAddresses() (map[blockchain.TokenName]string, error) {
+	client, err := beth.Connect(wallet.config.Ethereum.Network.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := map[blockchain.TokenName]string{}
+	for _, token := range wallet.SupportedTokens() {
+		if token.Blockchain != blockchain.Ethereum {
+			continue
+		}
+		swapperAddress, err := wallet.swapperAddress(client, token)
+		if err != nil {
+			return nil, err
+		}
+		addresses[token.Name] = swapperAddress.String()
+	}
+	return addresses, nil
+}
+
+// ContractAddresses returns the addresses of the ERC20 contract and the
+// Swapperd contract of the token. The token address of ETH is empty.
+func (wallet *wallet) ContractAddresses(token blockchain.Token) (string, string, error) {
+	client, err := beth.Connect(wallet.config.Ethereum.Network.URL)
+	if err != nil {
+		return "", "", err
+	}
+
+	swapperAddress, err := wallet.swapperAddress(client, token)
+	if err != nil {
+		return "", "", err
+	}
+
+	if token.Name == blockchain.ETH {
+		return "", swapperAddress.String(), nil
+	}
+
+	tokenAddress, err := wallet.tokenAddress(client, token)
+	if err != nil {
+		return "", "", err
+	}
+	return tokenAddress.String(), swapperAddress.String(), nil
+}
+
+// tokenAddress returns the ERC20 contract address of the token from the
+// registry, falling back to the address book of the network.
+func (wallet *wallet) tokenAddress(book addressBook, token blockchain.Token) (common.Address, error) {
+	config, err := wallet.tokens.Config(token.Name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if config.TokenAddress != "" {
+		return common.HexToAddress(config.TokenAddress), nil
+	}
+	return book.ReadAddress(string(token.Name))
+}
+
+// swapperAddress returns the Swapperd contract address of the token from the
+// registry, falling back to the address book of the network.
+func (wallet *wallet) swapperAddress(book addressBook, token blockchain.Token) (common.Address, error) {
+	config, err := wallet.tokens.Config(token.Name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if config.SwapperAddress != "" {
+		return common.HexToAddress(config.SwapperAddress), nil
+	}
+	if token.Name == blockchain.ETH {
+		return book.ReadAddress("SwapperdETH")
+	}
+	return book.ReadAddress(fmt.Sprintf("Swapperd%s", token.Name))
+}
+
+// VerifyContracts checks that the Swapperd contract of every supported
+// Ethereum token is deployed and has a compatible version. The results are
+// kept, and swaps of the tokens whose contract failed are refused.
+func (wallet *wallet) VerifyContracts() map[blockchain.TokenName]ContractStatus {
+	statuses := map[blockchain.TokenName]ContractStatus{}
+	for _, token := range wallet.SupportedTokens() {
+		if token.Blockchain != blockchain.Ethereum {
+			continue
+		}
+		statuses[token.Name] = wallet.verifyContract(token)
+	}
+
+	wallet.contractsMu.Lock()
+	defer wallet.contractsMu.Unlock()
+	wallet.contracts = statuses
+	return wallet.contractStatuses()
+}
+
+// ContractStatuses returns the results of the last VerifyContracts.
+func (wallet *wallet) ContractStatuses() map[blockchain.TokenName]ContractStatus {
+	wallet.contractsMu.RLock()
+	defer wallet.contractsMu.RUnlock()
+	return wallet.contractStatuses()
+}
+
+// VerifyContract returns an error if the Swapperd contract of the token failed
+// verification. Tokens that have not been verified are allowed. A failed
+// contract is verified again, so that a contract deployed or a node that came
+// back after the last verification is accepted.
+func (wallet *wallet) VerifyContract(token blockchain.Token) error {
+	wallet.contractsMu.RLock()
+	status, ok := wallet.contracts[token.Name]
+	wallet.contractsMu.RUnlock()
+	if !ok || status.Ok {
+		return nil
+	}
+
+	status = wallet.verifyContract(token)
+	wallet.contractsMu.Lock()
+	if _, ok := wallet.contracts[token.Name]; ok {
+		wallet.contracts[token.Name] = status
+	}
+	wallet.contractsMu.Unlock()
+	if status.Ok {
+		return nil
+	}
+	return fmt.Errorf("swapperd contract of %s failed verification: %s", token.Name, status.Error)
+}
+
+// contractStatuses copies the statuses, the caller must hold the lock.
+func (wallet *wallet) contractStatuses() map[blockchain.TokenName]ContractStatus {
+	statuses := make(map[blockchain.TokenName]ContractStatus, len(wallet.contracts))
+	for name, status := range wallet.contracts {
+		statuses[name] = status
+	}
+	return statuses
+}
+
+func (wallet *wallet) verifyContract(token blockchain.Token) ContractStatus {
+	client, err := beth.Connect(wallet.config.Ethereum.Network.URL)
+	if err != nil {
+		return ContractStatus{Error: err.Error()}
+	}
+	swapperAddress, err := wallet.swapperAddress(client, token)
+	if err != nil {
+		return ContractStatus{Error: err.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	version, err := eth.VerifyContract(ctx, client.EthClient(), swapperAddress)
+	if err != nil {
+		return ContractStatus{Address: swapperAddress.String(), Version: version, Error: err.Error()}
+	}
+	return ContractStatus{Address: swapperAddress.String(), Version: version, Ok: true}
+}
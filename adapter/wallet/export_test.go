@@ -0,0 +1,30 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// Derivation exports the derivation path of the account key of a blockchain,
+// and the type of its addresses, to the tests.
+func Derivation(config Config, blockchainName blockchain.BlockchainName, params *chaincfg.Params) (string, string, error) {
+	derivation, err := newWallet(config, nil, nil).derivation(blockchainName, params)
+	if err != nil {
+		return "", "", err
+	}
+	return FormatDerivationPath(derivation.path), derivation.addressType, nil
+}
+
+// ScanUTXOAddresses exports the addresses that a watch-only wallet of the
+// config finds with the client to the tests.
+func ScanUTXOAddresses(config Config, client btc.Client, blockchainName blockchain.BlockchainName) ([]string, error) {
+	wallet := newWallet(config, nil, nil)
+	derivation, err := wallet.derivation(blockchainName, client.NetworkParams())
+	if err != nil {
+		return nil, err
+	}
+	return wallet.scanUTXOAddresses(context.Background(), client, blockchainName, derivation)
+}
@@ -1,18 +1,215 @@
 package wallet
 
 import (
+	"context"
+	"fmt"
 	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
 )
 
+// EthereumTransferGas is the gas used by a transfer of ether.
+const EthereumTransferGas = 21000
+
+// EthereumSwapGas is the gas reserved for initiating a swap of ether. It is
+// above the gas used by the Swapperd contract, which is estimated when the
+// swap is initiated.
+const EthereumSwapGas = 200000
+
 func (wallet *wallet) DefaultFee(blockchainName blockchain.BlockchainName) (*big.Int, error) {
 	switch blockchainName {
 	case blockchain.Ethereum:
-		return big.NewInt(12000000000), nil
-	case blockchain.Bitcoin:
+		oracle, err := wallet.GasPriceOracle()
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		gasPrice, err := oracle.GasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return gasPrice.GasPrice, nil
+	case blockchain.Bitcoin, blockchain.Litecoin, blockchain.BitcoinCash:
 		return big.NewInt(10000), nil
 	default:
 		return nil, blockchain.NewErrUnsupportedBlockchain(blockchainName)
 	}
 }
+
+// GasPriceOracle returns the Ethereum gas price oracle chosen in the config.
+// The oracle keeps its connection to the node, so it is only dialed once.
+func (wallet *wallet) GasPriceOracle() (eth.GasPriceOracle, error) {
+	wallet.rpcMu.Lock()
+	defer wallet.rpcMu.Unlock()
+	if wallet.oracle != nil {
+		return wallet.oracle, nil
+	}
+	oracle, err := wallet.newGasPriceOracle()
+	if err != nil {
+		return nil, err
+	}
+	wallet.oracle = oracle
+	return oracle, nil
+}
+
+func (wallet *wallet) newGasPriceOracle() (eth.GasPriceOracle, error) {
+	config := wallet.config.Ethereum
+	switch config.GasPriceOracle {
+	case eth.GasPriceOracleStatic:
+		gasPrice, ok := new(big.Int).SetString(config.GasPrice, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid static gas price: %s", config.GasPrice)
+		}
+		return eth.NewStaticGasPriceOracle(gasPrice), nil
+	case eth.GasPriceOracleNode:
+		client, err := ethclient.Dial(config.Network.URL)
+		if err != nil {
+			return nil, err
+		}
+		return eth.NewNodeGasPriceOracle(client), nil
+	case eth.GasPriceOracleFeeHistory, "":
+		client, err := rpc.Dial(config.Network.URL)
+		if err != nil {
+			return nil, err
+		}
+		return eth.NewFeeHistoryGasPriceOracle(client), nil
+	default:
+		return nil, eth.NewErrUnknownGasPriceOracle(config.GasPriceOracle)
+	}
+}
+
+// MaxFeeMultiplier bounds the fees that can be set on transfers and swaps, as
+// a multiple of the default fee, so that a mistyped fee cannot spend the
+// balance on fees.
+const MaxFeeMultiplier = 10
+
+// NewErrFeeOutOfRange returns an error for a fee that is not positive, or
+// above the maximum fee.
+func NewErrFeeOutOfRange(fee, max *big.Int) error {
+	return fmt.Errorf("fee %s is out of range, expected a fee above 0 and at most %s", fee, max)
+}
+
+// VerifyFee returns an error if the fee, in satoshis for UTXO blockchains and
+// as a gas price in wei for Ethereum, is not positive or exceeds the
+// MaxFeeMultiplier times the default fee.
+func (wallet *wallet) VerifyFee(blockchainName blockchain.BlockchainName, fee *big.Int) error {
+	defaultFee, err := wallet.DefaultFee(blockchainName)
+	if err != nil {
+		return err
+	}
+	max := new(big.Int).Mul(defaultFee, big.NewInt(MaxFeeMultiplier))
+	if fee.Sign() <= 0 || fee.Cmp(max) > 0 {
+		return NewErrFeeOutOfRange(fee, max)
+	}
+	return nil
+}
+
+// The priorities of a transfer. The fee of each priority is a percentage of
+// the default fee.
+const (
+	PrioritySlow   = "slow"
+	PriorityNormal = "normal"
+	PriorityFast   = "fast"
+)
+
+var priorityPercentages = map[string]int64{
+	PrioritySlow:   50,
+	PriorityNormal: 100,
+	PriorityFast:   200,
+}
+
+// NewErrUnknownPriority returns an error for a priority that is not slow,
+// normal or fast.
+func NewErrUnknownPriority(priority string) error {
+	return fmt.Errorf("unknown priority %s, expected %s, %s or %s", priority, PrioritySlow, PriorityNormal, PriorityFast)
+}
+
+// PriorityFee returns the fee of the blockchain for the priority, in satoshis
+// for UTXO blockchains and as a gas price in wei for Ethereum. An empty
+// priority is the normal priority.
+func (wallet *wallet) PriorityFee(blockchainName blockchain.BlockchainName, priority string) (*big.Int, error) {
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	percentage, ok := priorityPercentages[priority]
+	if !ok {
+		return nil, NewErrUnknownPriority(priority)
+	}
+	fee, err := wallet.DefaultFee(blockchainName)
+	if err != nil {
+		return nil, err
+	}
+	fee = new(big.Int).Mul(fee, big.NewInt(percentage))
+	return fee.Div(fee, big.NewInt(100)), nil
+}
+
+// TransferFee returns the fee, in the smallest unit of the token, paid by a
+// transfer of the token at the fee. ERC20 transfers pay their fee in ether, so
+// their fee in the token is zero.
+func (wallet *wallet) TransferFee(token blockchain.Token, fee *big.Int) (*big.Int, error) {
+	config, err := wallet.tokens.Config(token.Name)
+	if err != nil {
+		return nil, err
+	}
+	switch config.Blockchain {
+	case blockchain.Bitcoin, blockchain.Litecoin, blockchain.BitcoinCash:
+		return new(big.Int).Set(fee), nil
+	case blockchain.Ethereum:
+		if config.IsERC20() {
+			return big.NewInt(0), nil
+		}
+		return new(big.Int).Mul(fee, big.NewInt(EthereumTransferGas)), nil
+	default:
+		return nil, blockchain.NewErrUnsupportedToken(token.Name)
+	}
+}
+
+// SwapFee returns the fee of initiating a swap of the token, in the smallest
+// unit of the token, at the fee of the swap. The default fee is used when the
+// fee is nil. The ether paid for an ERC20 swap is not included.
+func (wallet *wallet) SwapFee(token blockchain.Token, fee *big.Int) (*big.Int, error) {
+	config, err := wallet.tokens.Config(token.Name)
+	if err != nil {
+		return nil, err
+	}
+	if config.IsERC20() {
+		return big.NewInt(0), nil
+	}
+	if fee == nil {
+		if fee, err = wallet.DefaultFee(config.Blockchain); err != nil {
+			return nil, err
+		}
+	}
+	switch config.Blockchain {
+	case blockchain.Bitcoin, blockchain.Litecoin, blockchain.BitcoinCash:
+		return new(big.Int).Set(fee), nil
+	case blockchain.Ethereum:
+		return new(big.Int).Mul(fee, big.NewInt(EthereumSwapGas)), nil
+	default:
+		return nil, blockchain.NewErrUnsupportedToken(token.Name)
+	}
+}
+
+// MaxTransferAmount returns the largest amount of the token that can be
+// transferred at the fee, which is the balance that is not reserved less the
+// fee of the transfer.
+func (wallet *wallet) MaxTransferAmount(password string, token blockchain.Token, fee *big.Int) (*big.Int, error) {
+	available, err := wallet.availableBalance(password, token)
+	if err != nil {
+		return nil, err
+	}
+	transferFee, err := wallet.TransferFee(token, fee)
+	if err != nil {
+		return nil, err
+	}
+	amount := available.Sub(available, transferFee)
+	if amount.Sign() <= 0 {
+		return nil, fmt.Errorf("balance of %s does not cover the fee of %s %s", token.Name, transferFee, token.Name)
+	}
+	return amount, nil
+}
@@ -0,0 +1,164 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// AddressIndexStorage persists the last address index allocated by the
+// wallet.
+type AddressIndexStorage interface {
+	AddressIndex() (uint32, error)
+	PutAddressIndex(index uint32) error
+}
+
+// NextAddressIndex allocates a fresh address index, so that each swap funds
+// and receives on its own addresses on the UTXO blockchains. Index 0 is the
+// main address of the wallet, which is used by transfers and by the swaps
+// made before addresses were allocated. Without storage every swap uses the
+// main address.
+func (wallet *wallet) NextAddressIndex() (uint32, error) {
+	if wallet.indices == nil {
+		return 0, nil
+	}
+
+	wallet.indexMu.Lock()
+	defer wallet.indexMu.Unlock()
+	index, err := wallet.indices.AddressIndex()
+	if err != nil {
+		return 0, err
+	}
+	index++
+	if err := wallet.indices.PutAddressIndex(index); err != nil {
+		return 0, err
+	}
+	return index, nil
+}
+
+// lastAddressIndex returns the last allocated address index.
+func (wallet *wallet) lastAddressIndex() (uint32, error) {
+	if wallet.indices == nil {
+		return 0, nil
+	}
+
+	wallet.indexMu.Lock()
+	defer wallet.indexMu.Unlock()
+	return wallet.indices.AddressIndex()
+}
+
+// fundingIndices returns the index, followed by every other allocated index.
+func (wallet *wallet) fundingIndices(index uint32) ([]uint32, error) {
+	last, err := wallet.lastAddressIndex()
+	if err != nil {
+		return nil, err
+	}
+	indices := []uint32{index}
+	for i := uint32(0); i <= last; i++ {
+		if i != index {
+			indices = append(indices, i)
+		}
+	}
+	return indices, nil
+}
+
+// AddressGapLimit is the number of consecutive unused addresses after which a
+// watch-only wallet stops looking for used addresses, as in BIP44.
+const AddressGapLimit = 20
+
+// utxoAddresses returns the addresses of every allocated index of the UTXO
+// blockchain, in the format used by the clients. Watch-only wallets do not
+// know which indices were allocated by the wallet that holds the mnemonic, so
+// they return the used addresses found before the gap limit instead.
+func (wallet *wallet) utxoAddresses(ctx context.Context, password string, blockchainName blockchain.BlockchainName) ([]string, error) {
+	_, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return nil, err
+	}
+	derivation, err := wallet.derivation(blockchainName, client.NetworkParams())
+	if err != nil {
+		return nil, err
+	}
+	if wallet.watchOnly {
+		return wallet.scanUTXOAddresses(ctx, client, blockchainName, derivation)
+	}
+	indices, err := wallet.fundingIndices(0)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.deriveUTXOAddresses(password, client, blockchainName, derivation, indices)
+}
+
+// scanUTXOAddresses returns the main address, and every other used address
+// before AddressGapLimit consecutive unused ones. An address is used once it
+// has received outputs, whether or not they have been spent.
+func (wallet *wallet) scanUTXOAddresses(ctx context.Context, client btc.Client, blockchainName blockchain.BlockchainName, derivation derivation) ([]string, error) {
+	addresses := []string{}
+	unused := 0
+	for next := uint32(0); unused < AddressGapLimit; next += AddressGapLimit {
+		indices := make([]uint32, AddressGapLimit)
+		for i := range indices {
+			indices[i] = next + uint32(i)
+		}
+		batch, err := wallet.deriveUTXOAddresses("", client, blockchainName, derivation, indices)
+		if err != nil {
+			return nil, err
+		}
+		for i, address := range batch {
+			if unused >= AddressGapLimit {
+				break
+			}
+			used, err := utxoAddressUsed(ctx, client, address)
+			if err != nil {
+				return nil, err
+			}
+			if used || indices[i] == 0 {
+				addresses = append(addresses, address)
+			}
+			if used {
+				unused = 0
+			} else {
+				unused++
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// utxoAddressUsed returns true if the address has unspent outputs, or inputs
+// that spent its outputs.
+func utxoAddressUsed(ctx context.Context, client btc.Client, address string) (bool, error) {
+	utxos, err := client.UnspentOutputs(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	if len(utxos) > 0 {
+		return true, nil
+	}
+	scripts, err := client.SpendingScripts(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	return len(scripts) > 0, nil
+}
+
+// deriveUTXOAddresses returns the addresses of the indices of the UTXO
+// blockchain.
+func (wallet *wallet) deriveUTXOAddresses(password string, client btc.Client, blockchainName blockchain.BlockchainName, derivation derivation, indices []uint32) ([]string, error) {
+	pubKeys, err := wallet.loadPublicKeys(password, blockchainName, derivation, indices)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		address, err := btc.PubKeyAddress(derivation.addressType, (*btcec.PublicKey)(pubKey), client.NetworkParams())
+		if err != nil {
+			return nil, err
+		}
+		addresses[i] = address.EncodeAddress()
+	}
+	return addresses, nil
+}
@@ -0,0 +1,76 @@
+package wallet_test
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	. "github.com/republicprotocol/swapperd/adapter/wallet"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// mockUTXOClient treats the addresses as used by the order in which they are
+// looked up, which is the order of their indices.
+type mockUTXOClient struct {
+	btc.Client
+	queried  []string
+	received map[int]bool
+	spent    map[int]bool
+}
+
+func (client *mockUTXOClient) NetworkParams() *chaincfg.Params {
+	return &chaincfg.MainNetParams
+}
+
+func (client *mockUTXOClient) UnspentOutputs(ctx context.Context, address string) ([]btc.UTXO, error) {
+	index := len(client.queried)
+	client.queried = append(client.queried, address)
+	if client.received[index] {
+		return []btc.UTXO{{Amount: 10000}}, nil
+	}
+	return nil, nil
+}
+
+func (client *mockUTXOClient) SpendingScripts(ctx context.Context, address string) ([][]byte, error) {
+	if client.spent[len(client.queried)-1] {
+		return [][]byte{{0}}, nil
+	}
+	return nil, nil
+}
+
+var _ = Describe("Address indices", func() {
+	Context("when the wallet is watch-only", func() {
+		var config Config
+
+		BeforeEach(func() {
+			key, err := bip32.NewMasterKey(make([]byte, 32))
+			Expect(err).ShouldNot(HaveOccurred())
+			config = Config{Bitcoin: BlockchainConfig{
+				Network: Network{Name: "mainnet"},
+				XPub:    key.PublicKey().B58Serialize(),
+			}}
+		})
+
+		It("should only return the main address when no address is used", func() {
+			client := &mockUTXOClient{}
+			addresses, err := ScanUTXOAddresses(config, client, blockchain.Bitcoin)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(client.queried).Should(HaveLen(AddressGapLimit))
+			Expect(addresses).Should(Equal(client.queried[:1]))
+		})
+
+		It("should return the used addresses before the gap limit", func() {
+			client := &mockUTXOClient{
+				received: map[int]bool{5: true},
+				spent:    map[int]bool{24: true, 50: true},
+			}
+			addresses, err := ScanUTXOAddresses(config, client, blockchain.Bitcoin)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(client.queried).Should(HaveLen(24 + AddressGapLimit + 1))
+			Expect(addresses).Should(Equal([]string{client.queried[0], client.queried[5], client.queried[24]}))
+		})
+	})
+})
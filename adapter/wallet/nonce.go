@@ -0,0 +1,445 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/republicprotocol/beth-go"
+	"github.com/sirupsen/logrus"
+)
+
+// NonceSyncInterval is how often pending transactions are checked, so that
+// dropped transactions are resubmitted and nonce gaps are filled.
+const NonceSyncInterval = 30 * time.Second
+
+// nonceManager allocates the nonces of every Ethereum account derived by the
+// wallet, so that concurrent swaps and transfers from the same account do not
+// collide.
+type nonceManager struct {
+	mu       *sync.Mutex
+	url      string
+	trackers map[common.Address]*nonceTracker
+}
+
+func newNonceManager(url string) *nonceManager {
+	return &nonceManager{
+		mu:       new(sync.Mutex),
+		url:      url,
+		trackers: map[common.Address]*nonceTracker{},
+	}
+}
+
+// account wraps the beth account so that all of its transactions get their
+// nonces from the nonce manager.
+func (manager *nonceManager) account(account beth.Account) *nonceManagedAccount {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	tracker, ok := manager.trackers[account.Address()]
+	if !ok {
+		tracker = &nonceTracker{
+			mu:      new(sync.Mutex),
+			address: account.Address(),
+			url:     manager.url,
+			client:  account.EthClient(),
+			gaps:    map[uint64]struct{}{},
+			pending: map[uint64]*types.Transaction{},
+		}
+		manager.trackers[account.Address()] = tracker
+	}
+	return &nonceManagedAccount{account, tracker}
+}
+
+func (manager *nonceManager) run(done <-chan struct{}, logger logrus.FieldLogger) {
+	ticker := time.NewTicker(NonceSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		manager.mu.Lock()
+		trackers := make([]*nonceTracker, 0, len(manager.trackers))
+		for _, tracker := range manager.trackers {
+			trackers = append(trackers, tracker)
+		}
+		manager.mu.Unlock()
+
+		for _, tracker := range trackers {
+			if err := tracker.resubmit(logger); err != nil {
+				logger.Error(fmt.Errorf("failed to sync nonces of %s: %v", tracker.address.String(), err))
+			}
+		}
+	}
+}
+
+func (wallet *wallet) RunNonceManager(done <-chan struct{}, logger logrus.FieldLogger) {
+	wallet.nonces.run(done, logger)
+}
+
+// nonceTracker tracks the nonces of a single Ethereum account. Nonces that
+// were allocated but never used are kept as gaps, and are handed out again
+// before any new nonce.
+type nonceTracker struct {
+	mu      *sync.Mutex
+	address common.Address
+	url     string
+	client  *ethclient.Client
+	signer  bind.SignerFn
+	next    uint64
+	gaps    map[uint64]struct{}
+	pending map[uint64]*types.Transaction
+
+	// txSigner is the EIP-155 signer of the network, once it is known.
+	txSigner types.Signer
+}
+
+// acquire returns the nonce to use for the next attempt of a transaction. The
+// previously held nonce is reused as long as it has not been mined, so that
+// retries replace the pending transaction instead of queueing another one.
+func (tracker *nonceTracker) acquire(ctx context.Context, prev uint64, held bool) (uint64, error) {
+	confirmed, pendingNonce, err := tracker.nonces(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.sync(confirmed, pendingNonce)
+	if held && prev >= confirmed {
+		return prev, nil
+	}
+
+	if len(tracker.gaps) > 0 {
+		nonce := uint64(0)
+		first := true
+		for gap := range tracker.gaps {
+			if first || gap < nonce {
+				nonce = gap
+				first = false
+			}
+		}
+		delete(tracker.gaps, nonce)
+		return nonce, nil
+	}
+
+	nonce := tracker.next
+	tracker.next++
+	return nonce, nil
+}
+
+// release returns a nonce that was never broadcast, so that it can be used by
+// the next transaction.
+func (tracker *nonceTracker) release(nonce uint64) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if _, ok := tracker.pending[nonce]; ok {
+		return
+	}
+	if nonce >= tracker.next {
+		return
+	}
+	tracker.gaps[nonce] = struct{}{}
+	for tracker.next > 0 {
+		if _, ok := tracker.gaps[tracker.next-1]; !ok {
+			break
+		}
+		delete(tracker.gaps, tracker.next-1)
+		tracker.next--
+	}
+}
+
+func (tracker *nonceTracker) record(tx *types.Transaction, signer bind.SignerFn) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.pending[tx.Nonce()] = tx
+	tracker.signer = signer
+}
+
+// chainSigner returns the EIP-155 signer of the network of the account, so
+// that its transactions cannot be replayed on other networks. The chain id is
+// only fetched once.
+func (tracker *nonceTracker) chainSigner(ctx context.Context) (types.Signer, error) {
+	tracker.mu.Lock()
+	txSigner := tracker.txSigner
+	tracker.mu.Unlock()
+	if txSigner != nil {
+		return txSigner, nil
+	}
+
+	chainID, err := tracker.chainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the chain id: %v", err)
+	}
+	txSigner = types.NewEIP155Signer(chainID)
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.txSigner = txSigner
+	return txSigner, nil
+}
+
+// chainID returns the chain id of the node, which transactions are signed
+// with. The network id of the node is not used, since it differs from the
+// chain id on some networks.
+func (tracker *nonceTracker) chainID(ctx context.Context) (*big.Int, error) {
+	client, err := rpc.DialContext(ctx, tracker.url)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var chainID hexutil.Big
+	if err := client.CallContext(ctx, &chainID, "eth_chainId"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&chainID), nil
+}
+
+// nonces returns the number of mined transactions of the account, and the
+// next nonce known to the node including its pending transactions.
+func (tracker *nonceTracker) nonces(ctx context.Context) (uint64, uint64, error) {
+	confirmed, err := tracker.client.NonceAt(ctx, tracker.address, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	pendingNonce, err := tracker.client.PendingNonceAt(ctx, tracker.address)
+	if err != nil {
+		return 0, 0, err
+	}
+	return confirmed, pendingNonce, nil
+}
+
+// sync drops the pending transactions and gaps that have been mined, and
+// moves the next nonce past any transactions sent from outside the wallet.
+// Nonces only grow, so nonces fetched before the lock was taken are safe to
+// apply. It must be called with the lock held.
+func (tracker *nonceTracker) sync(confirmed, pendingNonce uint64) {
+	for nonce := range tracker.pending {
+		if nonce < confirmed {
+			delete(tracker.pending, nonce)
+		}
+	}
+	for nonce := range tracker.gaps {
+		if nonce < pendingNonce {
+			delete(tracker.gaps, nonce)
+		}
+	}
+	if tracker.next < pendingNonce {
+		tracker.next = pendingNonce
+	}
+}
+
+// resubmit broadcasts the pending transactions that have been dropped by the
+// node, and fills the gaps that are blocking pending transactions with empty
+// transactions. The lock is not held while talking to the node, and the gaps
+// being filled are taken out of the tracker so that they are not handed out
+// in the meantime.
+func (tracker *nonceTracker) resubmit(logger logrus.FieldLogger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	confirmed, pendingNonce, err := tracker.nonces(ctx)
+	if err != nil {
+		return err
+	}
+
+	tracker.mu.Lock()
+	tracker.sync(confirmed, pendingNonce)
+	highest := uint64(0)
+	pending := make([]*types.Transaction, 0, len(tracker.pending))
+	for nonce, tx := range tracker.pending {
+		if nonce > highest {
+			highest = nonce
+		}
+		pending = append(pending, tx)
+	}
+	gaps := []uint64{}
+	signer := tracker.signer
+	if signer != nil && len(pending) > 0 {
+		for nonce := range tracker.gaps {
+			if nonce < highest {
+				gaps = append(gaps, nonce)
+				delete(tracker.gaps, nonce)
+			}
+		}
+	}
+	tracker.mu.Unlock()
+
+	for _, tx := range pending {
+		if _, _, err := tracker.client.TransactionByHash(ctx, tx.Hash()); err != ethereum.NotFound {
+			continue
+		}
+		if err := tracker.client.SendTransaction(ctx, tx); err != nil {
+			logger.Error(fmt.Errorf("failed to resubmit transaction %s: %v", tx.Hash().String(), err))
+			continue
+		}
+		logger.Info(fmt.Sprintf("Resubmitted dropped transaction %s with nonce %d", tx.Hash().String(), tx.Nonce()))
+	}
+
+	if len(gaps) == 0 {
+		return nil
+	}
+	filled := map[uint64]*types.Transaction{}
+	defer func() {
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		for _, nonce := range gaps {
+			if tx, ok := filled[nonce]; ok {
+				tracker.pending[nonce] = tx
+				continue
+			}
+			if nonce >= tracker.next {
+				continue
+			}
+			tracker.gaps[nonce] = struct{}{}
+		}
+	}()
+
+	txSigner, err := tracker.chainSigner(ctx)
+	if err != nil {
+		return err
+	}
+	gasPrice, err := tracker.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	for _, nonce := range gaps {
+		tx, err := signer(txSigner, tracker.address, types.NewTransaction(nonce, tracker.address, big.NewInt(0), 21000, gasPrice, nil))
+		if err != nil {
+			return err
+		}
+		if err := tracker.client.SendTransaction(ctx, tx); err != nil {
+			logger.Error(fmt.Errorf("failed to fill nonce gap %d: %v", nonce, err))
+			continue
+		}
+		filled[nonce] = tx
+		logger.Info(fmt.Sprintf("Filled nonce gap %d with transaction %s", nonce, tx.Hash().String()))
+	}
+	return nil
+}
+
+// replacementSigner wraps the signer so that a transaction replacing a
+// pending transaction pays at least 10% more gas, which nodes require to
+// accept the replacement. Transactions are always signed with the EIP-155
+// signer of the network, including those of contract bindings that ask for
+// the homestead signer.
+func (tracker *nonceTracker) replacementSigner(signer bind.SignerFn, txSigner types.Signer) bind.SignerFn {
+	return func(_ types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		tracker.mu.Lock()
+		prev, ok := tracker.pending[tx.Nonce()]
+		tracker.mu.Unlock()
+
+		if ok && tx.To() != nil {
+			minGasPrice := replacementGasPrice(prev.GasPrice())
+			if tx.GasPrice().Cmp(minGasPrice) < 0 {
+				tx = types.NewTransaction(tx.Nonce(), *tx.To(), tx.Value(), tx.Gas(), minGasPrice, tx.Data())
+			}
+		}
+		return signer(txSigner, address, tx)
+	}
+}
+
+// replacementGasPrice returns the lowest gas price of a transaction that
+// replaces a pending transaction at the gas price.
+func replacementGasPrice(gasPrice *big.Int) *big.Int {
+	minGasPrice := new(big.Int).Mul(gasPrice, big.NewInt(110))
+	return minGasPrice.Add(minGasPrice, big.NewInt(99)).Div(minGasPrice, big.NewInt(100))
+}
+
+// nonceManagedAccount is a beth account whose transactions use the nonces
+// allocated by the nonce manager.
+type nonceManagedAccount struct {
+	beth.Account
+	tracker *nonceTracker
+}
+
+func (account *nonceManagedAccount) Transact(ctx context.Context, preConditionCheck func() bool, f func(*bind.TransactOpts) (*types.Transaction, error), postConditionCheck func() bool, waitBlocks int64) error {
+	var nonce uint64
+	var held, sent bool
+	defer func() {
+		if held && !sent {
+			account.tracker.release(nonce)
+		}
+	}()
+
+	return account.Account.Transact(
+		ctx,
+		preConditionCheck,
+		func(tops *bind.TransactOpts) (*types.Transaction, error) {
+			next, err := account.tracker.acquire(ctx, nonce, held)
+			if err != nil {
+				return nil, err
+			}
+			if !held || next != nonce {
+				sent = false
+			}
+			nonce, held = next, true
+
+			txSigner, err := account.tracker.chainSigner(ctx)
+			if err != nil {
+				return nil, err
+			}
+			opts := *tops
+			opts.Nonce = new(big.Int).SetUint64(nonce)
+			opts.Signer = account.tracker.replacementSigner(tops.Signer, txSigner)
+			tx, err := f(&opts)
+			if err != nil {
+				return tx, err
+			}
+			account.tracker.record(tx, tops.Signer)
+			sent = true
+			return tx, nil
+		},
+		postConditionCheck,
+		waitBlocks,
+	)
+}
+
+func (account *nonceManagedAccount) Transfer(ctx context.Context, to common.Address, value *big.Int, waitBlocks int64) (string, error) {
+	var txHash string
+	if err := account.Transact(
+		ctx,
+		nil,
+		func(tops *bind.TransactOpts) (*types.Transaction, error) {
+			gasPrice := tops.GasPrice
+			if gasPrice == nil {
+				var err error
+				gasPrice, err = account.EthClient().SuggestGasPrice(ctx)
+				if err != nil {
+					return nil, err
+				}
+			}
+			txSigner, err := account.tracker.chainSigner(ctx)
+			if err != nil {
+				return nil, err
+			}
+			tx, err := tops.Signer(txSigner, tops.From, types.NewTransaction(tops.Nonce.Uint64(), to, value, 21000, gasPrice, nil))
+			if err != nil {
+				return nil, err
+			}
+			if err := account.EthClient().SendTransaction(ctx, tx); err != nil {
+				return nil, err
+			}
+			txHash = tx.Hash().String()
+			return tx, nil
+		},
+		nil,
+		waitBlocks,
+	); err != nil {
+		return txHash, err
+	}
+	return txHash, nil
+}
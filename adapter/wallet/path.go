@@ -0,0 +1,152 @@
+package wallet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// The purposes of the standard derivation paths, which set the type of the
+// addresses of UTXO blockchains.
+const (
+	PurposeBIP44 = 44
+	PurposeBIP49 = 49
+	PurposeBIP84 = 84
+)
+
+// CoinTypeEthereum is the coin type of Ethereum. Unlike the UTXO blockchains,
+// Ethereum wallets use it on every network.
+const CoinTypeEthereum = 60
+
+// ParseDerivationPath parses a BIP32 path such as "m/84'/0'/0'". Hardened
+// children are marked with an apostrophe or an "h".
+func ParseDerivationPath(path string) ([]uint32, error) {
+	elems := strings.Split(strings.TrimSpace(path), "/")
+	if len(elems) == 0 || elems[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %s: expected it to start with m", path)
+	}
+
+	children := make([]uint32, 0, len(elems)-1)
+	for _, elem := range elems[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(elem, "'") || strings.HasSuffix(elem, "h") {
+			offset = bip32.FirstHardenedChild
+			elem = elem[:len(elem)-1]
+		}
+		child, err := strconv.ParseUint(elem, 10, 32)
+		if err != nil || uint32(child) >= bip32.FirstHardenedChild {
+			return nil, fmt.Errorf("invalid derivation path %s: invalid child %s", path, elem)
+		}
+		children = append(children, uint32(child)+offset)
+	}
+	return children, nil
+}
+
+// FormatDerivationPath formats a BIP32 path, marking hardened children with
+// an apostrophe.
+func FormatDerivationPath(path []uint32) string {
+	formatted := "m"
+	for _, child := range path {
+		if child >= bip32.FirstHardenedChild {
+			formatted += fmt.Sprintf("/%d'", child-bip32.FirstHardenedChild)
+			continue
+		}
+		formatted += fmt.Sprintf("/%d", child)
+	}
+	return formatted
+}
+
+// A derivation is the path of the account key of a blockchain, whose external
+// chain holds the address keys, and the type of the addresses of the keys.
+type derivation struct {
+	path        []uint32
+	addressType string
+}
+
+// addressPath returns the path of the parent of the address keys.
+func (derivation derivation) addressPath() []uint32 {
+	return append(append([]uint32{}, derivation.path...), 0)
+}
+
+// derivation returns the derivation of the blockchain. It is the path in the
+// config, or the hardened BIP44 path of the blockchain. The legacy derivation
+// derives non-hardened children, and is kept for the wallets created before
+// the standard paths were used.
+func (wallet *wallet) derivation(blockchainName blockchain.BlockchainName, params *chaincfg.Params) (derivation, error) {
+	config, err := wallet.blockchainConfig(blockchainName)
+	if err != nil {
+		return derivation{}, err
+	}
+	if config.LegacyDerivation {
+		return derivation{[]uint32{44, legacyCoinType(blockchainName, config.Network.Name, params), 0}, btc.AddressTypeP2PKH}, nil
+	}
+
+	path := []uint32{
+		PurposeBIP44 + bip32.FirstHardenedChild,
+		coinType(blockchainName, params) + bip32.FirstHardenedChild,
+		bip32.FirstHardenedChild,
+	}
+	if config.DerivationPath != "" {
+		if path, err = ParseDerivationPath(config.DerivationPath); err != nil {
+			return derivation{}, err
+		}
+	}
+	if blockchainName == blockchain.Ethereum {
+		return derivation{path, ""}, nil
+	}
+
+	addressType := btc.AddressTypeP2PKH
+	if len(path) > 0 {
+		switch path[0] {
+		case PurposeBIP49 + bip32.FirstHardenedChild:
+			addressType = btc.AddressTypeP2SHP2WPKH
+		case PurposeBIP84 + bip32.FirstHardenedChild:
+			addressType = btc.AddressTypeP2WPKH
+		}
+	}
+	if addressType != btc.AddressTypeP2PKH {
+		if blockchainName == blockchain.BitcoinCash {
+			return derivation{}, fmt.Errorf("%s does not support %s addresses", blockchainName, addressType)
+		}
+		if blockchainName == blockchain.Bitcoin && config.Network.URL == "" {
+			return derivation{}, fmt.Errorf("%s addresses require a %s node url", addressType, blockchainName)
+		}
+	}
+	return derivation{path, addressType}, nil
+}
+
+// coinType returns the registered coin type of the blockchain. The UTXO
+// blockchains share the coin type 1 on their test networks.
+func coinType(blockchainName blockchain.BlockchainName, params *chaincfg.Params) uint32 {
+	if blockchainName == blockchain.Ethereum {
+		return CoinTypeEthereum
+	}
+	if params == nil || params.Name != "mainnet" {
+		return 1
+	}
+	switch blockchainName {
+	case blockchain.Litecoin:
+		return 2
+	case blockchain.BitcoinCash:
+		return 145
+	default:
+		return 0
+	}
+}
+
+// legacyCoinType returns the coin type of the legacy derivation, which used
+// the coin type 1 on every test network, Ethereum included.
+func legacyCoinType(blockchainName blockchain.BlockchainName, network string, params *chaincfg.Params) uint32 {
+	if blockchainName == blockchain.Ethereum {
+		if network == "mainnet" {
+			return CoinTypeEthereum
+		}
+		return 1
+	}
+	return coinType(blockchainName, params)
+}
@@ -0,0 +1,166 @@
+package wallet
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+)
+
+// A Reservation is an amount of a token that is committed to a pending swap,
+// or to a transfer that is in flight, and so cannot be committed again.
+type Reservation struct {
+	Token  blockchain.TokenName `json:"token"`
+	Amount string               `json:"amount"`
+}
+
+// ReservationStorage persists the reservations of the wallet, so that they
+// survive restarts with the pending swaps. Reservations are deleted by their
+// owners once the funds have left the wallet.
+type ReservationStorage interface {
+	Reservations() (map[string]Reservation, error)
+	PutReservation(id string, reservation Reservation) error
+	DeleteReservation(id string) error
+}
+
+// Storage is the storage of the address indices, the reservations and the
+// address book of the wallet.
+type Storage interface {
+	AddressIndexStorage
+	ReservationStorage
+	AddressBookStorage
+}
+
+// ReserveSwap reserves the amount of the token, and the fee of initiating the
+// swap, for the id, once it has checked that the balance which is not yet
+// reserved covers them. The check and the reservation are atomic, so the same
+// funds cannot be committed twice. The default fee is used when the fee is
+// nil.
+func (wallet *wallet) ReserveSwap(password, id string, token blockchain.Token, amount, fee *big.Int) error {
+	swapFee, err := wallet.SwapFee(token, fee)
+	if err != nil {
+		return err
+	}
+	total := new(big.Int).Add(amount, swapFee)
+
+	wallet.reserveMu.Lock()
+	defer wallet.reserveMu.Unlock()
+
+	if err := wallet.VerifyBalance(password, token, total); err != nil {
+		return err
+	}
+	return wallet.reservations.PutReservation(id, Reservation{
+		Token:  token.Name,
+		Amount: total.String(),
+	})
+}
+
+// ReserveTransfer reserves the amount of the token, and the fee of transferring
+// it, for the id. Unlike ReserveSwap it only checks that the balance which
+// is not yet reserved covers the transfer and its fee, so that the whole
+// balance can be transferred. The ether paid for an ERC20 transfer is not
+// reserved.
+func (wallet *wallet) ReserveTransfer(password, id string, token blockchain.Token, amount, fee *big.Int) error {
+	wallet.reserveMu.Lock()
+	defer wallet.reserveMu.Unlock()
+
+	transferFee, err := wallet.TransferFee(token, fee)
+	if err != nil {
+		return err
+	}
+	total := new(big.Int).Add(amount, transferFee)
+	available, err := wallet.availableBalance(password, token)
+	if err != nil {
+		return err
+	}
+	if available.Cmp(total) < 0 {
+		return fmt.Errorf("You must have at least %s %s available in your wallet to cover the transfer and its fee. You have %s %s", total, token.Name, available, token.Name)
+	}
+	return wallet.reservations.PutReservation(id, Reservation{
+		Token:  token.Name,
+		Amount: total.String(),
+	})
+}
+
+// ReleaseBalance deletes the reservation of the id. Releasing an id that has
+// no reservation does nothing.
+func (wallet *wallet) ReleaseBalance(id string) error {
+	return wallet.reservations.DeleteReservation(id)
+}
+
+// ReservedBalance returns the total amount of the token that is reserved.
+func (wallet *wallet) ReservedBalance(token blockchain.Token) (*big.Int, error) {
+	reservations, err := wallet.reservations.Reservations()
+	if err != nil {
+		return nil, err
+	}
+	reserved := big.NewInt(0)
+	for id, reservation := range reservations {
+		if reservation.Token != token.Name {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(reservation.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount of reservation %s: %s", id, reservation.Amount)
+		}
+		reserved.Add(reserved, amount)
+	}
+	return reserved, nil
+}
+
+// availableBalance returns the balance of the token less the amount that is
+// reserved. The balance is not read from the cache, so that funds which have
+// left the wallet since it was cached cannot be committed again.
+func (wallet *wallet) availableBalance(password string, token blockchain.Token) (*big.Int, error) {
+	balance, err := wallet.uncachedBalance(password, token)
+	if err != nil {
+		return nil, err
+	}
+	amount, ok := new(big.Int).SetString(balance.Amount, 10)
+	if !ok {
+		return nil, fmt.Errorf("Invalid balance amount: %s", balance.Amount)
+	}
+	reserved, err := wallet.ReservedBalance(token)
+	if err != nil {
+		return nil, err
+	}
+	return amount.Sub(amount, reserved), nil
+}
+
+// memoryReservations keeps the reservations of a wallet without storage.
+type memoryReservations struct {
+	mu           *sync.Mutex
+	reservations map[string]Reservation
+}
+
+func newMemoryReservations() ReservationStorage {
+	return &memoryReservations{
+		mu:           new(sync.Mutex),
+		reservations: map[string]Reservation{},
+	}
+}
+
+func (memory *memoryReservations) Reservations() (map[string]Reservation, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	reservations := make(map[string]Reservation, len(memory.reservations))
+	for id, reservation := range memory.reservations {
+		reservations[id] = reservation
+	}
+	return reservations, nil
+}
+
+func (memory *memoryReservations) PutReservation(id string, reservation Reservation) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	memory.reservations[id] = reservation
+	return nil
+}
+
+func (memory *memoryReservations) DeleteReservation(id string) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	delete(memory.reservations, id)
+	return nil
+}
@@ -2,18 +2,52 @@ package wallet
 
 import "github.com/republicprotocol/swapperd/foundation/blockchain"
 
+// SupportedTokens returns the tokens of the registry whose blockchain is
+// configured. When the blockchain lists its tokens, the default tokens that are
+// not listed are left out. Watch-only wallets only support the blockchains
+// that have an extended public key.
 func (wallet *wallet) SupportedTokens() []blockchain.Token {
-	return []blockchain.Token{
-		blockchain.TokenBTC,
-		blockchain.TokenETH,
-		blockchain.TokenWBTC,
-		blockchain.TokenREN,
-		blockchain.TokenZRX,
-		blockchain.TokenOMG,
-		blockchain.TokenTUSD,
-		blockchain.TokenDGX,
-		blockchain.TokenGUSD,
-		blockchain.TokenDAI,
-		blockchain.TokenUSDC,
+	tokens := []blockchain.Token{}
+	for _, token := range wallet.tokens.Tokens() {
+		config, err := wallet.blockchainConfig(token.Blockchain)
+		if err != nil {
+			continue
+		}
+		if wallet.watchOnly && config.XPub == "" {
+			continue
+		}
+		if len(config.Tokens) > 0 && !listsToken(config.Tokens, token.Name) && !wallet.configuresToken(token.Name) {
+			continue
+		}
+		tokens = append(tokens, token)
 	}
+	return tokens
+}
+
+func (wallet *wallet) configuresToken(name blockchain.TokenName) bool {
+	for _, config := range wallet.config.Tokens {
+		if config.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func listsToken(tokens []string, name blockchain.TokenName) bool {
+	for _, token := range tokens {
+		if blockchain.TokenName(token) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PatchToken returns the supported token with the given name or alias.
+func (wallet *wallet) PatchToken(token string) (blockchain.Token, error) {
+	return wallet.tokens.PatchToken(token)
+}
+
+// TokenConfig returns the registry entry of the supported token.
+func (wallet *wallet) TokenConfig(token blockchain.Token) (blockchain.TokenConfig, error) {
+	return wallet.tokens.Config(token.Name)
 }
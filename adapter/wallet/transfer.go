@@ -2,104 +2,198 @@ package wallet
 
 import (
 	"context"
+	"encoding/hex"
+	"fmt"
 	"math/big"
 	"time"
 
+	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/republicprotocol/beth-go"
 	"github.com/republicprotocol/libbtc-go"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
 	"github.com/republicprotocol/swapperd/adapter/binder/erc20"
 	"github.com/republicprotocol/swapperd/core/transfer"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
 )
 
-func (wallet *wallet) Transfer(password string, token blockchain.Token, to string, amount *big.Int) (string, error) {
-	switch token {
-	case blockchain.TokenBTC:
-		return wallet.transferBTC(password, to, amount)
-	case blockchain.TokenETH:
-		return wallet.transferETH(password, to, amount)
-	case blockchain.TokenWBTC, blockchain.TokenDGX, blockchain.TokenREN,
-		blockchain.TokenTUSD, blockchain.TokenZRX, blockchain.TokenOMG,
-		blockchain.TokenGUSD, blockchain.TokenDAI, blockchain.TokenUSDC:
-		return wallet.transferERC20(password, token, to, amount)
+// DropTimeout is the time after which a transfer that the blockchain does not
+// know about is dropped.
+const DropTimeout = 10 * time.Minute
+
+// Transfer transfers the amount of the token to the address, paying the fee in
+// satoshis for UTXO blockchains or as a gas price in wei for Ethereum. The
+// default fee is paid when the fee is nil. It returns the signed transaction
+// and the fee paid, in satoshis or wei.
+func (wallet *wallet) Transfer(password string, token blockchain.Token, to string, amount, fee *big.Int) (transfer.Transaction, error) {
+	config, err := wallet.tokens.Config(token.Name)
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+	if fee == nil {
+		if fee, err = wallet.DefaultFee(config.Blockchain); err != nil {
+			return transfer.Transaction{}, err
+		}
+	}
+	switch config.Blockchain {
+	case blockchain.Bitcoin:
+		return wallet.transferBTC(password, to, amount, fee)
+	case blockchain.Litecoin, blockchain.BitcoinCash:
+		return wallet.transferUTXO(password, config.Blockchain, to, amount, fee)
+	case blockchain.Ethereum:
+		if config.IsERC20() {
+			return wallet.transferERC20(password, token, to, amount, fee)
+		}
+		return wallet.transferETH(password, to, amount, fee)
 	default:
-		return "", blockchain.NewErrUnsupportedToken(token.Name)
+		return transfer.Transaction{}, blockchain.NewErrUnsupportedToken(token.Name)
 	}
 }
 
-func (wallet *wallet) transferBTC(password, to string, amount *big.Int) (string, error) {
+func (wallet *wallet) transferBTC(password, to string, amount, fee *big.Int) (transfer.Transaction, error) {
+	if wallet.config.Bitcoin.Network.URL != "" {
+		return wallet.transferUTXO(password, blockchain.Bitcoin, to, amount, fee)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
 	account, err := wallet.BitcoinAccount(password)
 	if err != nil {
-		return "", err
+		return transfer.Transaction{}, err
+	}
+	tx, err := btc.Transfer(ctx, account, btc.Bitcoin, to, amount.Int64(), fee.Int64())
+	if err != nil {
+		return transfer.Transaction{}, err
 	}
-	return account.Transfer(ctx, to, amount.Int64())
+	return utxoTransaction(tx, fee)
 }
 
-func (wallet *wallet) transferETH(password, to string, amount *big.Int) (string, error) {
+// transferETH transfers the value at the gas price, so that the fee paid is
+// known before the transfer.
+func (wallet *wallet) transferETH(password, to string, amount, gasPrice *big.Int) (transfer.Transaction, error) {
+	var signedTx *types.Transaction
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
-	account, err := wallet.EthereumAccount(password)
+	account, err := wallet.ethereumAccount(password)
 	if err != nil {
-		return "", err
+		return transfer.Transaction{}, err
+	}
+
+	if err := account.Transact(
+		ctx,
+		nil,
+		func(tops *bind.TransactOpts) (*types.Transaction, error) {
+			txSigner, err := account.tracker.chainSigner(ctx)
+			if err != nil {
+				return nil, err
+			}
+			tx, err := tops.Signer(txSigner, tops.From, types.NewTransaction(tops.Nonce.Uint64(), common.HexToAddress(to), amount, EthereumTransferGas, gasPrice, nil))
+			if err != nil {
+				return nil, err
+			}
+			if err := account.EthClient().SendTransaction(ctx, tx); err != nil {
+				return nil, err
+			}
+			signedTx = tx
+			return tx, nil
+		},
+		nil,
+		0,
+	); err != nil {
+		return transfer.Transaction{}, err
 	}
-	return account.Transfer(ctx, common.HexToAddress(to), amount, 0)
+	return ethereumTransaction(signedTx)
 }
 
-func (wallet *wallet) transferERC20(password string, token blockchain.Token, to string, amount *big.Int) (string, error) {
-	var txHash string
+// transferERC20 transfers the amount of the token at the gas price. The fee
+// paid is the gas limit of the transaction, which is estimated to be the gas
+// that it uses, at the gas price.
+func (wallet *wallet) transferERC20(password string, token blockchain.Token, to string, amount, gasPrice *big.Int) (transfer.Transaction, error) {
+	var signedTx *types.Transaction
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
 	account, err := wallet.EthereumAccount(password)
 	if err != nil {
-		return txHash, err
+		return transfer.Transaction{}, err
 	}
-	tokenAddress, err := account.ReadAddress(string(token.Name))
+	tokenAddress, err := wallet.tokenAddress(account, token)
 	if err != nil {
-		return txHash, err
+		return transfer.Transaction{}, err
 	}
 
 	tokenContract, err := erc20.NewCompatibleERC20(tokenAddress, bind.ContractBackend(account.EthClient()))
 	if err != nil {
-		return txHash, err
+		return transfer.Transaction{}, err
 	}
 
 	if err := account.Transact(
 		ctx,
 		nil,
 		func(tops *bind.TransactOpts) (*types.Transaction, error) {
-			tx, err := tokenContract.Transfer(tops, common.HexToAddress(to), amount)
+			opts := *tops
+			opts.GasPrice = gasPrice
+			tx, err := tokenContract.Transfer(&opts, common.HexToAddress(to), amount)
 			if err != nil {
 				return tx, err
 			}
-			txHash = tx.Hash().String()
+			signedTx = tx
 			return tx, nil
 		},
 		nil,
 		1,
 	); err != nil {
-		return txHash, err
+		return transfer.Transaction{}, err
 	}
 
-	return txHash, nil
+	return ethereumTransaction(signedTx)
 }
 
-func (wallet *wallet) Lookup(token blockchain.Token, txHash string) (transfer.UpdateReceipt, error) {
-	switch token.Blockchain {
+func ethereumTransaction(tx *types.Transaction) (transfer.Transaction, error) {
+	rawTx, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+	return transfer.Transaction{
+		TxHash: tx.Hash().String(),
+		Fee:    new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas())),
+		RawTx:  hex.EncodeToString(rawTx),
+	}, nil
+}
+
+func decodeEthereumTransaction(rawTx string) (*types.Transaction, error) {
+	txBytes, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(txBytes, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Lookup returns the confirmations and the status of the transfer.
+func (wallet *wallet) Lookup(receipt transfer.TransferReceipt) (transfer.UpdateReceipt, error) {
+	switch receipt.Token.Blockchain {
 	case blockchain.Bitcoin:
-		return wallet.bitcoinLookup(txHash)
+		return wallet.bitcoinLookup(receipt)
+	case blockchain.Litecoin, blockchain.BitcoinCash:
+		return wallet.utxoLookup(receipt.Token.Blockchain, receipt)
 	case blockchain.Ethereum:
-		return wallet.ethereumLookup(txHash)
+		return wallet.ethereumLookup(receipt)
 	default:
-		return transfer.UpdateReceipt{}, blockchain.NewErrUnsupportedBlockchain(token.Blockchain)
+		return transfer.UpdateReceipt{}, blockchain.NewErrUnsupportedBlockchain(receipt.Token.Blockchain)
 	}
 }
 
-func (wallet *wallet) ethereumLookup(txHash string) (transfer.UpdateReceipt, error) {
+// ethereumLookup returns the confirmations and the status of the transfer. A
+// mined transfer is reverted if its receipt failed. A transfer that the node
+// does not know about is replaced once its nonce has been used by another
+// transaction, and dropped otherwise.
+func (wallet *wallet) ethereumLookup(receipt transfer.TransferReceipt) (transfer.UpdateReceipt, error) {
 	client, err := beth.Connect(wallet.config.Ethereum.Network.URL)
 	if err != nil {
 		return transfer.UpdateReceipt{}, err
@@ -108,34 +202,216 @@ func (wallet *wallet) ethereumLookup(txHash string) (transfer.UpdateReceipt, err
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
-	txBlockNumber, err := client.TxBlockNumber(ctx, txHash)
-	if err != nil {
+	txHash := common.HexToHash(receipt.TxHash)
+	txReceipt, err := client.EthClient().TransactionReceipt(ctx, txHash)
+	if err == nil {
+		txBlockNumber, err := client.TxBlockNumber(ctx, receipt.TxHash)
+		if err != nil {
+			return transfer.UpdateReceipt{}, err
+		}
+		currBlockNumber, err := client.CurrentBlockNumber(ctx)
+		if err != nil {
+			return transfer.UpdateReceipt{}, err
+		}
+		confirmations := new(big.Int).Sub(currBlockNumber, txBlockNumber).Int64()
+		status := confirmedStatus(confirmations)
+		if txReceipt.Status == types.ReceiptStatusFailed {
+			status = transfer.TransferStatusReverted
+		}
+		fee, err := ethereumFee(ctx, client.EthClient(), receipt, txReceipt)
+		if err != nil {
+			return transfer.UpdateReceipt{}, err
+		}
+		return transfer.NewUpdateReceipt(receipt.TxHash, func(receipt *transfer.TransferReceipt) {
+			receipt.Confirmations = confirmations
+			receipt.Status = status
+			receipt.Fee = fee.String()
+		}), nil
+	}
+	if err != ethereum.NotFound {
 		return transfer.UpdateReceipt{}, err
 	}
 
-	currBlockNumber, err := client.CurrentBlockNumber(ctx)
-	if err != nil {
+	status := transfer.TransferStatusBroadcast
+	if _, _, err := client.EthClient().TransactionByHash(ctx, txHash); err == ethereum.NotFound {
+		if status, err = unknownEthereumStatus(ctx, client.EthClient(), receipt); err != nil {
+			return transfer.UpdateReceipt{}, err
+		}
+	} else if err != nil {
 		return transfer.UpdateReceipt{}, err
 	}
-
-	confirmations := new(big.Int).Sub(currBlockNumber, txBlockNumber)
-	return transfer.NewUpdateReceipt(txHash, func(receipt *transfer.TransferReceipt) {
-		receipt.Confirmations = confirmations.Int64()
+	return transfer.NewUpdateReceipt(receipt.TxHash, func(receipt *transfer.TransferReceipt) {
+		receipt.Status = status
 	}), nil
 }
 
-func (wallet *wallet) bitcoinLookup(txHash string) (transfer.UpdateReceipt, error) {
+// ethereumFee returns the fee paid by a mined transfer. The fee recorded at
+// broadcast is the gas price times the gas limit, but only the gas used is
+// paid.
+func ethereumFee(ctx context.Context, client *ethclient.Client, receipt transfer.TransferReceipt, txReceipt *types.Receipt) (*big.Int, error) {
+	tx, err := decodeEthereumTransaction(receipt.RawTx)
+	if receipt.RawTx == "" || err != nil {
+		if tx, _, err = client.TransactionByHash(ctx, common.HexToHash(receipt.TxHash)); err != nil {
+			return nil, err
+		}
+	}
+	return new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(txReceipt.GasUsed)), nil
+}
+
+// unknownEthereumStatus returns the status of a transfer that the node does
+// not know about.
+func unknownEthereumStatus(ctx context.Context, client *ethclient.Client, receipt transfer.TransferReceipt) (string, error) {
+	if receipt.RawTx != "" {
+		tx, err := decodeEthereumTransaction(receipt.RawTx)
+		if err != nil {
+			return "", err
+		}
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+		if err != nil {
+			return "", err
+		}
+		nonce, err := client.NonceAt(ctx, from, nil)
+		if err != nil {
+			return "", err
+		}
+		if nonce > tx.Nonce() {
+			return transfer.TransferStatusReplaced, nil
+		}
+	}
+	if time.Since(time.Unix(receipt.Timestamp, 0)) < DropTimeout {
+		return transfer.TransferStatusBroadcast, nil
+	}
+	return transfer.TransferStatusDropped, nil
+}
+
+// bitcoinLookup returns the confirmations and the status of a Bitcoin
+// transfer. The transfers of the libbtc backend are looked up with the Insight
+// API of the network, which can tell when they are dropped, and only networks
+// without one fall back to the confirmations of libbtc.
+func (wallet *wallet) bitcoinLookup(receipt transfer.TransferReceipt) (transfer.UpdateReceipt, error) {
+	if wallet.config.Bitcoin.Network.URL != "" {
+		return wallet.utxoLookup(blockchain.Bitcoin, receipt)
+	}
+	if insight, err := wallet.libbtcClient(); err == nil {
+		return lookupUTXO(insight, receipt)
+	}
+
 	client := libbtc.NewBlockchainInfoClient(wallet.config.Bitcoin.Network.Name)
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
-	confirmations, err := client.Confirmations(ctx, txHash)
+	confirmations, err := client.Confirmations(ctx, receipt.TxHash)
 	if err != nil {
 		return transfer.UpdateReceipt{}, err
 	}
 
-	return transfer.NewUpdateReceipt(txHash, func(receipt *transfer.TransferReceipt) {
+	return transfer.NewUpdateReceipt(receipt.TxHash, func(receipt *transfer.TransferReceipt) {
 		receipt.Confirmations = confirmations
+		receipt.Status = confirmedStatus(confirmations)
 	}), nil
 }
+
+// confirmedStatus returns the status of a transfer that the blockchain knows
+// about.
+func confirmedStatus(confirmations int64) string {
+	if confirmations > 0 {
+		return transfer.TransferStatusConfirmed
+	}
+	return transfer.TransferStatusBroadcast
+}
+
+// Rebroadcast publishes the raw transaction of a transfer again.
+func (wallet *wallet) Rebroadcast(token blockchain.Token, rawTx string) error {
+	switch token.Blockchain {
+	case blockchain.Bitcoin:
+		if wallet.config.Bitcoin.Network.URL == "" {
+			client, err := wallet.libbtcClient()
+			if err != nil {
+				return err
+			}
+			return publishUTXO(client, rawTx)
+		}
+		return wallet.rebroadcastUTXO(token.Blockchain, rawTx)
+	case blockchain.Litecoin, blockchain.BitcoinCash:
+		return wallet.rebroadcastUTXO(token.Blockchain, rawTx)
+	case blockchain.Ethereum:
+		tx, err := decodeEthereumTransaction(rawTx)
+		if err != nil {
+			return err
+		}
+		client, err := beth.Connect(wallet.config.Ethereum.Network.URL)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		return client.EthClient().SendTransaction(ctx, tx)
+	default:
+		return blockchain.NewErrUnsupportedBlockchain(token.Blockchain)
+	}
+}
+
+// Cancel replaces a pending Ethereum transfer with a transfer of nothing to
+// the wallet, at the same nonce and a higher gas price. The transfers of UTXO
+// blockchains cannot be replaced.
+func (wallet *wallet) Cancel(password string, receipt transfer.TransferReceipt) (transfer.Transaction, error) {
+	if receipt.Token.Blockchain != blockchain.Ethereum {
+		return transfer.Transaction{}, fmt.Errorf("cannot cancel %s transfers, only %s transfers can be replaced", receipt.Token.Blockchain, blockchain.Ethereum)
+	}
+	if receipt.RawTx == "" {
+		return transfer.Transaction{}, fmt.Errorf("cannot cancel transfer %s without its raw transaction", receipt.TxHash)
+	}
+	prev, err := decodeEthereumTransaction(receipt.RawTx)
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+
+	account, err := wallet.ethereumAccount(password)
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	txSigner, err := account.tracker.chainSigner(ctx)
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+	if from, err := types.Sender(txSigner, prev); err != nil || from != account.Address() {
+		return transfer.Transaction{}, fmt.Errorf("cannot cancel transfer %s, it was not sent from %s", receipt.TxHash, account.Address().String())
+	}
+
+	gasPrice, err := wallet.DefaultFee(blockchain.Ethereum)
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+	if minGasPrice := replacementGasPrice(prev.GasPrice()); gasPrice.Cmp(minGasPrice) < 0 {
+		gasPrice = minGasPrice
+	}
+
+	// The nonce of the transfer is reused, so the transaction is sent from the
+	// account without the nonce manager, which is told about the replacement
+	var signedTx *types.Transaction
+	if err := account.Account.Transact(
+		ctx,
+		nil,
+		func(tops *bind.TransactOpts) (*types.Transaction, error) {
+			tx, err := tops.Signer(txSigner, tops.From, types.NewTransaction(prev.Nonce(), tops.From, big.NewInt(0), EthereumTransferGas, gasPrice, nil))
+			if err != nil {
+				return nil, err
+			}
+			if err := account.EthClient().SendTransaction(ctx, tx); err != nil {
+				return nil, err
+			}
+			account.tracker.record(tx, tops.Signer)
+			signedTx = tx
+			return tx, nil
+		},
+		nil,
+		0,
+	); err != nil {
+		return transfer.Transaction{}, err
+	}
+	return ethereumTransaction(signedTx)
+}
@@ -0,0 +1,516 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/republicprotocol/swapperd/adapter/binder/bch"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/adapter/binder/ltc"
+	"github.com/republicprotocol/swapperd/core/transfer"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+)
+
+// UTXOAccount returns the account of a UTXO blockchain. Bitcoin uses libbtc, or
+// a node when its url is set, and the other UTXO blockchains use an Insight
+// API.
+func (wallet *wallet) UTXOAccount(password string, blockchainName blockchain.BlockchainName) (btc.Account, error) {
+	return wallet.UTXOAccountAt(password, blockchainName, 0)
+}
+
+// UTXOAccountAt returns the account of a UTXO blockchain at the address index.
+// The account is also funded by the outputs of any one of the other allocated
+// addresses. The libbtc Bitcoin account funds and receives on the main
+// address, and only signs the swap scripts with the key of the index.
+func (wallet *wallet) UTXOAccountAt(password string, blockchainName blockchain.BlockchainName, index uint32) (btc.Account, error) {
+	if blockchainName == blockchain.Bitcoin && wallet.config.Bitcoin.Network.URL == "" {
+		return wallet.bitcoinAccountAt(password, index)
+	}
+	return wallet.utxoAccount(password, blockchainName, index)
+}
+
+func (wallet *wallet) utxoAccount(password string, blockchainName blockchain.BlockchainName, index uint32) (btc.Account, error) {
+	chain, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return nil, err
+	}
+	indices, err := wallet.fundingIndices(index)
+	if err != nil {
+		return nil, err
+	}
+	derivation, err := wallet.derivation(blockchainName, client.NetworkParams())
+	if err != nil {
+		return nil, err
+	}
+	keys, err := wallet.loadECDSAKeys(password, derivation.addressPath(), indices)
+	if err != nil {
+		return nil, err
+	}
+	return btc.NewHDAccount(chain, client, derivation.addressType, keys[0], keys[1:]), nil
+}
+
+// utxoClient returns the chain and the client of a UTXO blockchain. Litecoin
+// and Bitcoin Cash use the Insight API at the url of their network, and
+// Bitcoin uses the JSON-RPC API of the node at the url of its network.
+func (wallet *wallet) utxoClient(blockchainName blockchain.BlockchainName) (btc.Chain, btc.Client, error) {
+	config, err := wallet.blockchainConfig(blockchainName)
+	if err != nil {
+		return btc.Chain{}, nil, err
+	}
+
+	var chain btc.Chain
+	var params *chaincfg.Params
+	switch blockchainName {
+	case blockchain.Bitcoin:
+		params, err := bitcoinParams(config.Network.Name)
+		if err != nil {
+			return btc.Chain{}, nil, err
+		}
+		client, err := wallet.rpcClient(blockchainName, config.Network.URL, params)
+		if err != nil {
+			return btc.Chain{}, nil, err
+		}
+		return btc.Bitcoin, client, nil
+	case blockchain.Litecoin:
+		chain = ltc.Litecoin
+		switch config.Network.Name {
+		case "mainnet":
+			params = &ltc.MainNetParams
+		case "testnet", "testnet4":
+			params = &ltc.TestNet4Params
+		}
+	case blockchain.BitcoinCash:
+		chain = bch.BitcoinCash
+		switch config.Network.Name {
+		case "mainnet":
+			params = &bch.MainNetParams
+		case "testnet", "testnet3":
+			params = &bch.TestNetParams
+		}
+	default:
+		return btc.Chain{}, nil, blockchain.NewErrUnsupportedBlockchain(blockchainName)
+	}
+	if params == nil {
+		return btc.Chain{}, nil, fmt.Errorf("unsupported %s network: %s", blockchainName, config.Network.Name)
+	}
+	if config.Network.URL == "" {
+		return btc.Chain{}, nil, fmt.Errorf("no Insight API url is configured for the %s %s network", blockchainName, config.Network.Name)
+	}
+	client, err := wallet.cachedClient(blockchainName, func() (btc.Client, error) {
+		return btc.NewInsightClient(config.Network.URL, params), nil
+	})
+	return chain, client, err
+}
+
+// TimeLockBlocks returns the blocks of the timelock of a contract on a UTXO
+// blockchain that is funded now. In the height mode it is the height at which
+// the timelock expires, and in the sequence mode it is the number of blocks
+// after funding. The blocks are estimated from the target time per block of
+// the blockchain. Time based timelocks, and the other blockchains, have no
+// blocks.
+func (wallet *wallet) TimeLockBlocks(blockchainName blockchain.BlockchainName, mode string, timelock int64) (int64, error) {
+	if mode == "" || mode == swap.TimeLockModeTime {
+		return 0, nil
+	}
+	switch blockchainName {
+	case blockchain.Bitcoin, blockchain.Litecoin, blockchain.BitcoinCash:
+	default:
+		return 0, nil
+	}
+
+	client, spacing, err := wallet.blockSpacing(blockchainName)
+	if err != nil {
+		return 0, err
+	}
+	remaining := timelock - time.Now().Unix()
+	if remaining <= 0 {
+		return 0, fmt.Errorf("timelock %d has already expired", timelock)
+	}
+	blocks := (remaining + spacing - 1) / spacing
+
+	switch mode {
+	case swap.TimeLockModeHeight:
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		height, err := client.BlockHeight(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return height + blocks, nil
+	case swap.TimeLockModeSequence:
+		if blocks > int64(wire.SequenceLockTimeMask) {
+			return 0, fmt.Errorf("timelock %d is too far to be relative", timelock)
+		}
+		return blocks, nil
+	default:
+		return 0, fmt.Errorf("unknown timelock mode: %s", mode)
+	}
+}
+
+// TimeLockExpiry returns the time at which a contract on a UTXO blockchain
+// that is funded now is expected to expire, with the blocks of its timelock.
+// Block heights and relative timelocks are converted using the target time
+// per block of the blockchain. Time based timelocks, and the other
+// blockchains, expire at the timelock.
+func (wallet *wallet) TimeLockExpiry(blockchainName blockchain.BlockchainName, mode string, timelock, blocks int64) (int64, error) {
+	if mode == "" || mode == swap.TimeLockModeTime {
+		return timelock, nil
+	}
+	switch blockchainName {
+	case blockchain.Bitcoin, blockchain.Litecoin, blockchain.BitcoinCash:
+	default:
+		return timelock, nil
+	}
+
+	client, spacing, err := wallet.blockSpacing(blockchainName)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().Unix()
+
+	switch mode {
+	case swap.TimeLockModeHeight:
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		height, err := client.BlockHeight(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return now + (blocks-height)*spacing, nil
+	case swap.TimeLockModeSequence:
+		return now + blocks*spacing, nil
+	default:
+		return 0, fmt.Errorf("unknown timelock mode: %s", mode)
+	}
+}
+
+// blockSpacing returns the client of the UTXO blockchain, and its target time
+// per block in seconds.
+func (wallet *wallet) blockSpacing(blockchainName blockchain.BlockchainName) (btc.Client, int64, error) {
+	_, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return nil, 0, err
+	}
+	spacing := int64(client.NetworkParams().TargetTimePerBlock / time.Second)
+	if spacing <= 0 {
+		return nil, 0, fmt.Errorf("unknown block time of %s", blockchainName)
+	}
+	return client, spacing, nil
+}
+
+// rpcClient returns the JSON-RPC client of the node at the url. The clients
+// are reused, since they keep track of the addresses imported into the node.
+func (wallet *wallet) rpcClient(blockchainName blockchain.BlockchainName, url string, params *chaincfg.Params) (btc.Client, error) {
+	if url == "" {
+		return nil, fmt.Errorf("no %s node url configured", blockchainName)
+	}
+
+	return wallet.cachedClient(blockchainName, func() (btc.Client, error) {
+		return btc.NewRPCClient(url, params)
+	})
+}
+
+// cachedClient returns the client of the UTXO blockchain, which caches the
+// unspent outputs of the addresses of the wallet. The client is only created
+// once.
+func (wallet *wallet) cachedClient(blockchainName blockchain.BlockchainName, newClient func() (btc.Client, error)) (btc.Client, error) {
+	wallet.rpcMu.Lock()
+	defer wallet.rpcMu.Unlock()
+	if client, ok := wallet.rpcClients[blockchainName]; ok {
+		return client, nil
+	}
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	wallet.rpcClients[blockchainName] = btc.NewCachedClient(client)
+	return wallet.rpcClients[blockchainName], nil
+}
+
+// libbtcInsightURLs are the Insight APIs of the Bitcoin networks, which look
+// up and rebroadcast the transfers of the libbtc backend. libbtc can do
+// neither.
+var libbtcInsightURLs = map[string]string{
+	"mainnet":  "https://insight.bitpay.com/api",
+	"testnet":  "https://test-insight.bitpay.com/api",
+	"testnet3": "https://test-insight.bitpay.com/api",
+}
+
+// libbtcClient returns the Insight client of the Bitcoin network, when the
+// libbtc backend is used. It takes the place of the node client, which is not
+// configured.
+func (wallet *wallet) libbtcClient() (btc.Client, error) {
+	network := wallet.config.Bitcoin.Network.Name
+	url, ok := libbtcInsightURLs[network]
+	if !ok {
+		return nil, fmt.Errorf("no Insight API is known for the %s %s network, configure a %s node url", blockchain.Bitcoin, network, blockchain.Bitcoin)
+	}
+	params, err := bitcoinParams(network)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.cachedClient(blockchain.Bitcoin, func() (btc.Client, error) {
+		return btc.NewInsightClient(url, params), nil
+	})
+}
+
+func bitcoinParams(network string) (*chaincfg.Params, error) {
+	switch network {
+	case "mainnet":
+		return &chaincfg.MainNetParams, nil
+	case "testnet", "testnet3":
+		return &chaincfg.TestNet3Params, nil
+	case "regtest":
+		return &chaincfg.RegressionNetParams, nil
+	default:
+		return nil, fmt.Errorf("unsupported %s network: %s", blockchain.Bitcoin, network)
+	}
+}
+
+// blockchainConfig returns the config of the blockchain. Litecoin and Bitcoin
+// Cash are only enabled when their network is configured.
+func (wallet *wallet) blockchainConfig(blockchainName blockchain.BlockchainName) (BlockchainConfig, error) {
+	switch blockchainName {
+	case blockchain.Bitcoin:
+		return wallet.config.Bitcoin, nil
+	case blockchain.Ethereum:
+		return wallet.config.Ethereum, nil
+	case blockchain.Litecoin:
+		if wallet.config.Litecoin.Network.Name != "" {
+			return wallet.config.Litecoin, nil
+		}
+	case blockchain.BitcoinCash:
+		if wallet.config.BitcoinCash.Network.Name != "" {
+			return wallet.config.BitcoinCash, nil
+		}
+	}
+	return BlockchainConfig{}, blockchain.NewErrUnsupportedBlockchain(blockchainName)
+}
+
+func (wallet *wallet) getUTXOAddress(password string, blockchainName blockchain.BlockchainName, index uint32) (string, error) {
+	_, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return "", err
+	}
+	derivation, err := wallet.derivation(blockchainName, client.NetworkParams())
+	if err != nil {
+		return "", err
+	}
+	pubKeys, err := wallet.loadPublicKeys(password, blockchainName, derivation, []uint32{index})
+	if err != nil {
+		return "", err
+	}
+	address, err := btc.PubKeyAddress(derivation.addressType, (*btcec.PublicKey)(pubKeys[0]), client.NetworkParams())
+	if err != nil {
+		return "", err
+	}
+	if blockchainName == blockchain.BitcoinCash {
+		return bch.EncodeAddress(address, client.NetworkParams())
+	}
+	return address.EncodeAddress(), nil
+}
+
+// getUTXOSwapAddress returns the address of the index used in swaps. The swap
+// scripts pay to the hash of a public key, which a P2SH-P2WPKH address does
+// not encode, so those wallets swap with the P2WPKH address of the same key.
+func (wallet *wallet) getUTXOSwapAddress(password string, blockchainName blockchain.BlockchainName, index uint32) (string, error) {
+	_, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return "", err
+	}
+	derivation, err := wallet.derivation(blockchainName, client.NetworkParams())
+	if err != nil {
+		return "", err
+	}
+	if derivation.addressType != btc.AddressTypeP2SHP2WPKH {
+		return wallet.getUTXOAddress(password, blockchainName, index)
+	}
+
+	pubKeys, err := wallet.loadPublicKeys(password, blockchainName, derivation, []uint32{index})
+	if err != nil {
+		return "", err
+	}
+	address, err := btc.PubKeyAddress(btc.AddressTypeP2WPKH, (*btcec.PublicKey)(pubKeys[0]), client.NetworkParams())
+	if err != nil {
+		return "", err
+	}
+	return address.EncodeAddress(), nil
+}
+
+func (wallet *wallet) verifyUTXOAddress(blockchainName blockchain.BlockchainName, address string) error {
+	if address == "" {
+		return fmt.Errorf("Empty %s address", blockchainName)
+	}
+	chain, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return err
+	}
+	if _, err := chain.DecodeAddress(address, client.NetworkParams()); err != nil {
+		return fmt.Errorf("Invalid %s %s address: %s", client.NetworkParams().Name, blockchainName, address)
+	}
+	return nil
+}
+
+// balanceUTXO returns the balance of the addresses of the UTXO blockchain
+// returned by utxoAddresses. Each transaction only spends the outputs of one
+// of them.
+func (wallet *wallet) balanceUTXO(ctx context.Context, password string, blockchainName blockchain.BlockchainName, address string) (blockchain.Balance, error) {
+	_, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return blockchain.Balance{}, err
+	}
+	addresses, err := wallet.utxoAddresses(ctx, password, blockchainName)
+	if err != nil {
+		return blockchain.Balance{}, err
+	}
+
+	balance := int64(0)
+	for _, addr := range addresses {
+		utxos, err := client.UnspentOutputs(ctx, addr)
+		if err != nil {
+			return blockchain.Balance{}, err
+		}
+		for _, utxo := range utxos {
+			balance += utxo.Amount
+		}
+	}
+
+	return blockchain.Balance{
+		Address: address,
+		Amount:  big.NewInt(balance).String(),
+	}, nil
+}
+
+func (wallet *wallet) transferUTXO(password string, blockchainName blockchain.BlockchainName, to string, amount, fee *big.Int) (transfer.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	chain, _, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+	account, err := wallet.UTXOAccount(password, blockchainName)
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+	tx, err := btc.Transfer(ctx, account, chain, to, amount.Int64(), fee.Int64())
+	if err != nil {
+		return transfer.Transaction{}, err
+	}
+	return utxoTransaction(tx, fee)
+}
+
+func utxoTransaction(tx *wire.MsgTx, fee *big.Int) (transfer.Transaction, error) {
+	buf := new(bytes.Buffer)
+	if err := tx.Serialize(buf); err != nil {
+		return transfer.Transaction{}, err
+	}
+	return transfer.Transaction{
+		TxHash: tx.TxHash().String(),
+		Fee:    fee,
+		RawTx:  hex.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// utxoLookup returns the confirmations and the status of the transfer. A
+// transfer that the blockchain no longer knows about is dropped once one of
+// its inputs is unspent again.
+func (wallet *wallet) utxoLookup(blockchainName blockchain.BlockchainName, receipt transfer.TransferReceipt) (transfer.UpdateReceipt, error) {
+	_, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return transfer.UpdateReceipt{}, err
+	}
+	return lookupUTXO(client, receipt)
+}
+
+func lookupUTXO(client btc.Client, receipt transfer.TransferReceipt) (transfer.UpdateReceipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	confirmations, err := client.Confirmations(ctx, receipt.TxHash)
+	if err == btc.ErrTxNotFound {
+		dropped, err := utxoDropped(ctx, client, receipt)
+		if err != nil {
+			return transfer.UpdateReceipt{}, err
+		}
+		return transfer.NewUpdateReceipt(receipt.TxHash, func(receipt *transfer.TransferReceipt) {
+			receipt.Status = transfer.TransferStatusBroadcast
+			if dropped {
+				receipt.Status = transfer.TransferStatusDropped
+			}
+		}), nil
+	}
+	if err != nil {
+		return transfer.UpdateReceipt{}, err
+	}
+
+	return transfer.NewUpdateReceipt(receipt.TxHash, func(receipt *transfer.TransferReceipt) {
+		receipt.Confirmations = confirmations
+		receipt.Status = confirmedStatus(confirmations)
+	}), nil
+}
+
+// utxoDropped returns true if an input of the transfer, which the blockchain
+// does not know about, can be spent again. Inputs that were spent cannot tell
+// a dropped transfer from one that nodes without a transaction index cannot
+// find.
+func utxoDropped(ctx context.Context, client btc.Client, receipt transfer.TransferReceipt) (bool, error) {
+	if receipt.RawTx == "" || time.Since(time.Unix(receipt.Timestamp, 0)) < DropTimeout {
+		return false, nil
+	}
+	tx, err := decodeUTXOTransaction(receipt.RawTx)
+	if err != nil {
+		return false, err
+	}
+	utxos, err := client.UnspentOutputs(ctx, receipt.From)
+	if err != nil {
+		return false, err
+	}
+	for _, txIn := range tx.TxIn {
+		for _, utxo := range utxos {
+			if utxo.TxHash == txIn.PreviousOutPoint.Hash.String() && utxo.Vout == txIn.PreviousOutPoint.Index {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// rebroadcastUTXO publishes the raw transaction again.
+func (wallet *wallet) rebroadcastUTXO(blockchainName blockchain.BlockchainName, rawTx string) error {
+	_, client, err := wallet.utxoClient(blockchainName)
+	if err != nil {
+		return err
+	}
+	return publishUTXO(client, rawTx)
+}
+
+func publishUTXO(client btc.Client, rawTx string) error {
+	tx, err := decodeUTXOTransaction(rawTx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return client.PublishTransaction(ctx, tx)
+}
+
+func decodeUTXOTransaction(rawTx string) (*wire.MsgTx, error) {
+	txBytes, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, btc.NewErrDecodeTransaction(txBytes, err)
+	}
+	return tx, nil
+}
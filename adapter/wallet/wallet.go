@@ -1,23 +1,84 @@
 package wallet
 
 import (
+	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/republicprotocol/beth-go"
-	"github.com/republicprotocol/libbtc-go"
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
+	"github.com/republicprotocol/swapperd/adapter/policy"
 	"github.com/republicprotocol/swapperd/core/transfer"
 	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/sirupsen/logrus"
 )
 
 type Config struct {
-	Mnemonic string           `json:"mnemonic"`
-	Ethereum BlockchainConfig `json:"ethereum"`
-	Bitcoin  BlockchainConfig `json:"bitcoin"`
+	Mnemonic    string           `json:"mnemonic,omitempty"`
+	Ethereum    BlockchainConfig `json:"ethereum"`
+	Bitcoin     BlockchainConfig `json:"bitcoin"`
+	Litecoin    BlockchainConfig `json:"litecoin"`
+	BitcoinCash BlockchainConfig `json:"bitcoinCash"`
+
+	// Tokens are added to, or replace, the default tokens.
+	Tokens []blockchain.TokenConfig `json:"tokens,omitempty"`
+
+	AddressBook AddressBookConfig `json:"addressBook"`
+
+	// Policies limit the transfers and swaps of each account.
+	Policies policy.Config `json:"policies"`
 }
 
 type BlockchainConfig struct {
 	Network Network  `json:"network"`
 	Tokens  []string `json:"tokens"`
+
+	// Allowances are the opt-in standing allowances, in the smallest unit of
+	// each ERC20 token, granted to the Swapperd contracts.
+	Allowances map[string]string `json:"allowances,omitempty"`
+
+	// GasPriceOracle is the source of Ethereum gas prices, one of "node",
+	// "feeHistory" or "static". It defaults to "feeHistory". GasPrice is the
+	// price, in wei, used by the "static" oracle.
+	GasPriceOracle string `json:"gasPriceOracle,omitempty"`
+	GasPrice       string `json:"gasPrice,omitempty"`
+
+	// DerivationPath is the BIP32 path of the account key, such as
+	// "m/84'/0'/0'". Its purpose sets the type of the addresses of UTXO
+	// blockchains. It defaults to the BIP44 path of the blockchain.
+	// LegacyDerivation keeps the non-hardened path used by wallets created
+	// before the standard paths, and overrides the derivation path.
+	DerivationPath   string `json:"derivationPath,omitempty"`
+	LegacyDerivation bool   `json:"legacyDerivation,omitempty"`
+
+	// XPub is the extended public key of the account key, at the derivation
+	// path, which watch-only wallets derive their addresses from.
+	XPub string `json:"xpub,omitempty"`
+}
+
+// WatchOnly returns true if the config has the extended public keys of its
+// blockchains instead of a mnemonic.
+func (config Config) WatchOnly() bool {
+	if config.Mnemonic != "" {
+		return false
+	}
+	for _, blockchainConfig := range []BlockchainConfig{config.Ethereum, config.Bitcoin, config.Litecoin, config.BitcoinCash} {
+		if blockchainConfig.XPub != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// WithLegacyDerivation returns the config with the legacy derivation set, or
+// unset, on every blockchain.
+func (config Config) WithLegacyDerivation(legacy bool) Config {
+	config.Ethereum.LegacyDerivation = legacy
+	config.Bitcoin.LegacyDerivation = legacy
+	config.Litecoin.LegacyDerivation = legacy
+	config.BitcoinCash.LegacyDerivation = legacy
+	return config
 }
 
 type Network struct {
@@ -33,26 +94,191 @@ type Balance struct {
 type Wallet interface {
 	ID(password string) (string, error)
 	SupportedTokens() []blockchain.Token
+	PatchToken(token string) (blockchain.Token, error)
+	TokenConfig(token blockchain.Token) (blockchain.TokenConfig, error)
+	ContractAddresses(token blockchain.Token) (string, string, error)
 	Balances(password string) (map[blockchain.TokenName]blockchain.Balance, error)
-	Lookup(token blockchain.Token, txHash string) (transfer.UpdateReceipt, error)
-	Transfer(password string, token blockchain.Token, to string, amount *big.Int) (string, error)
+	Lookup(receipt transfer.TransferReceipt) (transfer.UpdateReceipt, error)
+	Transfer(password string, token blockchain.Token, to string, amount, fee *big.Int) (transfer.Transaction, error)
+	Rebroadcast(token blockchain.Token, rawTx string) error
+	Cancel(password string, receipt transfer.TransferReceipt) (transfer.Transaction, error)
 	GetAddress(password string, blockchainName blockchain.BlockchainName) (string, error)
+	GetAddressAt(password string, blockchainName blockchain.BlockchainName, index uint32) (string, error)
+	NextAddressIndex() (uint32, error)
 	Addresses(password string) (map[blockchain.TokenName]string, error)
 	VerifyAddress(blockchain blockchain.BlockchainName, address string) error
 	VerifyBalance(password string, token blockchain.Token, balance *big.Int) error
+	VerifyWhitelisted(account string, token blockchain.Token, address string) error
+	AddressBook(account string) ([]AddressBookEntry, error)
+	AddAddressBookEntry(account string, token blockchain.Token, address, label string) (AddressBookEntry, error)
+	DeleteAddressBookEntry(account string, token blockchain.Token, address string) error
+	ReserveSwap(password, id string, token blockchain.Token, amount, fee *big.Int) error
+	ReserveTransfer(password, id string, token blockchain.Token, amount, fee *big.Int) error
+	ReleaseBalance(id string) error
+	ReservedBalance(token blockchain.Token) (*big.Int, error)
 	DefaultFee(blockchainName blockchain.BlockchainName) (*big.Int, error)
+	PriorityFee(blockchainName blockchain.BlockchainName, priority string) (*big.Int, error)
+	VerifyFee(blockchainName blockchain.BlockchainName, fee *big.Int) error
+	TransferFee(token blockchain.Token, fee *big.Int) (*big.Int, error)
+	SwapFee(token blockchain.Token, fee *big.Int) (*big.Int, error)
+	MaxTransferAmount(password string, token blockchain.Token, fee *big.Int) (*big.Int, error)
+	TimeLockBlocks(blockchainName blockchain.BlockchainName, mode string, timelock int64) (int64, error)
+	TimeLockExpiry(blockchainName blockchain.BlockchainName, mode string, timelock, blocks int64) (int64, error)
+	GasPriceOracle() (eth.GasPriceOracle, error)
+	SwapperAddresses() (map[blockchain.TokenName]string, error)
+	VerifyContracts() map[blockchain.TokenName]ContractStatus
+	ContractStatuses() map[blockchain.TokenName]ContractStatus
+	VerifyContract(token blockchain.Token) error
+	StandingAllowance(token blockchain.Token) *big.Int
+	Allowances(password string) (map[blockchain.TokenName]string, error)
+	RevokeAllowance(password string, token blockchain.Token) (string, error)
+	BrokerFees(password string) (map[blockchain.TokenName]BrokerFee, error)
+	WithdrawBrokerFees(password string, token blockchain.Token, amount *big.Int) (transfer.Transaction, *big.Int, error)
+	EthereumURL() string
+	Locked() bool
+	Unlock(passphrase string) error
+	WatchOnly() bool
+	RunNonceManager(done <-chan struct{}, logger logrus.FieldLogger)
 
 	EthereumAccount(password string) (beth.Account, error)
-	BitcoinAccount(password string) (libbtc.Account, error)
+	BitcoinAccount(password string) (btc.Account, error)
+	UTXOAccount(password string, blockchainName blockchain.BlockchainName) (btc.Account, error)
+	UTXOAccountAt(password string, blockchainName blockchain.BlockchainName, index uint32) (btc.Account, error)
 	ECDSASigner(password string) (ECDSASigner, error)
 }
 
 type wallet struct {
 	config Config
+	tokens blockchain.TokenRegistry
+	nonces *nonceManager
+
+	rpcMu      *sync.Mutex
+	rpcClients map[blockchain.BlockchainName]btc.Client
+	oracle     eth.GasPriceOracle
+
+	contractsMu *sync.RWMutex
+	contracts   map[blockchain.TokenName]ContractStatus
+
+	mnemonicMu *sync.RWMutex
+	mnemonic   string
+	unlock     func(passphrase string) (string, error)
+	watchOnly  bool
+
+	indexMu *sync.Mutex
+	indices AddressIndexStorage
+
+	reserveMu    *sync.Mutex
+	reservations ReservationStorage
+
+	balancesMu *sync.Mutex
+	balances   map[string]cachedBalance
+
+	addressBookMu *sync.Mutex
+	addressBook   AddressBookStorage
+}
+
+// New returns a Wallet that allocates address indices, reserves balances and
+// keeps its address book in the storage. When the storage is nil it only uses
+// its main addresses, and keeps its reservations and address book in memory.
+// A config with extended public keys instead of a mnemonic returns a
+// watch-only Wallet.
+func New(config Config, storage Storage) Wallet {
+	return newWallet(config, storage, nil)
 }
 
-func New(config Config) Wallet {
+// NewLocked returns a Wallet whose mnemonic is encrypted. It cannot load any
+// keys until it is unlocked with the passphrase, which the unlock function
+// uses to decrypt the mnemonic.
+func NewLocked(config Config, storage Storage, unlock func(passphrase string) (string, error)) Wallet {
+	config.Mnemonic = ""
+	return newWallet(config, storage, unlock)
+}
+
+func newWallet(config Config, storage Storage, unlock func(passphrase string) (string, error)) *wallet {
+	var indices AddressIndexStorage
+	reservations, addressBook := newMemoryReservations(), newMemoryAddressBook()
+	if storage != nil {
+		indices, reservations, addressBook = storage, storage, storage
+	}
 	return &wallet{
-		config: config,
+		config:       config,
+		tokens:       blockchain.NewTokenRegistry(blockchain.DefaultTokens, config.Tokens),
+		nonces:       newNonceManager(config.Ethereum.Network.URL),
+		rpcMu:        new(sync.Mutex),
+		rpcClients:   map[blockchain.BlockchainName]btc.Client{},
+		contractsMu:  new(sync.RWMutex),
+		contracts:    map[blockchain.TokenName]ContractStatus{},
+		mnemonicMu:   new(sync.RWMutex),
+		mnemonic:     config.Mnemonic,
+		unlock:       unlock,
+		watchOnly:    unlock == nil && config.WatchOnly(),
+		indexMu:      new(sync.Mutex),
+		indices:      indices,
+		reserveMu:    new(sync.Mutex),
+		reservations: reservations,
+		balancesMu:   new(sync.Mutex),
+		balances:     map[string]cachedBalance{},
+
+		addressBookMu: new(sync.Mutex),
+		addressBook:   addressBook,
+	}
+}
+
+// ErrLocked is returned when a key is loaded before the wallet is unlocked.
+var ErrLocked = fmt.Errorf("wallet is locked, bootload with the keystore passphrase to unlock it")
+
+// ErrWatchOnly is returned when a key is loaded by a watch-only wallet.
+var ErrWatchOnly = fmt.Errorf("wallet is watch-only, it has extended public keys instead of a mnemonic and cannot sign transfers, swaps or messages")
+
+// Locked returns true until the mnemonic of the wallet has been decrypted.
+// Watch-only wallets are never locked.
+func (wallet *wallet) Locked() bool {
+	if wallet.watchOnly {
+		return false
+	}
+	wallet.mnemonicMu.RLock()
+	defer wallet.mnemonicMu.RUnlock()
+	return wallet.mnemonic == ""
+}
+
+// WatchOnly returns true if the wallet only has the extended public keys of
+// its blockchains. It can return addresses and balances, and look up
+// transfers, but it cannot sign.
+func (wallet *wallet) WatchOnly() bool {
+	return wallet.watchOnly
+}
+
+// Unlock decrypts the mnemonic of the wallet with the passphrase. Unlocking an
+// unlocked wallet does nothing.
+func (wallet *wallet) Unlock(passphrase string) error {
+	wallet.mnemonicMu.Lock()
+	defer wallet.mnemonicMu.Unlock()
+	if wallet.mnemonic != "" {
+		return nil
 	}
+	if wallet.unlock == nil {
+		return fmt.Errorf("wallet has no mnemonic")
+	}
+	mnemonic, err := wallet.unlock(passphrase)
+	if err != nil {
+		return err
+	}
+	wallet.mnemonic = mnemonic
+	return nil
+}
+
+func (wallet *wallet) loadMnemonic() (string, error) {
+	if wallet.watchOnly {
+		return "", ErrWatchOnly
+	}
+	wallet.mnemonicMu.RLock()
+	defer wallet.mnemonicMu.RUnlock()
+	if wallet.mnemonic == "" {
+		return "", ErrLocked
+	}
+	return wallet.mnemonic, nil
+}
+
+func (wallet *wallet) EthereumURL() string {
+	return wallet.config.Ethereum.Network.URL
 }
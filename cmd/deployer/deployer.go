@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/republicprotocol/swapperd/driver/deployer"
+	"github.com/republicprotocol/swapperd/driver/keystore"
+	"github.com/republicprotocol/swapperd/driver/logger"
+)
+
+func main() {
+	networkFlag := flag.String("network", "localnet", "Network of the keystore to deploy the contracts for")
+	passwordFlag := flag.String("password", "", "Password of the account that deploys the contracts")
+	passphraseFlag := flag.String("passphrase", "", "Passphrase of the keystore")
+	flag.Parse()
+
+	if *networkFlag != "localnet" {
+		fmt.Printf("contracts can only be deployed to localnet, not %s\n", *networkFlag)
+		os.Exit(1)
+	}
+
+	homeDir := getDefaultSwapperHome()
+	if _, err := keystore.Config(homeDir, *networkFlag); err != nil {
+		fmt.Printf("no %s keystore at %s, run the installer with -localnet first\n", *networkFlag, homeDir)
+		os.Exit(1)
+	}
+	if *passphraseFlag == "" {
+		fmt.Println("a -passphrase is required to unlock and save the keystore")
+		os.Exit(1)
+	}
+	config, err := keystore.Unlock(homeDir, *networkFlag, *passphraseFlag)
+	if err != nil {
+		panic(err)
+	}
+
+	config, err = deployer.Deploy(config, *passwordFlag, logger.NewStdOut())
+	if err != nil {
+		panic(err)
+	}
+	if err := keystore.Save(homeDir, *networkFlag, *passphraseFlag, config); err != nil {
+		panic(err)
+	}
+}
+
+func getDefaultSwapperHome() string {
+	unix := os.Getenv("HOME")
+	if unix != "" {
+		return unix + "/.swapperd"
+	}
+
+	windows := os.Getenv("programfiles(x86)")
+	if windows != "" {
+		return windows + "\\Swapperd"
+	}
+	panic("unknown Operating System")
+}
@@ -17,11 +17,21 @@ const bold = "\033[1m"
 
 func main() {
 	mnemonicFlag := flag.String("mnemonic", "", "Mneumonic for restoring an existing account")
+	localnetFlag := flag.Bool("localnet", false, "Also create a keystore for a local regtest and development network")
+	passphraseFlag := flag.String("passphrase", "", "Passphrase that encrypts the keystores, and unlocks them when bootloading")
 	flag.Parse()
 
+	if *passphraseFlag == "" {
+		fmt.Println("a -passphrase is required to encrypt the keystores, it must differ from the swap password")
+		os.Exit(1)
+	}
+
 	if *mnemonicFlag != "" {
-		createKeystore("testnet", *mnemonicFlag)
-		createKeystore("mainnet", *mnemonicFlag)
+		createKeystore("testnet", *passphraseFlag, *mnemonicFlag)
+		createKeystore("mainnet", *passphraseFlag, *mnemonicFlag)
+		if *localnetFlag {
+			createKeystore("localnet", *passphraseFlag, *mnemonicFlag)
+		}
 		return
 	}
 
@@ -34,13 +44,16 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	createKeystore("testnet", mnemonic)
-	createKeystore("mainnet", mnemonic)
+	createKeystore("testnet", *passphraseFlag, mnemonic)
+	createKeystore("mainnet", *passphraseFlag, mnemonic)
+	if *localnetFlag {
+		createKeystore("localnet", *passphraseFlag, mnemonic)
+	}
 }
 
-func createKeystore(network, mnemonic string) {
+func createKeystore(network, passphrase, mnemonic string) {
 	homeDir := getDefaultSwapperHome()
-	if _, err := keystore.Wallet(homeDir, network); err == nil {
+	if _, err := keystore.Config(homeDir, network); err == nil {
 		fmt.Printf("swapper already exists at the default location (%s)\n", getDefaultSwapperHome())
 		return
 	}
@@ -49,7 +62,7 @@ func createKeystore(network, mnemonic string) {
 		panic(err)
 	}
 
-	if err := keystore.Generate(homeDir, network, mnemonic); err != nil {
+	if err := keystore.Generate(homeDir, network, passphrase, mnemonic); err != nil {
 		panic(err)
 	}
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/republicprotocol/swapperd/adapter/db"
+	"github.com/republicprotocol/swapperd/driver/keystore"
+	"github.com/republicprotocol/swapperd/driver/leveldb"
+	"github.com/republicprotocol/swapperd/driver/logger"
+	"github.com/republicprotocol/swapperd/driver/migrator"
+)
+
+func main() {
+	networkFlag := flag.String("network", "mainnet", "Network of the keystore to migrate")
+	passwordFlag := flag.String("password", "", "Password of the wallet")
+	passphraseFlag := flag.String("passphrase", "", "Passphrase of the keystore")
+	moveFlag := flag.Bool("move", false, "Move the balances, instead of only showing them")
+	toFlag := flag.String("to", "standard", "Derivation to move the balances to, standard or legacy")
+	flag.Parse()
+
+	if *toFlag != "standard" && *toFlag != "legacy" {
+		fmt.Printf("cannot move the balances to %s, expected standard or legacy\n", *toFlag)
+		os.Exit(1)
+	}
+
+	homeDir := getDefaultSwapperHome()
+	if _, err := keystore.Config(homeDir, *networkFlag); err != nil {
+		fmt.Printf("no %s keystore at %s\n", *networkFlag, homeDir)
+		os.Exit(1)
+	}
+	if *passphraseFlag == "" {
+		fmt.Println("a -passphrase is required to unlock and save the keystore")
+		os.Exit(1)
+	}
+	config, err := keystore.Unlock(homeDir, *networkFlag, *passphraseFlag)
+	if err != nil {
+		panic(err)
+	}
+
+	// The address indices are needed to find every address of the wallet, and
+	// the database can only be opened while swapperd is stopped
+	ldb, err := leveldb.NewStore(homeDir, *networkFlag)
+	if err != nil {
+		fmt.Printf("failed to open the %s database, stop swapperd before migrating: %v\n", *networkFlag, err)
+		os.Exit(1)
+	}
+	defer ldb.Close()
+	storage := db.New(ldb)
+
+	pairs, err := migrator.Compare(config, storage, *passwordFlag)
+	if err != nil {
+		panic(err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TOKEN\tLEGACY ADDRESS\tLEGACY BALANCE\tSTANDARD ADDRESS\tSTANDARD BALANCE\tERROR")
+	for _, pair := range pairs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", pair.Token, pair.LegacyAddress, pair.LegacyBalance, pair.StandardAddress, pair.StandardBalance, pair.Error)
+	}
+	w.Flush()
+
+	if !*moveFlag {
+		return
+	}
+	config, err = migrator.Move(config, storage, *passwordFlag, *toFlag == "standard", logger.NewStdOut())
+	if _, ok := err.(migrator.ErrUnsettled); ok {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err != nil {
+		panic(err)
+	}
+	if err := keystore.Save(homeDir, *networkFlag, *passphraseFlag, config); err != nil {
+		panic(err)
+	}
+	fmt.Printf("the %s keystore now uses the %s derivation\n", *networkFlag, *toFlag)
+}
+
+func getDefaultSwapperHome() string {
+	unix := os.Getenv("HOME")
+	if unix != "" {
+		return unix + "/.swapperd"
+	}
+
+	windows := os.Getenv("programfiles(x86)")
+	if windows != "" {
+		return windows + "\\Swapperd"
+	}
+	panic("unknown Operating System")
+}
@@ -5,6 +5,7 @@ import (
 	"os/signal"
 
 	"github.com/republicprotocol/swapperd/driver/composer"
+	"github.com/republicprotocol/swapperd/driver/keystore"
 )
 
 func main() {
@@ -16,6 +17,12 @@ func main() {
 	mainnet := composer.New(homeDir, "mainnet", "7927")
 	go mainnet.Run(done)
 
+	// The localnet only runs when its keystore has been created
+	if _, err := keystore.Config(homeDir, "localnet"); err == nil {
+		localnet := composer.New(homeDir, "localnet", "37927")
+		go localnet.Run(done)
+	}
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	<-c
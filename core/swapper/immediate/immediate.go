@@ -12,6 +12,24 @@ import (
 var ErrSwapExpired = fmt.Errorf("swap expired")
 var ErrAuditPending = fmt.Errorf("audit pending")
 
+// ErrUnsafeTimeLock is returned by the Audit of the initiator's contract when
+// it expires too soon after the responder's contract would, if the responder
+// funded it now. The responder never funds its contract then, but finishes a
+// contract that it has already funded.
+var ErrUnsafeTimeLock = fmt.Errorf("timelock of the initiator expires too soon")
+
+// ErrAuditMismatch is returned by a Contract when the counterparty has
+// initiated a swap with parameters that differ from the agreed ones.
+type ErrAuditMismatch string
+
+func NewErrAuditMismatch(field string, expected, actual interface{}) error {
+	return ErrAuditMismatch(fmt.Sprintf("audit mismatch on %s: expected %v, got %v", field, expected, actual))
+}
+
+func (err ErrAuditMismatch) Error() string {
+	return string(err)
+}
+
 type Contract interface {
 	Initiate() error
 	Audit() error
@@ -21,6 +39,20 @@ type Contract interface {
 	Cost() blockchain.Cost
 }
 
+// A PresignedRefundContract signs its refund transaction when it is funded,
+// so that the refund can be published by anyone once the swap expires. The
+// error is returned when the refund could not be signed.
+type PresignedRefundContract interface {
+	PresignedRefund() (string, error)
+}
+
+// A FundedContract reports the outputs that fund its HTLC on a UTXO
+// blockchain, and whether they differ from the agreed funding.
+type FundedContract interface {
+	FundingOutputs() []swap.Output
+	FundingFlags() swap.FundingFlags
+}
+
 type ContractBuilder interface {
 	BuildSwapContracts(request SwapRequest) (Contract, Contract, error)
 }
@@ -68,14 +100,25 @@ func (swapper *swapper) handleSwap(req SwapRequest) tau.Message {
 }
 
 func (swapper *swapper) initiate(req SwapRequest, native, foreign Contract) tau.Message {
-	secret := sha3.Sum256(append([]byte(req.Blob.Password), []byte(req.Blob.ID)...))
 	if err := native.Initiate(); err != nil {
 		return swapper.handleResult(req, swap.Inactive, native, foreign, err, false)
 	}
+	return tau.NewMessageBatch([]tau.Message{Funded{req.Blob.ID}, swapper.initiated(req, native, foreign)})
+}
+
+// initiated continues the swap of the initiator once the native contract is
+// funded.
+func (swapper *swapper) initiated(req SwapRequest, native, foreign Contract) tau.Message {
+	secret := sha3.Sum256(append([]byte(req.Blob.Password), []byte(req.Blob.ID)...))
 	if err := foreign.Audit(); err != nil {
 		if err == ErrAuditPending {
 			return swapper.handleResult(req, swap.AuditPending, native, foreign, nil, false)
 		}
+		if _, ok := err.(ErrAuditMismatch); ok {
+			// Never redeem a mismatched swap, wait for the native swap to
+			// expire and refund it.
+			return swapper.handleResult(req, swap.AuditMismatch, native, foreign, err, false)
+		}
 		if err != ErrSwapExpired {
 			return swapper.handleResult(req, swap.AuditPending, native, foreign, err, false)
 		}
@@ -98,12 +141,28 @@ func (swapper *swapper) respond(req SwapRequest, native, foreign Contract) tau.M
 		if err == ErrSwapExpired {
 			return swapper.handleResult(req, swap.AuditFailed, native, foreign, err, true)
 		}
+		if _, ok := err.(ErrAuditMismatch); ok {
+			return swapper.handleResult(req, swap.AuditMismatch, native, foreign, err, true)
+		}
+		if err == ErrUnsafeTimeLock {
+			if !req.Funded {
+				return swapper.handleResult(req, swap.AuditFailed, native, foreign, err, true)
+			}
+			return tau.NewMessageBatch([]tau.Message{tau.NewError(err), swapper.responded(req, native, foreign)})
+		}
 		return swapper.handleResult(req, swap.AuditPending, native, foreign, err, false)
 	}
 
 	if err := native.Initiate(); err != nil {
 		return swapper.handleResult(req, swap.Audited, native, foreign, err, false)
 	}
+	req.Funded = true
+	return tau.NewMessageBatch([]tau.Message{Funded{req.Blob.ID}, swapper.responded(req, native, foreign)})
+}
+
+// responded continues the swap of the responder once the native contract is
+// funded.
+func (swapper *swapper) responded(req SwapRequest, native, foreign Contract) tau.Message {
 	secret, err := native.AuditSecret()
 	if err != nil {
 		if err == ErrAuditPending {
@@ -137,20 +196,25 @@ func (swapper *swapper) handleResult(req SwapRequest, status int, native, foreig
 	return tau.NewMessageBatch(messages)
 }
 
+// A SwapRequest is a swap to be executed. Funded is set once the native
+// contract of the swap has been funded, so that a responder whose audit fails
+// after funding still finishes its swap.
 type SwapRequest struct {
 	Blob        swap.SwapBlob
 	SendCost    blockchain.Cost
 	ReceiveCost blockchain.Cost
+	Funded      bool
 }
 
 func (msg SwapRequest) IsMessage() {
 }
 
-func NewSwapRequest(blob swap.SwapBlob, sendCost, receiveCost blockchain.Cost) SwapRequest {
+func NewSwapRequest(blob swap.SwapBlob, sendCost, receiveCost blockchain.Cost, funded bool) SwapRequest {
 	return SwapRequest{
 		Blob:        blob,
 		SendCost:    sendCost,
 		ReceiveCost: receiveCost,
+		Funded:      funded,
 	}
 }
 
@@ -160,13 +224,53 @@ func (msg ReceiptUpdate) IsMessage() {
 }
 
 func NewReceiptUpdate(id swap.SwapID, status int, native, foreign Contract) ReceiptUpdate {
+	refundTx, refundErr := "", error(nil)
+	if contract, ok := native.(PresignedRefundContract); ok {
+		refundTx, refundErr = contract.PresignedRefund()
+	}
+	sendOutputs, sendFunding := fundingOutputs(native)
+	receiveOutputs, receiveFunding := fundingOutputs(foreign)
 	return ReceiptUpdate(swap.NewReceiptUpdate(id, func(receipt *swap.SwapReceipt) {
 		receipt.Status = status
 		receipt.SendCost = blockchain.CostToCostBlob(native.Cost())
 		receipt.ReceiveCost = blockchain.CostToCostBlob(foreign.Cost())
+		if refundTx != "" {
+			receipt.RefundTx = refundTx
+			receipt.RefundTxError = ""
+		} else if refundErr != nil {
+			receipt.RefundTxError = refundErr.Error()
+		}
+		if len(sendOutputs) > 0 {
+			receipt.SendOutputs = sendOutputs
+		}
+		if len(receiveOutputs) > 0 {
+			receipt.ReceiveOutputs = receiveOutputs
+		}
+		if !sendFunding.IsZero() {
+			receipt.SendFunding = &sendFunding
+		}
+		if !receiveFunding.IsZero() {
+			receipt.ReceiveFunding = &receiveFunding
+		}
 	}))
 }
 
+func fundingOutputs(contract Contract) ([]swap.Output, swap.FundingFlags) {
+	if contract, ok := contract.(FundedContract); ok {
+		return contract.FundingOutputs(), contract.FundingFlags()
+	}
+	return nil, swap.FundingFlags{}
+}
+
+// Funded is sent once the native contract of the swap is funded, so that the
+// balance reserved for the swap can be released.
+type Funded struct {
+	ID swap.SwapID
+}
+
+func (msg Funded) IsMessage() {
+}
+
 type DeleteSwap struct {
 	ID swap.SwapID
 }
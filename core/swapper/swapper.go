@@ -22,6 +22,9 @@ type Storage interface {
 	UpdateReceipt(receiptUpdate swap.ReceiptUpdate) error
 	PutSwap(blob swap.SwapBlob) error
 	PendingSwaps() ([]swap.SwapBlob, error)
+	Reserved(id string) (bool, error)
+	DeleteReservation(id string) error
+	DeleteSpend(id string) error
 }
 
 type core struct {
@@ -46,6 +49,8 @@ func (core *core) Reduce(msg tau.Message) tau.Message {
 		return core.handleSwapRequest(msg)
 	case immediate.ReceiptUpdate:
 		return core.handleReceiptUpdate(swap.ReceiptUpdate(msg))
+	case immediate.Funded:
+		return core.handleFunded(msg.ID)
 	case immediate.DeleteSwap:
 		return core.handleDeleteSwap(msg.ID)
 	case delayed.SwapRequest:
@@ -102,7 +107,11 @@ func (core *core) handleSwapRequest(msg SwapRequest) tau.Message {
 	}
 
 	sendCost, receiveCost := core.storage.LoadCosts(msg.ID)
-	core.immediateSwapper.Send(immediate.NewSwapRequest(swap.SwapBlob(msg), sendCost, receiveCost))
+	funded, err := core.funded(msg.ID)
+	if err != nil {
+		return tau.NewError(err)
+	}
+	core.immediateSwapper.Send(immediate.NewSwapRequest(swap.SwapBlob(msg), sendCost, receiveCost, funded))
 	return nil
 }
 
@@ -151,17 +160,52 @@ func (core *core) handleSwapperBootload(msg Bootload) tau.Message {
 		}
 
 		sendCost, receiveCost := core.storage.LoadCosts(pendingSwap.ID)
-		core.immediateSwapper.Send(immediate.NewSwapRequest(pendingSwap, sendCost, receiveCost))
+		funded, err := core.funded(pendingSwap.ID)
+		if err != nil {
+			return tau.NewError(err)
+		}
+		core.immediateSwapper.Send(immediate.NewSwapRequest(pendingSwap, sendCost, receiveCost, funded))
 	}
 
 	return nil
 }
 
+// funded returns true if the native contract of the swap has been funded. The
+// reservation of a swap is only released once it is funded.
+func (core *core) funded(id swap.SwapID) (bool, error) {
+	reserved, err := core.storage.Reserved(string(id))
+	if err != nil {
+		return false, err
+	}
+	return !reserved, nil
+}
+
+// handleFunded releases the balance reserved for the swap, since the funds
+// have left the wallet.
+func (core *core) handleFunded(id swap.SwapID) tau.Message {
+	if err := core.storage.DeleteReservation(string(id)); err != nil {
+		return tau.NewError(err)
+	}
+	return nil
+}
+
+// handleDeleteSwap deletes the finished swap, and releases its reservation.
+// The reservation of a swap is released once it is funded, so a swap that
+// still has one was never funded and its policy spend is revoked as well.
 func (core *core) handleDeleteSwap(id swap.SwapID) tau.Message {
 	if err := core.storage.DeletePendingSwap(id); err != nil {
 		return tau.NewError(err)
 	}
-	return nil
+	reserved, err := core.storage.Reserved(string(id))
+	if err != nil {
+		return tau.NewError(err)
+	}
+	if reserved {
+		if err := core.storage.DeleteSpend(string(id)); err != nil {
+			return tau.NewError(err)
+		}
+	}
+	return core.handleFunded(id)
 }
 
 type SwapRequest swap.SwapBlob
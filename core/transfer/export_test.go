@@ -0,0 +1,14 @@
+package transfer
+
+import (
+	"sync"
+
+	"github.com/republicprotocol/tau"
+	"github.com/sirupsen/logrus"
+)
+
+// NewReducer returns the reducer of the transfers task, so that its messages
+// can be reduced without running the task.
+func NewReducer(bc Blockchain, storage Storage, logger logrus.FieldLogger) tau.Reducer {
+	return &transfers{new(sync.RWMutex), TransferReceiptMap{}, logger, bc, storage}
+}
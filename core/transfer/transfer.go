@@ -13,15 +13,31 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Storage persists the transfers. DeleteSpend revokes the policy spend of a
+// transfer whose funds were never sent.
 type Storage interface {
 	PutTransfer(receipt TransferReceipt) error
 	Transfers() ([]TransferReceipt, error)
+	DeleteSpend(id string) error
 }
 
 type Blockchain interface {
 	GetAddress(password string, blockchainName blockchain.BlockchainName) (string, error)
-	Transfer(password string, token blockchain.Token, to string, amount *big.Int) (string, error)
-	Lookup(token blockchain.Token, txHash string) (UpdateReceipt, error)
+	Transfer(password string, token blockchain.Token, to string, amount, fee *big.Int) (Transaction, error)
+	WithdrawBrokerFees(password string, token blockchain.Token, amount *big.Int) (Transaction, *big.Int, error)
+	ContractAddresses(token blockchain.Token) (string, string, error)
+	Lookup(receipt TransferReceipt) (UpdateReceipt, error)
+	Rebroadcast(token blockchain.Token, rawTx string) error
+	Cancel(password string, receipt TransferReceipt) (Transaction, error)
+	ReleaseBalance(id string) error
+}
+
+// A Transaction is a transaction that has been broadcast, and the fee that it
+// pays. The raw transaction is kept so that it can be rebroadcast.
+type Transaction struct {
+	TxHash string
+	Fee    *big.Int
+	RawTx  string
 }
 
 type transfers struct {
@@ -44,6 +60,12 @@ func (transfers *transfers) Reduce(msg tau.Message) tau.Message {
 		return transfers.handleTransferReceiptRequest(msg)
 	case TransferRequest:
 		return transfers.handleTransferRequest(msg)
+	case WithdrawRequest:
+		return transfers.handleWithdrawRequest(msg)
+	case RebroadcastRequest:
+		return transfers.handleRebroadcastRequest(msg)
+	case CancelRequest:
+		return transfers.handleCancelRequest(msg)
 	case tau.Tick:
 		return transfers.handleTick()
 	default:
@@ -76,13 +98,26 @@ func (transfers *transfers) handleTransferReceiptRequest(msg TransferReceiptRequ
 func (transfers *transfers) handleTransferRequest(msg TransferRequest) tau.Message {
 	from, err := transfers.blockchain.GetAddress(msg.Password, msg.Token.Blockchain)
 	if err != nil {
+		transfers.abort(msg.ReservationID)
 		return tau.NewError(err)
 	}
-	txHash, err := transfers.blockchain.Transfer(msg.Password, msg.Token, msg.To, msg.Amount)
+	tx, err := transfers.blockchain.Transfer(msg.Password, msg.Token, msg.To, msg.Amount, msg.Fee)
 	if err != nil {
+		transfers.abort(msg.ReservationID)
 		return tau.NewError(err)
 	}
-	receipt := buildReceipt(msg, from, txHash)
+	msg.Fee = tx.Fee
+	receipt := buildReceipt(msg, from, tx.TxHash)
+
+	// The balances of UTXO blockchains no longer include the outputs spent by
+	// a broadcast transaction, so only Ethereum transfers stay reserved until
+	// they are confirmed
+	if msg.Token.Blockchain == blockchain.Ethereum {
+		receipt.ReservationID = msg.ReservationID
+	} else {
+		transfers.release(msg.ReservationID)
+	}
+	receipt.RawTx = tx.RawTx
 	transfers.write(receipt)
 	msg.Responder <- receipt
 	if err := transfers.storage.PutTransfer(receipt); err != nil {
@@ -91,15 +126,157 @@ func (transfers *transfers) handleTransferRequest(msg TransferRequest) tau.Messa
 	return nil
 }
 
+// handleWithdrawRequest withdraws the broker fees, and records the withdrawal
+// as a transfer from the Swapperd contract to the wallet. Errors are sent to
+// the responder, so that they can be returned to the broker.
+func (transfers *transfers) handleWithdrawRequest(msg WithdrawRequest) tau.Message {
+	receipt, err := transfers.withdraw(msg)
+	msg.Responder <- WithdrawResponse{receipt, err}
+	if err != nil {
+		return tau.NewError(err)
+	}
+	transfers.write(receipt)
+	if err := transfers.storage.PutTransfer(receipt); err != nil {
+		return tau.NewError(err)
+	}
+	return nil
+}
+
+func (transfers *transfers) withdraw(msg WithdrawRequest) (TransferReceipt, error) {
+	to, err := transfers.blockchain.GetAddress(msg.Password, msg.Token.Blockchain)
+	if err != nil {
+		return TransferReceipt{}, err
+	}
+	_, from, err := transfers.blockchain.ContractAddresses(msg.Token)
+	if err != nil {
+		return TransferReceipt{}, err
+	}
+	tx, amount, err := transfers.blockchain.WithdrawBrokerFees(msg.Password, msg.Token, msg.Amount)
+	if err != nil {
+		return TransferReceipt{}, err
+	}
+	receipt := buildReceipt(NewTransferRequest(msg.Password, msg.Token, to, amount, tx.Fee, nil), from, tx.TxHash)
+	receipt.Type = TransferTypeBrokerWithdrawal
+	receipt.RawTx = tx.RawTx
+	return receipt, nil
+}
+
+// handleRebroadcastRequest broadcasts a pending, or dropped, transfer again.
+// Errors are sent to the responder, so that they can be returned to the user.
+func (transfers *transfers) handleRebroadcastRequest(msg RebroadcastRequest) tau.Message {
+	receipt, err := transfers.rebroadcast(msg)
+	msg.Responder <- TransferResponse{receipt, err}
+	if err != nil {
+		return tau.NewError(err)
+	}
+	return nil
+}
+
+func (transfers *transfers) rebroadcast(msg RebroadcastRequest) (TransferReceipt, error) {
+	receipt, err := transfers.pending(msg.Password, msg.TxHash)
+	if err != nil {
+		return TransferReceipt{}, err
+	}
+	if receipt.RawTx == "" {
+		return TransferReceipt{}, fmt.Errorf("transfer %s cannot be rebroadcast", msg.TxHash)
+	}
+	if err := transfers.blockchain.Rebroadcast(receipt.Token, receipt.RawTx); err != nil {
+		return TransferReceipt{}, err
+	}
+	receipt.Status = TransferStatusBroadcast
+	receipt.Timestamp = time.Now().Unix()
+	transfers.write(receipt)
+	return receipt, transfers.storage.PutTransfer(receipt)
+}
+
+// handleCancelRequest replaces a pending, or dropped, transfer with a
+// transaction that pays a higher fee and sends nothing. Either transaction can
+// still be mined, so the transfer stays broadcast, and keeps its reservation,
+// until the blockchain reports which one used the nonce. Errors are sent to
+// the responder, so that they can be returned to the user.
+func (transfers *transfers) handleCancelRequest(msg CancelRequest) tau.Message {
+	cancellation, err := transfers.cancel(msg)
+	msg.Responder <- TransferResponse{cancellation, err}
+	if err != nil {
+		return tau.NewError(err)
+	}
+	return nil
+}
+
+func (transfers *transfers) cancel(msg CancelRequest) (TransferReceipt, error) {
+	receipt, err := transfers.pending(msg.Password, msg.TxHash)
+	if err != nil {
+		return TransferReceipt{}, err
+	}
+	tx, err := transfers.blockchain.Cancel(msg.Password, receipt)
+	if err != nil {
+		return TransferReceipt{}, err
+	}
+
+	cancellation := buildReceipt(NewTransferRequest(msg.Password, blockchain.TokenETH, receipt.From, big.NewInt(0), tx.Fee, nil), receipt.From, tx.TxHash)
+	cancellation.Type = TransferTypeCancellation
+	cancellation.RawTx = tx.RawTx
+	receipt.Status = TransferStatusBroadcast
+	receipt.ReplacedBy = tx.TxHash
+
+	transfers.write(receipt)
+	transfers.write(cancellation)
+	if err := transfers.storage.PutTransfer(receipt); err != nil {
+		return cancellation, err
+	}
+	return cancellation, transfers.storage.PutTransfer(cancellation)
+}
+
+// pending returns the receipt of the transfer, once it has checked that the
+// password sent it, that it has not been confirmed and that it is not being
+// replaced.
+func (transfers *transfers) pending(password, txHash string) (TransferReceipt, error) {
+	receipt, ok := transfers.read()[txHash]
+	if !ok || !receipt.sentBy(password) {
+		return TransferReceipt{}, fmt.Errorf("transfer %s not found", txHash)
+	}
+	if receipt.Status != TransferStatusBroadcast && receipt.Status != TransferStatusDropped {
+		return TransferReceipt{}, fmt.Errorf("transfer %s is %s, only %s or %s transfers can be changed", txHash, receipt.Status, TransferStatusBroadcast, TransferStatusDropped)
+	}
+	if receipt.ReplacedBy != "" {
+		return TransferReceipt{}, fmt.Errorf("transfer %s is being replaced by %s", txHash, receipt.ReplacedBy)
+	}
+	return receipt, nil
+}
+
+// update looks up the transfers that can still change, and persists the ones
+// whose status changed. The reservation of a transfer is released once its
+// funds can no longer leave the wallet, and the policy spend of a transfer
+// that settled without being confirmed is revoked with it. A dropped Ethereum transfer keeps its
+// reservation, since it can still be mined until its nonce is used.
 func (transfers *transfers) update() {
 	updatedTransferMap := TransferReceiptMap{}
 	for txHash, receipt := range transfers.transferMap {
-		update, err := transfers.blockchain.Lookup(receipt.Token, txHash)
+		if receipt.Status == TransferStatusReverted || receipt.Status == TransferStatusReplaced {
+			updatedTransferMap[txHash] = receipt
+			continue
+		}
+		update, err := transfers.blockchain.Lookup(receipt)
 		if err != nil {
 			transfers.logger.Error(err)
+			updatedTransferMap[txHash] = receipt
 			continue
 		}
+		status := receipt.Status
 		update.Update(&receipt)
+		if receipt.ReservationID != "" && receipt.settled() {
+			if receipt.Status == TransferStatusConfirmed {
+				transfers.release(receipt.ReservationID)
+			} else {
+				transfers.abort(receipt.ReservationID)
+			}
+			receipt.ReservationID = ""
+		}
+		if receipt.Status != status {
+			if err := transfers.storage.PutTransfer(receipt); err != nil {
+				transfers.logger.Error(err)
+			}
+		}
 		updatedTransferMap[txHash] = receipt
 	}
 	transfers.mu.Lock()
@@ -107,6 +284,29 @@ func (transfers *transfers) update() {
 	transfers.transferMap = updatedTransferMap
 }
 
+// release releases the balance reserved for a transfer. Transfers without a
+// reservation have an empty id.
+func (transfers *transfers) release(id string) {
+	if id == "" {
+		return
+	}
+	if err := transfers.blockchain.ReleaseBalance(id); err != nil {
+		transfers.logger.Error(err)
+	}
+}
+
+// abort releases the balance reserved for a transfer whose funds were never
+// sent, and revokes its policy spend, which has the same id.
+func (transfers *transfers) abort(id string) {
+	if id == "" {
+		return
+	}
+	transfers.release(id)
+	if err := transfers.storage.DeleteSpend(id); err != nil {
+		transfers.logger.Error(err)
+	}
+}
+
 func (transfers *transfers) write(receipt TransferReceipt) {
 	transfers.mu.Lock()
 	defer transfers.mu.Unlock()
@@ -122,6 +322,7 @@ func (transfers *transfers) read() TransferReceiptMap {
 func buildReceipt(req TransferRequest, from, txHash string) TransferReceipt {
 	passwordHash, _ := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	return TransferReceipt{
+		Status:        TransferStatusBroadcast,
 		Confirmations: 0,
 		Timestamp:     time.Now().Unix(),
 		PasswordHash:  base64.StdEncoding.EncodeToString(passwordHash),
@@ -143,28 +344,150 @@ type TransferRequest struct {
 	Amount   *big.Int
 	Fee      *big.Int
 
+	// ReservationID is the id of the balance reserved for the transfer, which
+	// is released once the transfer is confirmed.
+	ReservationID string
+
 	Responder chan<- TransferReceipt
 }
 
 func NewTransferRequest(password string, token blockchain.Token, to string, amount, fee *big.Int, responder chan<- TransferReceipt) TransferRequest {
-	return TransferRequest{password, token, to, amount, fee, responder}
+	return TransferRequest{password, token, to, amount, fee, "", responder}
 }
 
 func (request TransferRequest) IsMessage() {
 }
 
+// A WithdrawRequest withdraws the broker fees accrued in the Swapperd contract
+// of the token. All the fees are withdrawn when the amount is nil.
+type WithdrawRequest struct {
+	Password string
+	Token    blockchain.Token
+	Amount   *big.Int
+
+	Responder chan<- WithdrawResponse
+}
+
+func NewWithdrawRequest(password string, token blockchain.Token, amount *big.Int, responder chan<- WithdrawResponse) WithdrawRequest {
+	return WithdrawRequest{password, token, amount, responder}
+}
+
+func (request WithdrawRequest) IsMessage() {
+}
+
+// A WithdrawResponse is the receipt of the withdrawal, or the error that
+// stopped it.
+type WithdrawResponse struct {
+	Receipt TransferReceipt
+	Err     error
+}
+
+// A RebroadcastRequest broadcasts the raw transaction of a pending, or
+// dropped, transfer again.
+type RebroadcastRequest struct {
+	Password string
+	TxHash   string
+
+	Responder chan<- TransferResponse
+}
+
+func NewRebroadcastRequest(password, txHash string, responder chan<- TransferResponse) RebroadcastRequest {
+	return RebroadcastRequest{password, txHash, responder}
+}
+
+func (request RebroadcastRequest) IsMessage() {
+}
+
+// A CancelRequest replaces a pending, or dropped, transfer with a transaction
+// that sends nothing.
+type CancelRequest struct {
+	Password string
+	TxHash   string
+
+	Responder chan<- TransferResponse
+}
+
+func NewCancelRequest(password, txHash string, responder chan<- TransferResponse) CancelRequest {
+	return CancelRequest{password, txHash, responder}
+}
+
+func (request CancelRequest) IsMessage() {
+}
+
+// A TransferResponse is the receipt of a rebroadcast or cancelled transfer,
+// or the error that stopped it.
+type TransferResponse struct {
+	Receipt TransferReceipt
+	Err     error
+}
+
 type TransferReceiptMap map[string]TransferReceipt
 
 func (request TransferReceiptMap) IsMessage() {
 }
 
+// The types of transfers. Plain transfers have no type.
+const (
+	TransferTypeBrokerWithdrawal = "brokerWithdrawal"
+	TransferTypeCancellation     = "cancellation"
+)
+
+// The statuses of a transfer. A broadcast transfer has not been confirmed
+// yet. A dropped transfer is no longer known to the blockchain, but can still
+// be rebroadcast. Reverted and replaced transfers are final, and their funds
+// were not sent.
+const (
+	TransferStatusBroadcast = "broadcast"
+	TransferStatusConfirmed = "confirmed"
+	TransferStatusReverted  = "reverted"
+	TransferStatusDropped   = "dropped"
+	TransferStatusReplaced  = "replaced"
+)
+
 type TransferReceipt struct {
+	Status        string `json:"status"`
 	Confirmations int64  `json:"confirmations"`
 	Timestamp     int64  `json:"timestamp"`
 	PasswordHash  string `json:"passwordHash,omitempty"`
+	Type          string `json:"type,omitempty"`
+	ReservationID string `json:"reservationId,omitempty"`
+
+	// RawTx is the hex encoded signed transaction, which is rebroadcast if it
+	// is dropped. ReplacedBy is the hash of the transaction that replaces it,
+	// the transfer is only replaced once that transaction uses its nonce.
+	RawTx      string `json:"rawTx,omitempty"`
+	ReplacedBy string `json:"replacedBy,omitempty"`
 	TokenDetails
 }
 
+// settled returns true once the transfer can no longer send more funds than
+// it already has. Confirmed, reverted and replaced transfers are final. The
+// inputs of a dropped UTXO transfer can be spent again, but a dropped Ethereum
+// transfer can still be mined until its nonce is used by another transaction.
+func (receipt TransferReceipt) settled() bool {
+	switch receipt.Status {
+	case TransferStatusConfirmed, TransferStatusReverted, TransferStatusReplaced:
+		return true
+	case TransferStatusDropped:
+		return receipt.Token.Blockchain != blockchain.Ethereum
+	default:
+		return false
+	}
+}
+
+// sentBy returns true if the transfer was sent with the password. Transfers
+// without a password hash can be read, and changed, with any password.
+func (receipt TransferReceipt) sentBy(password string) bool {
+	if receipt.PasswordHash == "" {
+		return true
+	}
+	passwordHash, err := base64.StdEncoding.DecodeString(receipt.PasswordHash)
+	if err != nil {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(passwordHash, []byte(password)) == nil
+}
+
 type TokenDetails struct {
 	To     string           `json:"to"`
 	From   string           `json:"from"`
@@ -0,0 +1,274 @@
+package transfer_test
+
+import (
+	"fmt"
+	"math/big"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/core/transfer"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/republicprotocol/tau"
+	"github.com/sirupsen/logrus"
+)
+
+// mockBlockchain broadcasts transactions with increasing hashes, and looks
+// them up with the statuses set by the tests.
+type mockBlockchain struct {
+	sent     int
+	statuses map[string]string
+	released []string
+}
+
+func newMockBlockchain() *mockBlockchain {
+	return &mockBlockchain{statuses: map[string]string{}}
+}
+
+func (bc *mockBlockchain) nextTransaction() Transaction {
+	bc.sent++
+	return Transaction{TxHash: fmt.Sprintf("tx%d", bc.sent), Fee: big.NewInt(1000), RawTx: fmt.Sprintf("raw%d", bc.sent)}
+}
+
+func (bc *mockBlockchain) GetAddress(password string, blockchainName blockchain.BlockchainName) (string, error) {
+	if password != "password" {
+		return "", fmt.Errorf("wrong password")
+	}
+	return "from", nil
+}
+
+func (bc *mockBlockchain) Transfer(password string, token blockchain.Token, to string, amount, fee *big.Int) (Transaction, error) {
+	return bc.nextTransaction(), nil
+}
+
+func (bc *mockBlockchain) WithdrawBrokerFees(password string, token blockchain.Token, amount *big.Int) (Transaction, *big.Int, error) {
+	return bc.nextTransaction(), amount, nil
+}
+
+func (bc *mockBlockchain) ContractAddresses(token blockchain.Token) (string, string, error) {
+	return "swapper", "swapper", nil
+}
+
+func (bc *mockBlockchain) Lookup(receipt TransferReceipt) (UpdateReceipt, error) {
+	status, ok := bc.statuses[receipt.TxHash]
+	return NewUpdateReceipt(receipt.TxHash, func(receipt *TransferReceipt) {
+		if ok {
+			receipt.Status = status
+		}
+	}), nil
+}
+
+func (bc *mockBlockchain) Rebroadcast(token blockchain.Token, rawTx string) error {
+	return nil
+}
+
+func (bc *mockBlockchain) Cancel(password string, receipt TransferReceipt) (Transaction, error) {
+	return bc.nextTransaction(), nil
+}
+
+func (bc *mockBlockchain) ReleaseBalance(id string) error {
+	bc.released = append(bc.released, id)
+	return nil
+}
+
+type mockStorage struct {
+	receipts map[string]TransferReceipt
+	revoked  []string
+}
+
+func (storage *mockStorage) PutTransfer(receipt TransferReceipt) error {
+	storage.receipts[receipt.TxHash] = receipt
+	return nil
+}
+
+func (storage *mockStorage) Transfers() ([]TransferReceipt, error) {
+	receipts := []TransferReceipt{}
+	for _, receipt := range storage.receipts {
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+func (storage *mockStorage) DeleteSpend(id string) error {
+	storage.revoked = append(storage.revoked, id)
+	return nil
+}
+
+var _ = Describe("Transfers", func() {
+	var bc *mockBlockchain
+	var storage *mockStorage
+	var transfers tau.Reducer
+
+	BeforeEach(func() {
+		bc = newMockBlockchain()
+		storage = &mockStorage{receipts: map[string]TransferReceipt{}}
+		transfers = NewReducer(bc, storage, logrus.StandardLogger())
+	})
+
+	send := func(token blockchain.Token, reservationID string) TransferReceipt {
+		responder := make(chan TransferReceipt, 1)
+		req := NewTransferRequest("password", token, "to", big.NewInt(50000), nil, responder)
+		req.ReservationID = reservationID
+		Expect(transfers.Reduce(req)).Should(BeNil())
+		return <-responder
+	}
+	receipts := func() TransferReceiptMap {
+		responder := make(chan TransferReceiptMap, 1)
+		transfers.Reduce(TransferReceiptRequest{Responder: responder})
+		return <-responder
+	}
+	tick := func() {
+		transfers.Reduce(tau.Tick{})
+	}
+	cancel := func(txHash string) TransferResponse {
+		responder := make(chan TransferResponse, 1)
+		transfers.Reduce(NewCancelRequest("password", txHash, responder))
+		return <-responder
+	}
+
+	Context("when an Ethereum transfer is broadcast", func() {
+		It("should keep the reservation until the transfer is confirmed", func() {
+			receipt := send(blockchain.TokenETH, "transfer")
+			Expect(receipt.Status).Should(Equal(TransferStatusBroadcast))
+			Expect(receipt.ReservationID).Should(Equal("transfer"))
+			tick()
+			Expect(bc.released).Should(BeEmpty())
+
+			bc.statuses[receipt.TxHash] = TransferStatusConfirmed
+			tick()
+			Expect(bc.released).Should(ConsistOf("transfer"))
+			Expect(receipts()[receipt.TxHash].Status).Should(Equal(TransferStatusConfirmed))
+			Expect(receipts()[receipt.TxHash].ReservationID).Should(BeEmpty())
+			Expect(storage.receipts[receipt.TxHash].Status).Should(Equal(TransferStatusConfirmed))
+			Expect(storage.revoked).Should(BeEmpty())
+		})
+
+		It("should keep the reservation of a dropped transfer until its nonce is used", func() {
+			receipt := send(blockchain.TokenETH, "transfer")
+			bc.statuses[receipt.TxHash] = TransferStatusDropped
+			tick()
+			Expect(receipts()[receipt.TxHash].Status).Should(Equal(TransferStatusDropped))
+			Expect(bc.released).Should(BeEmpty())
+
+			bc.statuses[receipt.TxHash] = TransferStatusReplaced
+			tick()
+			Expect(receipts()[receipt.TxHash].Status).Should(Equal(TransferStatusReplaced))
+			Expect(bc.released).Should(ConsistOf("transfer"))
+			Expect(storage.revoked).Should(ConsistOf("transfer"))
+		})
+
+		It("should release the reservation of a reverted transfer", func() {
+			receipt := send(blockchain.TokenETH, "transfer")
+			bc.statuses[receipt.TxHash] = TransferStatusReverted
+			tick()
+			Expect(bc.released).Should(ConsistOf("transfer"))
+			Expect(storage.revoked).Should(ConsistOf("transfer"))
+
+			// Reverted transfers are final, and are not looked up again
+			bc.statuses[receipt.TxHash] = TransferStatusConfirmed
+			tick()
+			Expect(receipts()[receipt.TxHash].Status).Should(Equal(TransferStatusReverted))
+		})
+
+		It("should rebroadcast a dropped transfer", func() {
+			receipt := send(blockchain.TokenETH, "transfer")
+			bc.statuses[receipt.TxHash] = TransferStatusDropped
+			tick()
+			delete(bc.statuses, receipt.TxHash)
+
+			responder := make(chan TransferResponse, 1)
+			transfers.Reduce(NewRebroadcastRequest("password", receipt.TxHash, responder))
+			response := <-responder
+			Expect(response.Err).ShouldNot(HaveOccurred())
+			Expect(response.Receipt.Status).Should(Equal(TransferStatusBroadcast))
+			Expect(response.Receipt.ReservationID).Should(Equal("transfer"))
+			Expect(bc.released).Should(BeEmpty())
+		})
+	})
+
+	Context("when a UTXO transfer is broadcast", func() {
+		It("should release the reservation at once", func() {
+			receipt := send(blockchain.TokenBTC, "transfer")
+			Expect(receipt.Status).Should(Equal(TransferStatusBroadcast))
+			Expect(receipt.ReservationID).Should(BeEmpty())
+			Expect(bc.released).Should(ConsistOf("transfer"))
+			Expect(storage.revoked).Should(BeEmpty())
+		})
+
+		It("should release the reservation of a dropped transfer", func() {
+			storage.receipts["utxo"] = TransferReceipt{
+				Status:        TransferStatusBroadcast,
+				ReservationID: "transfer",
+				TokenDetails:  TokenDetails{Token: blockchain.TokenBTC, TxHash: "utxo"},
+			}
+			Expect(transfers.Reduce(Bootload{})).Should(BeNil())
+			Expect(bc.released).Should(BeEmpty())
+
+			bc.statuses["utxo"] = TransferStatusDropped
+			tick()
+			Expect(bc.released).Should(ConsistOf("transfer"))
+			Expect(storage.revoked).Should(ConsistOf("transfer"))
+		})
+	})
+
+	Context("when the address cannot be read", func() {
+		It("should release the reservation and revoke the spend", func() {
+			responder := make(chan TransferReceipt, 1)
+			req := NewTransferRequest("wrong", blockchain.TokenETH, "to", big.NewInt(50000), nil, responder)
+			req.ReservationID = "transfer"
+			Expect(transfers.Reduce(req)).ShouldNot(BeNil())
+			Expect(bc.released).Should(ConsistOf("transfer"))
+			Expect(storage.revoked).Should(ConsistOf("transfer"))
+			Expect(receipts()).Should(BeEmpty())
+		})
+	})
+
+	Context("when a transfer is cancelled", func() {
+		It("should stay broadcast, and reserved, until the cancellation uses its nonce", func() {
+			receipt := send(blockchain.TokenETH, "transfer")
+			response := cancel(receipt.TxHash)
+			Expect(response.Err).ShouldNot(HaveOccurred())
+			cancellation := response.Receipt
+			Expect(cancellation.Type).Should(Equal(TransferTypeCancellation))
+			Expect(cancellation.ReservationID).Should(BeEmpty())
+
+			original := receipts()[receipt.TxHash]
+			Expect(original.Status).Should(Equal(TransferStatusBroadcast))
+			Expect(original.ReplacedBy).Should(Equal(cancellation.TxHash))
+			Expect(original.ReservationID).Should(Equal("transfer"))
+			tick()
+			Expect(bc.released).Should(BeEmpty())
+
+			bc.statuses[cancellation.TxHash] = TransferStatusConfirmed
+			bc.statuses[receipt.TxHash] = TransferStatusReplaced
+			tick()
+			Expect(receipts()[receipt.TxHash].Status).Should(Equal(TransferStatusReplaced))
+			Expect(receipts()[cancellation.TxHash].Status).Should(Equal(TransferStatusConfirmed))
+			Expect(bc.released).Should(ConsistOf("transfer"))
+		})
+
+		It("should be confirmed if the transfer is mined before the cancellation", func() {
+			receipt := send(blockchain.TokenETH, "transfer")
+			cancellation := cancel(receipt.TxHash).Receipt
+
+			bc.statuses[receipt.TxHash] = TransferStatusConfirmed
+			bc.statuses[cancellation.TxHash] = TransferStatusReplaced
+			tick()
+			Expect(receipts()[receipt.TxHash].Status).Should(Equal(TransferStatusConfirmed))
+			Expect(receipts()[cancellation.TxHash].Status).Should(Equal(TransferStatusReplaced))
+			Expect(bc.released).Should(ConsistOf("transfer"))
+		})
+
+		It("should not cancel a transfer twice", func() {
+			receipt := send(blockchain.TokenETH, "transfer")
+			Expect(cancel(receipt.TxHash).Err).ShouldNot(HaveOccurred())
+			Expect(cancel(receipt.TxHash).Err).Should(HaveOccurred())
+		})
+
+		It("should not cancel a confirmed transfer", func() {
+			receipt := send(blockchain.TokenETH, "transfer")
+			bc.statuses[receipt.TxHash] = TransferStatusConfirmed
+			tick()
+			Expect(cancel(receipt.TxHash).Err).Should(HaveOccurred())
+		})
+	})
+})
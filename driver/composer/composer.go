@@ -1,19 +1,33 @@
 package composer
 
 import (
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/republicprotocol/swapperd/adapter/binder"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
 	"github.com/republicprotocol/swapperd/adapter/callback"
 	"github.com/republicprotocol/swapperd/adapter/db"
+	"github.com/republicprotocol/swapperd/adapter/policy"
 	"github.com/republicprotocol/swapperd/adapter/server"
+	"github.com/republicprotocol/swapperd/adapter/wallet"
 	"github.com/republicprotocol/swapperd/core/swapper"
 	"github.com/republicprotocol/swapperd/core/transfer"
 	"github.com/republicprotocol/swapperd/driver/keystore"
 	"github.com/republicprotocol/swapperd/driver/leveldb"
 	"github.com/republicprotocol/swapperd/driver/logger"
+	"github.com/republicprotocol/tau"
+	"github.com/sirupsen/logrus"
 )
 
 const BufferCapacity = 128
 
+// PassphraseEnv is the environment variable of the passphrase that unlocks the
+// keystores at startup, and encrypts plaintext keystores. Without it, an
+// encrypted keystore stays locked until a bootload gives its passphrase.
+const PassphraseEnv = "SWAPPERD_PASSPHRASE"
+
 type composer struct {
 	homeDir string
 	network string
@@ -29,22 +43,76 @@ func New(homeDir, network, port string) Composer {
 }
 
 func (composer *composer) Run(done <-chan struct{}) {
-	blockchain, err := keystore.Wallet(composer.homeDir, composer.network)
+	ldb, err := leveldb.NewStore(composer.homeDir, composer.network)
 	if err != nil {
 		panic(err)
 	}
+	storage := db.New(ldb)
 
-	ldb, err := leveldb.NewStore(composer.homeDir, composer.network)
+	blockchain, err := keystore.Wallet(composer.homeDir, composer.network, os.Getenv(PassphraseEnv), storage)
 	if err != nil {
 		panic(err)
 	}
 
-	storage := db.New(ldb)
 	logger := logger.NewStdOut()
+	if blockchain.Locked() {
+		logger.Infof("%s keystore is locked until a bootload with its passphrase", composer.network)
+	}
+	if plaintext, err := keystore.Plaintext(composer.homeDir, composer.network); err == nil && plaintext {
+		logger.Errorf("%s keystore is not encrypted, set %s to encrypt it", composer.network, PassphraseEnv)
+	}
+
+	// Swaps of the tokens whose contract fails verification are refused
+	for token, status := range blockchain.VerifyContracts() {
+		if !status.Ok {
+			logger.Errorf("swapperd contract of %s failed verification: %s", token, status.Error)
+		}
+	}
 
-	swapperTask := swapper.New(BufferCapacity, storage, binder.NewBuilder(blockchain, logger), callback.New())
+	// New Ethereum events retry the swaps straight away, instead of waiting
+	// for the next tick.
+	var swapperTask tau.Task
+	watcher := newWatcher(blockchain, storage, logger, func() {
+		swapperTask.IO().InputWriter() <- tau.NewTick(time.Now())
+	})
+
+	swapperTask = swapper.New(BufferCapacity, storage, binder.NewBuilder(blockchain, watcher, logger), callback.New())
+	if watcher != nil {
+		go watcher.Run(done)
+	}
+	go blockchain.RunNonceManager(done, logger)
 	walletTask := transfer.New(BufferCapacity, blockchain, storage, logger)
 
-	httpServer := server.NewHttpServer(blockchain, logger, swapperTask, walletTask, composer.port)
+	// Transfers and swaps are checked against the policies in the keystore
+	// config before they reach the tasks
+	config, err := keystore.Config(composer.homeDir, composer.network)
+	if err != nil {
+		panic(err)
+	}
+	policies := policy.New(config.Policies, storage)
+
+	httpServer := server.NewHttpServer(blockchain, policies, logger, swapperTask, walletTask, composer.port)
 	httpServer.Run(done)
 }
+
+// newWatcher returns an Ethereum watcher for all the Swapperd contracts, or
+// nil if it cannot be created, in which case the contracts are polled.
+func newWatcher(blockchain wallet.Wallet, storage db.Storage, logger logrus.FieldLogger, onEvent func()) eth.Watcher {
+	swapperAddresses, err := blockchain.SwapperAddresses()
+	if err != nil {
+		logger.Errorf("failed to load swapper addresses: %v", err)
+		return nil
+	}
+
+	contracts := []common.Address{}
+	for _, swapperAddress := range swapperAddresses {
+		contracts = append(contracts, common.HexToAddress(swapperAddress))
+	}
+
+	watcher, err := eth.NewWatcher(blockchain.EthereumURL(), contracts, storage, logger, onEvent)
+	if err != nil {
+		logger.Errorf("failed to create ethereum watcher: %v", err)
+		return nil
+	}
+	return watcher
+}
@@ -0,0 +1,145 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/republicprotocol/beth-go"
+	"github.com/republicprotocol/swapperd/adapter/binder/erc20"
+	"github.com/republicprotocol/swapperd/adapter/binder/eth"
+	"github.com/republicprotocol/swapperd/adapter/wallet"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/sirupsen/logrus"
+)
+
+// DeployTimeout is the time allowed for each contract to be deployed.
+const DeployTimeout = 5 * time.Minute
+
+// Deploy deploys the SwapperdEth contract, and a SwapperdERC20 contract for
+// every ERC20 token listed by the Ethereum config. ERC20 tokens without a
+// token address get a test token, which mints its supply to the account. It
+// returns the config with the addresses of the new contracts.
+func Deploy(config wallet.Config, password string, logger logrus.FieldLogger) (wallet.Config, error) {
+	registry := blockchain.NewTokenRegistry(blockchain.DefaultTokens, config.Tokens)
+	account, err := wallet.New(config, nil).EthereumAccount(password)
+	if err != nil {
+		return config, err
+	}
+
+	swapperAddress, err := deploy(account, func(tops *bind.TransactOpts) (common.Address, *types.Transaction, error) {
+		address, tx, _, err := eth.DeploySwapperdEth(tops, account.EthClient(), eth.SwapperdVersion)
+		return address, tx, err
+	})
+	if err != nil {
+		return config, fmt.Errorf("failed to deploy SwapperdEth: %v", err)
+	}
+	logger.Infof("deployed SwapperdEth at %s", swapperAddress.String())
+	config, err = setSwapperAddress(config, registry, blockchain.ETH, swapperAddress)
+	if err != nil {
+		return config, err
+	}
+
+	for _, name := range config.Ethereum.Tokens {
+		tokenConfig, err := registry.Config(blockchain.TokenName(name))
+		if err != nil {
+			return config, err
+		}
+		if !tokenConfig.IsERC20() {
+			continue
+		}
+		if tokenConfig.TokenAddress == "" {
+			tokenAddress, err := deploy(account, func(tops *bind.TransactOpts) (common.Address, *types.Transaction, error) {
+				return deployTestToken(tops, account.EthClient())
+			})
+			if err != nil {
+				return config, fmt.Errorf("failed to deploy test token %s: %v", name, err)
+			}
+			logger.Infof("deployed test token %s at %s", name, tokenAddress.String())
+			config, err = setTokenAddress(config, registry, tokenConfig.Name, tokenAddress)
+			if err != nil {
+				return config, err
+			}
+			tokenConfig.TokenAddress = tokenAddress.String()
+		}
+
+		tokenAddress := common.HexToAddress(tokenConfig.TokenAddress)
+		swapperAddress, err := deploy(account, func(tops *bind.TransactOpts) (common.Address, *types.Transaction, error) {
+			address, tx, _, err := erc20.DeploySwapperdERC20(tops, account.EthClient(), eth.SwapperdVersion, tokenAddress)
+			return address, tx, err
+		})
+		if err != nil {
+			return config, fmt.Errorf("failed to deploy Swapperd%s: %v", name, err)
+		}
+		logger.Infof("deployed Swapperd%s at %s", name, swapperAddress.String())
+		config, err = setSwapperAddress(config, registry, tokenConfig.Name, swapperAddress)
+		if err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+func deploy(account beth.Account, f func(*bind.TransactOpts) (common.Address, *types.Transaction, error)) (common.Address, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DeployTimeout)
+	defer cancel()
+
+	var address common.Address
+	if err := account.Transact(
+		ctx,
+		nil,
+		func(tops *bind.TransactOpts) (*types.Transaction, error) {
+			addr, tx, err := f(tops)
+			if err != nil {
+				return tx, err
+			}
+			address = addr
+			return tx, nil
+		},
+		nil,
+		1,
+	); err != nil {
+		return common.Address{}, err
+	}
+	return address, nil
+}
+
+// setSwapperAddress sets the swapper address of the token in the config,
+// adding the token from the registry if it is not configured yet.
+func setSwapperAddress(config wallet.Config, registry blockchain.TokenRegistry, name blockchain.TokenName, swapperAddress common.Address) (wallet.Config, error) {
+	return updateToken(config, registry, name, func(tokenConfig *blockchain.TokenConfig) {
+		tokenConfig.SwapperAddress = swapperAddress.String()
+	})
+}
+
+// setTokenAddress sets the token address of the token in the config, adding
+// the token from the registry if it is not configured yet.
+func setTokenAddress(config wallet.Config, registry blockchain.TokenRegistry, name blockchain.TokenName, tokenAddress common.Address) (wallet.Config, error) {
+	return updateToken(config, registry, name, func(tokenConfig *blockchain.TokenConfig) {
+		tokenConfig.TokenAddress = tokenAddress.String()
+	})
+}
+
+func updateToken(config wallet.Config, registry blockchain.TokenRegistry, name blockchain.TokenName, update func(*blockchain.TokenConfig)) (wallet.Config, error) {
+	tokens := make([]blockchain.TokenConfig, len(config.Tokens))
+	copy(tokens, config.Tokens)
+	config.Tokens = tokens
+
+	for i := range config.Tokens {
+		if config.Tokens[i].Name == name {
+			update(&config.Tokens[i])
+			return config, nil
+		}
+	}
+
+	tokenConfig, err := registry.Config(name)
+	if err != nil {
+		return config, err
+	}
+	update(&tokenConfig)
+	config.Tokens = append(config.Tokens, tokenConfig)
+	return config, nil
+}
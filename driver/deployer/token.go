@@ -0,0 +1,26 @@
+package deployer
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/republicprotocol/swapperd/adapter/binder/erc20"
+)
+
+// TestTokenBin is the bytecode of the ERC20 token deployed to localnet for
+// the ERC20 tokens that have no token address. It implements totalSupply,
+// balanceOf, transfer, transferFrom, approve, allowance and decimals, with 18
+// decimals, and mints its supply of 10^27 to the account that deploys it.
+const TestTokenBin = `0x7f0000000000000000000000000000000000000000033b2e3c9fd0803ce800000033557f0000000000000000000000000000000000000000033b2e3c9fd0803ce80000006000523360007fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef60206000a361019d8061007d6000396000f36000357c01000000000000000000000000000000000000000000000000000000009004806318160ddd1461007557806370a08231146100aa578063a9059cbb1461011a57806323b872dd14610126578063095ea7b3146100d2578063dd62ed3e146100b7578063313ce5671461009f575b600080fd5b7f0000000000000000000000000000000000000000033b2e3c9fd0803ce800000060005260206000f35b601260005260206000f35b6004355460005260206000f35b60043560005260243560205260406000205460005260206000f35b33600052600435602052602435604060002055602435600052600435337f8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b92560206000a3610192565b33600435602435610153565b60043560005233602052604060002080546044358181116100705790039055600435602435604435610153565b8254818110610070578190038355815481018255600052907fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef60206000a35b600160005260206000f3`
+
+func deployTestToken(tops *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, error) {
+	parsed, err := abi.JSON(strings.NewReader(erc20.CompatibleERC20ABI))
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	address, tx, _, err := bind.DeployContract(tops, parsed, common.FromHex(TestTokenBin), backend)
+	return address, tx, err
+}
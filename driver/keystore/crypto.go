@@ -0,0 +1,120 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// The scrypt parameters used to derive the key that encrypts the mnemonic.
+// They are the standard parameters of Ethereum keystores.
+const (
+	ScryptN      = 1 << 18
+	ScryptR      = 8
+	ScryptP      = 1
+	ScryptKeyLen = 32
+)
+
+// ErrWrongPassphrase is returned when the mnemonic cannot be decrypted with the
+// passphrase.
+var ErrWrongPassphrase = fmt.Errorf("could not decrypt the keystore with the given passphrase")
+
+// An EncryptedMnemonic is the mnemonic encrypted with AES-256-GCM, using a key
+// derived from the passphrase with scrypt.
+type EncryptedMnemonic struct {
+	Cipher     string       `json:"cipher"`
+	CipherText string       `json:"ciphertext"`
+	Nonce      string       `json:"nonce"`
+	KDF        string       `json:"kdf"`
+	KDFParams  ScryptParams `json:"kdfparams"`
+}
+
+// ScryptParams are the parameters of the key derivation.
+type ScryptParams struct {
+	N      int    `json:"n"`
+	R      int    `json:"r"`
+	P      int    `json:"p"`
+	KeyLen int    `json:"dklen"`
+	Salt   string `json:"salt"`
+}
+
+func encryptMnemonic(mnemonic, passphrase string) (EncryptedMnemonic, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return EncryptedMnemonic{}, err
+	}
+	params := ScryptParams{
+		N:      ScryptN,
+		R:      ScryptR,
+		P:      ScryptP,
+		KeyLen: ScryptKeyLen,
+		Salt:   hex.EncodeToString(salt),
+	}
+
+	aead, err := newAEAD(passphrase, params)
+	if err != nil {
+		return EncryptedMnemonic{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return EncryptedMnemonic{}, err
+	}
+
+	return EncryptedMnemonic{
+		Cipher:     "aes-256-gcm",
+		CipherText: hex.EncodeToString(aead.Seal(nil, nonce, []byte(mnemonic), nil)),
+		Nonce:      hex.EncodeToString(nonce),
+		KDF:        "scrypt",
+		KDFParams:  params,
+	}, nil
+}
+
+func decryptMnemonic(encrypted EncryptedMnemonic, passphrase string) (string, error) {
+	if encrypted.Cipher != "aes-256-gcm" || encrypted.KDF != "scrypt" {
+		return "", fmt.Errorf("unsupported keystore encryption: %s with %s", encrypted.Cipher, encrypted.KDF)
+	}
+	nonce, err := hex.DecodeString(encrypted.Nonce)
+	if err != nil {
+		return "", fmt.Errorf("invalid keystore nonce: %v", err)
+	}
+	cipherText, err := hex.DecodeString(encrypted.CipherText)
+	if err != nil {
+		return "", fmt.Errorf("invalid keystore ciphertext: %v", err)
+	}
+
+	aead, err := newAEAD(passphrase, encrypted.KDFParams)
+	if err != nil {
+		return "", err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return "", fmt.Errorf("invalid keystore nonce size: %d", len(nonce))
+	}
+	mnemonic, err := aead.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(mnemonic), nil
+}
+
+func newAEAD(passphrase string, params ScryptParams) (cipher.AEAD, error) {
+	if params.KeyLen != ScryptKeyLen {
+		return nil, fmt.Errorf("unsupported keystore key length: %d", params.KeyLen)
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %v", err)
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.KeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,155 @@
+package keystore_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/republicprotocol/swapperd/driver/keystore"
+)
+
+var _ = Describe("Keystore", func() {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	var homeDir string
+
+	BeforeEach(func() {
+		var err error
+		homeDir, err = ioutil.TempDir("", "keystore")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(homeDir)
+	})
+
+	readKeystore := func() map[string]interface{} {
+		data, err := ioutil.ReadFile(path.Join(homeDir, "testnet.json"))
+		Expect(err).ShouldNot(HaveOccurred())
+		file := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &file)).Should(Succeed())
+		return file
+	}
+
+	writePlaintextKeystore := func() {
+		config := Testnet
+		config.Mnemonic = mnemonic
+		data, err := json.Marshal(config)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ioutil.WriteFile(path.Join(homeDir, "testnet.json"), data, 0600)).Should(Succeed())
+	}
+
+	Context("when generating a keystore", func() {
+		It("should encrypt the mnemonic with the passphrase", func() {
+			Expect(Generate(homeDir, "testnet", "passphrase", mnemonic)).Should(Succeed())
+			file := readKeystore()
+			Expect(file).ShouldNot(HaveKey("mnemonic"))
+			Expect(file).Should(HaveKey("crypto"))
+
+			config, err := Unlock(homeDir, "testnet", "passphrase")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(config.Mnemonic).Should(Equal(mnemonic))
+		})
+
+		It("should not unlock with the wrong passphrase", func() {
+			Expect(Generate(homeDir, "testnet", "passphrase", mnemonic)).Should(Succeed())
+			_, err := Unlock(homeDir, "testnet", "password")
+			Expect(err).Should(Equal(ErrWrongPassphrase))
+		})
+
+		It("should refuse an empty passphrase", func() {
+			Expect(Generate(homeDir, "testnet", "", mnemonic)).Should(Equal(ErrEmptyPassphrase))
+			_, err := Config(homeDir, "testnet")
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should only be readable by the owner and leave no temporary files", func() {
+			Expect(Generate(homeDir, "testnet", "passphrase", mnemonic)).Should(Succeed())
+			info, err := os.Stat(path.Join(homeDir, "testnet.json"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(info.Mode().Perm()).Should(Equal(os.FileMode(0600)))
+
+			files, err := ioutil.ReadDir(homeDir)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files).Should(HaveLen(1))
+		})
+	})
+
+	Context("when the keystore is plaintext", func() {
+		BeforeEach(func() {
+			writePlaintextKeystore()
+		})
+
+		It("should unlock without changing the keystore", func() {
+			config, err := Unlock(homeDir, "testnet", "password")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(config.Mnemonic).Should(Equal(mnemonic))
+			Expect(readKeystore()).ShouldNot(HaveKey("crypto"))
+
+			plaintext, err := Plaintext(homeDir, "testnet")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(plaintext).Should(BeTrue())
+		})
+
+		It("should migrate to an encrypted keystore with the passphrase", func() {
+			Expect(Encrypt(homeDir, "testnet", "passphrase")).Should(Succeed())
+			file := readKeystore()
+			Expect(file).ShouldNot(HaveKey("mnemonic"))
+			Expect(file).Should(HaveKey("crypto"))
+
+			plaintext, err := Plaintext(homeDir, "testnet")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(plaintext).Should(BeFalse())
+
+			config, err := Unlock(homeDir, "testnet", "passphrase")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(config.Mnemonic).Should(Equal(mnemonic))
+			_, err = Unlock(homeDir, "testnet", "password")
+			Expect(err).Should(Equal(ErrWrongPassphrase))
+		})
+
+		It("should not migrate with an empty passphrase", func() {
+			Expect(Encrypt(homeDir, "testnet", "")).ShouldNot(Succeed())
+			Expect(readKeystore()).Should(HaveKeyWithValue("mnemonic", mnemonic))
+		})
+
+		It("should not encrypt the keystore when the wallet is loaded without a passphrase", func() {
+			wallet, err := Wallet(homeDir, "testnet", "", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(wallet.Locked()).Should(BeFalse())
+			Expect(readKeystore()).Should(HaveKeyWithValue("mnemonic", mnemonic))
+		})
+	})
+
+	Context("when the keystore is encrypted", func() {
+		BeforeEach(func() {
+			Expect(Generate(homeDir, "testnet", "passphrase", mnemonic)).Should(Succeed())
+		})
+
+		It("should not re-encrypt the keystore when migrating", func() {
+			before := readKeystore()
+			Expect(Encrypt(homeDir, "testnet", "password")).Should(Succeed())
+			Expect(readKeystore()).Should(Equal(before))
+		})
+
+		It("should return a locked wallet that only unlocks with the passphrase", func() {
+			wallet, err := Wallet(homeDir, "testnet", "", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(wallet.Locked()).Should(BeTrue())
+
+			Expect(wallet.Unlock("password")).Should(Equal(ErrWrongPassphrase))
+			Expect(wallet.Locked()).Should(BeTrue())
+			Expect(wallet.Unlock("passphrase")).Should(Succeed())
+			Expect(wallet.Locked()).Should(BeFalse())
+		})
+
+		It("should return an unlocked wallet with the passphrase", func() {
+			wallet, err := Wallet(homeDir, "testnet", "passphrase", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(wallet.Locked()).Should(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,224 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/republicprotocol/swapperd/adapter/binder/btc"
+	"github.com/republicprotocol/swapperd/adapter/wallet"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+	"github.com/sirupsen/logrus"
+)
+
+// TransferTimeout is the time allowed for the balance of each token to be
+// moved.
+const TransferTimeout = 30 * time.Minute
+
+// Storage is the storage of the wallet, with the swaps that are still pending.
+type Storage interface {
+	wallet.Storage
+	PendingSwaps() ([]swap.SwapBlob, error)
+}
+
+// ErrUnsettled is returned when the balances are moved while swaps or
+// reservations are unsettled. Their contracts and transfers are bound to the
+// addresses of the current derivation, so they must settle first.
+type ErrUnsettled struct {
+	Swaps        int
+	Reservations int
+}
+
+func (err ErrUnsettled) Error() string {
+	return fmt.Sprintf("cannot move the balances while %d swaps and %d reservations are unsettled, wait for them to settle", err.Swaps, err.Reservations)
+}
+
+// A Pair is the legacy and the standard address of a token, with their
+// balances in the smallest unit of the token.
+type Pair struct {
+	Token           blockchain.TokenName
+	LegacyAddress   string
+	LegacyBalance   string
+	StandardAddress string
+	StandardBalance string
+
+	// Error is set when one of the balances could not be fetched.
+	Error string
+}
+
+// Compare returns the legacy and the standard address of every supported
+// token, with their balances. The balances include every address index
+// allocated in the storage.
+func Compare(config wallet.Config, storage wallet.Storage, password string) ([]Pair, error) {
+	legacy := wallet.New(config.WithLegacyDerivation(true), storage)
+	standard := wallet.New(config.WithLegacyDerivation(false), storage)
+
+	legacyBalances, err := legacy.Balances(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the legacy balances: %v", err)
+	}
+	standardBalances, err := standard.Balances(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the standard balances: %v", err)
+	}
+
+	pairs := []Pair{}
+	for _, token := range sortedTokens(legacy) {
+		legacyBalance, standardBalance := legacyBalances[token.Name], standardBalances[token.Name]
+		pair := Pair{
+			Token:           token.Name,
+			LegacyAddress:   legacyBalance.Address,
+			LegacyBalance:   legacyBalance.Amount,
+			StandardAddress: standardBalance.Address,
+			StandardBalance: standardBalance.Amount,
+		}
+		if legacyBalance.Error != "" {
+			pair.Error = fmt.Sprintf("legacy: %s", legacyBalance.Error)
+		} else if standardBalance.Error != "" {
+			pair.Error = fmt.Sprintf("standard: %s", standardBalance.Error)
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// Move transfers the balance of every token from the legacy addresses to the
+// standard addresses, or from the standard addresses to the legacy addresses
+// when toStandard is false. The fees are paid from the balances being moved,
+// so ERC20 tokens are moved before ether. It returns the config that derives
+// the addresses that now hold the balances. Nothing is moved while any swap or
+// reservation is unsettled.
+func Move(config wallet.Config, storage Storage, password string, toStandard bool, logger logrus.FieldLogger) (wallet.Config, error) {
+	if err := verifySettled(storage); err != nil {
+		return config, err
+	}
+
+	from := wallet.New(config.WithLegacyDerivation(toStandard), storage)
+	to := wallet.New(config.WithLegacyDerivation(!toStandard), storage)
+
+	balances, err := from.Balances(password)
+	if err != nil {
+		return config, err
+	}
+
+	var eth *blockchain.Token
+	for _, token := range sortedTokens(from) {
+		token := token
+		if token.Name == blockchain.ETH {
+			eth = &token
+			continue
+		}
+		if balances[token.Name].Error != "" {
+			return config, fmt.Errorf("failed to read the balance of %s: %s", token.Name, balances[token.Name].Error)
+		}
+		amount, ok := new(big.Int).SetString(balances[token.Name].Amount, 10)
+		if !ok || amount.Sign() == 0 {
+			continue
+		}
+		address, err := to.GetAddress(password, token.Blockchain)
+		if err != nil {
+			return config, err
+		}
+		if token.Blockchain != blockchain.Ethereum {
+			if amount, err = sendableUTXO(from, token, amount); err != nil {
+				logger.Warnf("not moving %s: %v", token.Name, err)
+				continue
+			}
+		}
+
+		tx, err := from.Transfer(password, token, address, amount, nil)
+		if err != nil {
+			return config, fmt.Errorf("failed to move %s to %s: %v", token.Name, address, err)
+		}
+		logger.Infof("moved %s %s from %s to %s in %s", amount, token.Name, balances[token.Name].Address, address, tx.TxHash)
+	}
+
+	if eth != nil {
+		if err := moveETH(from, to, password, logger); err != nil {
+			return config, err
+		}
+	}
+	return config.WithLegacyDerivation(!toStandard), nil
+}
+
+// verifySettled returns ErrUnsettled if the storage has pending swaps or
+// reservations.
+func verifySettled(storage Storage) error {
+	swaps, err := storage.PendingSwaps()
+	if err != nil {
+		return fmt.Errorf("failed to read the pending swaps: %v", err)
+	}
+	reservations, err := storage.Reservations()
+	if err != nil {
+		return fmt.Errorf("failed to read the reservations: %v", err)
+	}
+	if len(swaps) > 0 || len(reservations) > 0 {
+		return ErrUnsettled{len(swaps), len(reservations)}
+	}
+	return nil
+}
+
+// sendableUTXO returns the balance of a UTXO token less the fee of the
+// transfer.
+func sendableUTXO(from wallet.Wallet, token blockchain.Token, balance *big.Int) (*big.Int, error) {
+	fee, err := from.DefaultFee(token.Blockchain)
+	if err != nil {
+		return nil, err
+	}
+	amount := new(big.Int).Sub(balance, fee)
+	if amount.Cmp(big.NewInt(btc.DustAmount)) < 0 {
+		return nil, fmt.Errorf("balance of %s does not cover the fee of %s", balance, fee)
+	}
+	return amount, nil
+}
+
+// moveETH transfers the whole ether balance, less the gas of the transfer.
+// The balance is read after the ERC20 tokens have been moved, since their
+// gas is paid in ether.
+func moveETH(from, to wallet.Wallet, password string, logger logrus.FieldLogger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), TransferTimeout)
+	defer cancel()
+
+	account, err := from.EthereumAccount(password)
+	if err != nil {
+		return err
+	}
+	address, err := to.EthereumAccount(password)
+	if err != nil {
+		return err
+	}
+
+	balance, err := account.EthClient().BalanceAt(ctx, account.Address(), nil)
+	if err != nil {
+		return err
+	}
+	gasPrice, err := account.EthClient().SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	value := new(big.Int).Sub(balance, new(big.Int).Mul(gasPrice, big.NewInt(wallet.EthereumTransferGas)))
+	if value.Sign() <= 0 {
+		if balance.Sign() > 0 {
+			logger.Warnf("not moving %s: balance of %s does not cover the gas", blockchain.ETH, balance)
+		}
+		return nil
+	}
+
+	tx, err := from.Transfer(password, blockchain.TokenETH, address.Address().String(), value, gasPrice)
+	if err != nil {
+		return fmt.Errorf("failed to move %s to %s: %v", blockchain.ETH, address.Address().String(), err)
+	}
+	logger.Infof("moved %s %s from %s to %s in %s", value, blockchain.ETH, account.Address().String(), address.Address().String(), tx.TxHash)
+	return nil
+}
+
+func sortedTokens(w wallet.Wallet) []blockchain.Token {
+	tokens := w.SupportedTokens()
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens
+}
@@ -0,0 +1,49 @@
+package migrator_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/republicprotocol/swapperd/adapter/wallet"
+	"github.com/republicprotocol/swapperd/driver/keystore"
+	"github.com/republicprotocol/swapperd/driver/logger"
+	. "github.com/republicprotocol/swapperd/driver/migrator"
+	"github.com/republicprotocol/swapperd/foundation/blockchain"
+	"github.com/republicprotocol/swapperd/foundation/swap"
+)
+
+// mockStorage only has pending swaps and reservations. Moving the balances
+// must not touch the rest of the storage while they are unsettled.
+type mockStorage struct {
+	wallet.Storage
+	swaps        []swap.SwapBlob
+	reservations map[string]wallet.Reservation
+}
+
+func (storage *mockStorage) PendingSwaps() ([]swap.SwapBlob, error) {
+	return storage.swaps, nil
+}
+
+func (storage *mockStorage) Reservations() (map[string]wallet.Reservation, error) {
+	return storage.reservations, nil
+}
+
+var _ = Describe("Migrator", func() {
+	config := keystore.Testnet
+	config.Mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	It("should not move the balances while a swap is pending", func() {
+		storage := &mockStorage{swaps: []swap.SwapBlob{{ID: swap.RandomID()}}}
+		moved, err := Move(config, storage, "password", true, logger.NewStdOut())
+		Expect(err).Should(Equal(ErrUnsettled{Swaps: 1}))
+		Expect(moved).Should(Equal(config))
+	})
+
+	It("should not move the balances while a balance is reserved", func() {
+		storage := &mockStorage{reservations: map[string]wallet.Reservation{
+			"transfer": {Token: blockchain.BTC, Amount: "10000"},
+		}}
+		moved, err := Move(config, storage, "password", false, logger.NewStdOut())
+		Expect(err).Should(Equal(ErrUnsettled{Reservations: 1}))
+		Expect(moved).Should(Equal(config))
+	})
+})
@@ -3,4 +3,13 @@ package blockchain
 type Balance struct {
 	Address string `json:"address"`
 	Amount  string `json:"balance"`
+
+	// Reserved is the amount committed to pending swaps and transfers in
+	// flight, and Available is the rest of the balance.
+	Available string `json:"available,omitempty"`
+	Reserved  string `json:"reserved,omitempty"`
+
+	// Error is set instead of the amounts when the balance could not be
+	// fetched.
+	Error string `json:"error,omitempty"`
 }
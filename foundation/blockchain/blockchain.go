@@ -5,8 +5,10 @@ import "fmt"
 type BlockchainName string
 
 var (
-	Bitcoin  = BlockchainName("bitcoin")
-	Ethereum = BlockchainName("ethereum")
+	Bitcoin     = BlockchainName("bitcoin")
+	Ethereum    = BlockchainName("ethereum")
+	Litecoin    = BlockchainName("litecoin")
+	BitcoinCash = BlockchainName("bitcoincash")
 )
 
 type Blockchain struct {
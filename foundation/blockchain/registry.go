@@ -0,0 +1,86 @@
+package blockchain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A TokenConfig describes a token in the TokenRegistry. Empty contract
+// addresses are read from the address book of the network.
+type TokenConfig struct {
+	Name           TokenName      `json:"name"`
+	Aliases        []string       `json:"aliases,omitempty"`
+	Blockchain     BlockchainName `json:"blockchain"`
+	Decimals       int            `json:"decimals"`
+	TokenAddress   string         `json:"tokenAddress,omitempty"`
+	SwapperAddress string         `json:"swapperAddress,omitempty"`
+}
+
+// Token returns the token described by the config.
+func (config TokenConfig) Token() Token {
+	return Token{config.Name, config.Blockchain}
+}
+
+// IsERC20 returns true if the token is an ERC20 token, as opposed to the
+// native token of its blockchain.
+func (config TokenConfig) IsERC20() bool {
+	return config.Blockchain == Ethereum && config.Name != ETH
+}
+
+// A TokenRegistry holds the configuration of all the supported tokens.
+type TokenRegistry struct {
+	names   []TokenName
+	configs map[TokenName]TokenConfig
+	aliases map[string]TokenName
+}
+
+// NewTokenRegistry returns a TokenRegistry from lists of token configs. A
+// token in a later list replaces the token with the same name in an earlier
+// list.
+func NewTokenRegistry(configLists ...[]TokenConfig) TokenRegistry {
+	registry := TokenRegistry{
+		names:   []TokenName{},
+		configs: map[TokenName]TokenConfig{},
+		aliases: map[string]TokenName{},
+	}
+	for _, configs := range configLists {
+		for _, config := range configs {
+			if _, ok := registry.configs[config.Name]; !ok {
+				registry.names = append(registry.names, config.Name)
+			}
+			registry.configs[config.Name] = config
+			registry.aliases[strings.ToLower(string(config.Name))] = config.Name
+			for _, alias := range config.Aliases {
+				registry.aliases[strings.ToLower(alias)] = config.Name
+			}
+		}
+	}
+	return registry
+}
+
+// PatchToken returns the token with the given name or alias.
+func (registry TokenRegistry) PatchToken(token string) (Token, error) {
+	name, ok := registry.aliases[strings.ToLower(token)]
+	if !ok {
+		return Token{}, fmt.Errorf("unsupported token: %s", token)
+	}
+	return registry.configs[name].Token(), nil
+}
+
+// Config returns the config of the token with the given name.
+func (registry TokenRegistry) Config(name TokenName) (TokenConfig, error) {
+	config, ok := registry.configs[name]
+	if !ok {
+		return TokenConfig{}, NewErrUnsupportedToken(name)
+	}
+	return config, nil
+}
+
+// Tokens returns all the tokens in the registry.
+func (registry TokenRegistry) Tokens() []Token {
+	tokens := make([]Token, len(registry.names))
+	for i, name := range registry.names {
+		tokens[i] = registry.configs[name].Token()
+	}
+	return tokens
+}
@@ -1,9 +1,6 @@
 package blockchain
 
-import (
-	"fmt"
-	"strings"
-)
+import "fmt"
 
 type ErrUnsupportedToken string
 
@@ -38,6 +35,8 @@ var (
 	USDC = TokenName("USDC")
 	GUSD = TokenName("GUSD")
 	TUSD = TokenName("TUSD")
+	LTC  = TokenName("LTC")
+	BCH  = TokenName("BCH")
 )
 
 var (
@@ -52,36 +51,33 @@ var (
 	TokenDAI  = Token{TokenName("DAI"), Ethereum}
 	TokenUSDC = Token{TokenName("USDC"), Ethereum}
 	TokenGUSD = Token{TokenName("GUSD"), Ethereum}
+	TokenLTC  = Token{TokenName("LTC"), Litecoin}
+	TokenBCH  = Token{TokenName("BCH"), BitcoinCash}
 )
 
+// DefaultTokens are the tokens supported without any configuration. Their
+// contract addresses are read from the address book of the network.
+var DefaultTokens = []TokenConfig{
+	{Name: BTC, Aliases: []string{"bitcoin", "btc", "xbt"}, Blockchain: Bitcoin, Decimals: 8},
+	{Name: ETH, Aliases: []string{"ethereum", "eth", "ether"}, Blockchain: Ethereum, Decimals: 18},
+	{Name: WBTC, Aliases: []string{"wrappedbtc", "wbtc", "wrappedbitcoin"}, Blockchain: Ethereum, Decimals: 8},
+	{Name: REN, Aliases: []string{"ren", "republictoken", "republic token"}, Blockchain: Ethereum, Decimals: 18},
+	{Name: ZRX, Aliases: []string{"zerox", "zrx", "0x"}, Blockchain: Ethereum, Decimals: 18},
+	{Name: OMG, Aliases: []string{"omisego", "omg", "omise go"}, Blockchain: Ethereum, Decimals: 18},
+	{Name: TUSD, Aliases: []string{"tusd", "trueusd", "true usd"}, Blockchain: Ethereum, Decimals: 18},
+	{Name: DGX, Aliases: []string{"digix gold token", "dgx", "dgt"}, Blockchain: Ethereum, Decimals: 9},
+	{Name: GUSD, Aliases: []string{"gusd", "gemini usd", "geminiusd"}, Blockchain: Ethereum, Decimals: 2},
+	{Name: DAI, Aliases: []string{"dai", "maker dai", "makerdai"}, Blockchain: Ethereum, Decimals: 18},
+	{Name: USDC, Aliases: []string{"usdc", "usd coin", "usdcoin"}, Blockchain: Ethereum, Decimals: 6},
+	{Name: LTC, Aliases: []string{"litecoin", "ltc"}, Blockchain: Litecoin, Decimals: 8},
+	{Name: BCH, Aliases: []string{"bitcoincash", "bitcoin cash", "bch"}, Blockchain: BitcoinCash, Decimals: 8},
+}
+
+var defaultRegistry = NewTokenRegistry(DefaultTokens)
+
+// PatchToken returns the default token with the given name or alias.
 func PatchToken(token string) (Token, error) {
-	token = strings.ToLower(token)
-	switch token {
-	case "bitcoin", "btc", "xbt":
-		return TokenBTC, nil
-	case "wrappedbtc", "wbtc", "wrappedbitcoin":
-		return TokenWBTC, nil
-	case "ethereum", "eth", "ether":
-		return TokenETH, nil
-	case "ren", "republictoken", "republic token":
-		return TokenREN, nil
-	case "tusd", "trueusd", "true usd":
-		return TokenTUSD, nil
-	case "digix gold token", "dgx", "dgt":
-		return TokenDGX, nil
-	case "zerox", "zrx", "0x":
-		return TokenZRX, nil
-	case "omisego", "omg", "omise go":
-		return TokenOMG, nil
-	case "usdc", "usd coin", "usdcoin":
-		return TokenUSDC, nil
-	case "dai", "maker dai", "makerdai":
-		return TokenDAI, nil
-	case "gusd", "gemini usd", "geminiusd":
-		return TokenGUSD, nil
-	default:
-		return Token{}, fmt.Errorf("unsupported token: %s", token)
-	}
+	return defaultRegistry.PatchToken(token)
 }
 
 func IsValidToken(name TokenName) bool {
@@ -12,6 +12,7 @@ const (
 	RefundFailed
 	Cancelled
 	Expired
+	AuditMismatch
 )
 
 type StatusUpdate struct {
@@ -21,6 +21,33 @@ func RandomID() SwapID {
 
 const ExpiryUnit = int64(2 * 60 * 60)
 
+// TimeLockMargin is the least time, in seconds, between the expiry of the
+// responder's leg and the expiry of the initiator's leg that the responder
+// accepts. It gives the responder time to redeem after the secret has been
+// revealed, even when the blocks of block based timelocks come early.
+const TimeLockMargin = ExpiryUnit / 4
+
+// The modes of the timelocks of the Bitcoin HTLCs. The time mode uses the Unix
+// timelock with OP_CHECKLOCKTIMEVERIFY, the height mode uses a block height
+// with OP_CHECKLOCKTIMEVERIFY, and the sequence mode uses a number of blocks
+// after funding with OP_CHECKSEQUENCEVERIFY.
+const (
+	TimeLockModeTime     = "time"
+	TimeLockModeHeight   = "height"
+	TimeLockModeSequence = "sequence"
+)
+
+// ValidTimeLockMode returns true if the mode is empty, which is the time mode,
+// or one of the timelock modes.
+func ValidTimeLockMode(mode string) bool {
+	switch mode {
+	case "", TimeLockModeTime, TimeLockModeHeight, TimeLockModeSequence:
+		return true
+	default:
+		return false
+	}
+}
+
 // The SwapReceipt contains the swap details and the status.
 type SwapReceipt struct {
 	ID            SwapID              `json:"id"`
@@ -37,6 +64,44 @@ type SwapReceipt struct {
 	DelayInfo     json.RawMessage     `json:"delayInfo,omitempty"`
 	Active        bool                `json:"active"`
 	PasswordHash  string              `json:"passwordHash,omitempty"`
+
+	// RefundTx is the signed refund transaction of the native leg, hex
+	// encoded. It can be published by anyone once the swap expires.
+	RefundTx string `json:"refundTx,omitempty"`
+
+	// RefundTxError is the reason the refund of the native leg could not be
+	// pre-signed, when it could not.
+	RefundTxError string `json:"refundTxError,omitempty"`
+
+	// SendOutputs and ReceiveOutputs are the outputs that fund the HTLCs of
+	// the legs on UTXO blockchains, as last seen by the binders.
+	SendOutputs    []Output `json:"sendOutputs,omitempty"`
+	ReceiveOutputs []Output `json:"receiveOutputs,omitempty"`
+
+	// SendFunding and ReceiveFunding flag the HTLCs of the legs on UTXO
+	// blockchains that have not been funded as agreed.
+	SendFunding    *FundingFlags `json:"sendFunding,omitempty"`
+	ReceiveFunding *FundingFlags `json:"receiveFunding,omitempty"`
+}
+
+// FundingFlags flag an HTLC that is funded by more than one output, or with
+// more than the agreed value. Overfunded is the value above the agreed value.
+type FundingFlags struct {
+	MultipleOutputs bool   `json:"multipleOutputs,omitempty"`
+	Overfunded      string `json:"overfunded,omitempty"`
+}
+
+// IsZero returns true if none of the flags are set.
+func (flags FundingFlags) IsZero() bool {
+	return !flags.MultipleOutputs && flags.Overfunded == ""
+}
+
+// An Output is an unspent transaction output that funds the HTLC of a leg on a
+// UTXO blockchain.
+type Output struct {
+	TxHash string `json:"txHash"`
+	Vout   uint32 `json:"vout"`
+	Amount string `json:"amount"`
 }
 
 func NewSwapReceipt(blob SwapBlob) SwapReceipt {
@@ -70,6 +135,20 @@ type Swap struct {
 	SpendingAddress string
 	FundingAddress  string
 	BrokerAddress   string
+
+	// TimeLockMode and TimeLockBlocks set the timelock of Bitcoin HTLCs. The
+	// blocks are a block height in the height mode, and a number of blocks
+	// after funding in the sequence mode.
+	TimeLockMode   string
+	TimeLockBlocks int64
+
+	// ResponderExpiry and ResponderLockDuration are set by the responder on
+	// the leg of the initiator. The leg of the responder expires at the
+	// expiry, or the duration, in seconds, after it is funded when it uses a
+	// relative timelock. The leg of the initiator must expire TimeLockMargin
+	// after it.
+	ResponderExpiry       int64
+	ResponderLockDuration int64
 }
 
 // A SwapBlob is used to encode a Swap for storage and transmission.
@@ -91,6 +170,18 @@ type SwapBlob struct {
 	SecretHash          string `json:"secretHash"`
 	ShouldInitiateFirst bool   `json:"shouldInitiateFirst"`
 
+	// TimeLockMode is the timelock mode of the Bitcoin HTLCs, and the blocks
+	// are the timelocks of the send and receive legs in the height and
+	// sequence modes. They are filled in by the initiator.
+	TimeLockMode          string `json:"timeLockMode,omitempty"`
+	SendTimeLockBlocks    int64  `json:"sendTimeLockBlocks,omitempty"`
+	ReceiveTimeLockBlocks int64  `json:"receiveTimeLockBlocks,omitempty"`
+
+	// AddressIndex is the index of the addresses of the wallet used by the
+	// swap on UTXO blockchains. It is allocated by each side of the swap, and
+	// is not shared with the counterparty.
+	AddressIndex uint32 `json:"addressIndex,omitempty"`
+
 	Delay            bool            `json:"delay,omitempty"`
 	DelayInfo        json.RawMessage `json:"delayInfo,omitempty"`
 	DelayCallbackURL string          `json:"delayCallbackUrl,omitempty"`